@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/goccy/go-json v0.7.2
 	github.com/pkg/errors v0.9.1
+	github.com/segmentio/kafka-go v0.4.10
 	github.com/shopspring/decimal v1.2.0
 	go.mongodb.org/mongo-driver v1.5.3
 	golang.org/x/text v0.3.5
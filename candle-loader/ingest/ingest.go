@@ -0,0 +1,195 @@
+// Package ingest upserts candle and ticker messages consumed from kafka into
+// the mongo candle collections.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"ipoemi/candle-loader/trade/candle"
+	"ipoemi/candle-loader/upbit"
+	"log"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Options configures Run.
+type Options struct {
+	// BatchSize is the number of messages written and committed together.
+	BatchSize int
+	// BatchTimeout flushes an incomplete batch after this long.
+	BatchTimeout time.Duration
+}
+
+// probe holds the fields telling the kind of a message apart.
+type probe struct {
+	Code              string `json:"code"`
+	CandleDateTimeUtc string `json:"candle_date_time_utc"`
+	TradeDate         string `json:"trade_date"`
+}
+
+// Decode converts a message value to a candle. Values are REST candles, minute
+// candles carrying a unit, or tickers, either from REST or from the websocket
+// which names the market "code". Tickers become the day candle in progress.
+func Decode(value []byte) (candle.Candle, error) {
+	var p probe
+	if err := json.Unmarshal(value, &p); err != nil {
+		return nil, errors.Wrap(err, "Decode Invalid Json Error")
+	}
+	switch {
+	case p.CandleDateTimeUtc != "":
+		var c upbit.MarketCandle
+		if err := json.Unmarshal(value, &c); err != nil {
+			return nil, errors.Wrap(err, "Decode Invalid Candle Error")
+		}
+		if c.Unit != nil {
+			m, err := candle.NewFromUpbitMinuteCandle(c)
+			if err != nil {
+				return nil, err
+			}
+			return *m, nil
+		}
+		d, err := candle.NewFromUpbitDayCandle(c)
+		if err != nil {
+			return nil, err
+		}
+		return *d, nil
+	case p.TradeDate != "":
+		var t upbit.MarketTicker
+		if err := json.Unmarshal(value, &t); err != nil {
+			return nil, errors.Wrap(err, "Decode Invalid Ticker Error")
+		}
+		if t.MarketName == "" {
+			t.MarketName = p.Code
+		}
+		d, err := candle.NewFromUpbitTicker(t)
+		if err != nil {
+			return nil, err
+		}
+		return *d, nil
+	}
+	return nil, errors.Errorf("Decode Unknown Message: %v", string(value))
+}
+
+type key struct {
+	marketId       string
+	unit           int
+	tradeTimestamp int64
+}
+
+// batch holds the latest candle of each key, so that frequent tickers of the
+// same day only write once, and the messages to commit once written.
+type batch struct {
+	minutes  map[key]candle.MinuteCandle
+	days     map[key]candle.DayCandle
+	order    []key
+	messages []kafka.Message
+}
+
+func newBatch() *batch {
+	return &batch{minutes: make(map[key]candle.MinuteCandle), days: make(map[key]candle.DayCandle)}
+}
+
+func (b *batch) add(m kafka.Message, c candle.Candle) {
+	b.messages = append(b.messages, m)
+	switch c := c.(type) {
+	case candle.MinuteCandle:
+		k := key{c.MarketId, c.Unit, c.TradeTimestamp}
+		if _, ok := b.minutes[k]; !ok {
+			b.order = append(b.order, k)
+		}
+		b.minutes[k] = c
+	case candle.DayCandle:
+		k := key{c.MarketId, 0, c.TradeTimestamp}
+		if _, ok := b.days[k]; !ok {
+			b.order = append(b.order, k)
+		}
+		b.days[k] = c
+	}
+}
+
+func (b *batch) models() (minutes []mongo.WriteModel, days []mongo.WriteModel) {
+	for _, k := range b.order {
+		if c, ok := b.minutes[k]; ok && k.unit != 0 {
+			// keyed as in LoadMinuteCandles, units share the collection
+			minutes = append(minutes, mongo.NewReplaceOneModel().SetFilter(bson.M{
+				"market_id":       c.MarketId,
+				"unit":            c.Unit,
+				"trade_timestamp": c.TradeTimestamp,
+			}).SetReplacement(c).SetUpsert(true))
+		}
+		if c, ok := b.days[k]; ok && k.unit == 0 {
+			days = append(days, mongo.NewReplaceOneModel().SetFilter(bson.M{
+				"market_id":       c.MarketId,
+				"trade_timestamp": c.TradeTimestamp,
+			}).SetReplacement(c).SetUpsert(true))
+		}
+	}
+	return
+}
+
+// flush upserts the batch, then commits its messages.
+func flush(ctx context.Context, r *kafka.Reader, minuteColl, dayColl *mongo.Collection, b *batch) error {
+	if len(b.messages) == 0 {
+		return nil
+	}
+	minutes, days := b.models()
+	opts := options.BulkWrite().SetOrdered(true)
+	if len(minutes) > 0 {
+		if _, err := minuteColl.BulkWrite(ctx, minutes, opts); err != nil {
+			return errors.Wrap(err, "flush minute candles")
+		}
+	}
+	if len(days) > 0 {
+		if _, err := dayColl.BulkWrite(ctx, days, opts); err != nil {
+			return errors.Wrap(err, "flush day candles")
+		}
+	}
+	if err := r.CommitMessages(ctx, b.messages...); err != nil {
+		return errors.Wrap(err, "commit offsets")
+	}
+	fmt.Printf("[%v] %v messages, %v minute candles, %v day candles written\n", time.Now().Format(time.RFC3339), len(b.messages), len(minutes), len(days))
+	return nil
+}
+
+// Run consumes r until ctx is done, upserting candles into minuteColl and
+// dayColl by batch and committing the offsets of each batch once written.
+// Messages that cannot be decoded are logged and skipped.
+func Run(ctx context.Context, r *kafka.Reader, minuteColl, dayColl *mongo.Collection, opts Options) error {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 1
+	}
+	b := newBatch()
+	deadline := time.Now().Add(opts.BatchTimeout)
+	for {
+		fetchCtx, cancel := context.WithDeadline(ctx, deadline)
+		m, err := r.FetchMessage(fetchCtx)
+		cancel()
+		switch {
+		case err == nil:
+			if c, err := Decode(m.Value); err != nil {
+				log.Printf("skip message at %v/%v: %v", m.Partition, m.Offset, err)
+				b.messages = append(b.messages, m)
+			} else {
+				b.add(m, c)
+			}
+		case ctx.Err() != nil:
+			// write what was consumed before stopping
+			return flush(context.Background(), r, minuteColl, dayColl, b)
+		case !errors.Is(err, context.DeadlineExceeded):
+			return errors.Wrap(err, "fetch message")
+		}
+		if len(b.messages) >= opts.BatchSize || !time.Now().Before(deadline) {
+			if err := flush(ctx, r, minuteColl, dayColl, b); err != nil {
+				return err
+			}
+			b = newBatch()
+			deadline = time.Now().Add(opts.BatchTimeout)
+		}
+	}
+}
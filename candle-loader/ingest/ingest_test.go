@@ -0,0 +1,54 @@
+package ingest
+
+import (
+	"ipoemi/candle-loader/trade/candle"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDecode(t *testing.T) {
+	minute, err := Decode([]byte(`{"market":"KRW-BTC","candle_date_time_utc":"2021-06-01T00:00:00","opening_price":1,"high_price":3,"low_price":0.5,"trade_price":2,"timestamp":1622505600000,"unit":5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := minute.(candle.MinuteCandle); !ok || c.Unit != 5 || c.MarketId != "KRW-BTC" || c.HighPrice != 3 {
+		t.Errorf("expect minute candle of unit 5 but return %#v", minute)
+	}
+
+	day, err := Decode([]byte(`{"market":"KRW-BTC","candle_date_time_utc":"2021-06-01T00:00:00","opening_price":1,"high_price":3,"low_price":0.5,"trade_price":2,"timestamp":1622505600000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := day.(candle.DayCandle); !ok {
+		t.Errorf("expect day candle but return %#v", day)
+	}
+
+	ticker, err := Decode([]byte(`{"type":"ticker","code":"KRW-ETH","trade_date":"20210601","opening_price":1,"high_price":3,"low_price":0.5,"trade_price":2,"timestamp":1622505600000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := ticker.(candle.DayCandle); !ok || c.MarketId != "KRW-ETH" || c.TradeTimestamp != day.GetTradeTimestamp() {
+		t.Errorf("expect day candle of KRW-ETH at %v but return %#v", day.GetTradeTimestamp(), ticker)
+	}
+
+	for _, v := range []string{`not json`, `{"market":"KRW-BTC"}`, `{"trade_date":"2021-06-01"}`} {
+		if c, err := Decode([]byte(v)); err == nil {
+			t.Errorf("expect error for %v but return %#v", v, c)
+		}
+	}
+}
+
+func TestBatchKeepsLastOfKey(t *testing.T) {
+	b := newBatch()
+	b.add(kafka.Message{Offset: 0}, candle.DayCandle{MarketId: "KRW-BTC", TradeTimestamp: 1, TradePrice: 1})
+	b.add(kafka.Message{Offset: 1}, candle.MinuteCandle{MarketId: "KRW-BTC", Unit: 1, TradeTimestamp: 1})
+	b.add(kafka.Message{Offset: 2}, candle.DayCandle{MarketId: "KRW-BTC", TradeTimestamp: 1, TradePrice: 2})
+	minutes, days := b.models()
+	if len(b.messages) != 3 || len(minutes) != 1 || len(days) != 1 {
+		t.Errorf("expect 3 messages, 1 minute and 1 day model but return %v, %v, %v", len(b.messages), len(minutes), len(days))
+	}
+	if c := b.days[key{"KRW-BTC", 0, 1}]; c.TradePrice != 2 {
+		t.Errorf("expect last trade price 2 but return %v", c.TradePrice)
+	}
+}
@@ -98,3 +98,19 @@ func NewFromUpbitDayCandle(c upbit.MarketCandle) (*DayCandle, error) {
 		TradePrice:     tradePrice,
 	}, nil
 }
+
+func NewFromUpbitTicker(t upbit.MarketTicker) (*DayCandle, error) {
+	d, err := time.Parse("20060102", t.TradeDate)
+	if err != nil {
+		return nil, TimeParseError(errors.Wrapf(err, "Invalid date format: %v", t.TradeDate))
+	}
+	return NewFromUpbitDayCandle(upbit.MarketCandle{
+		MarketName:        t.MarketName,
+		CandleDateTimeUtc: d.Format("2006-01-02T15:04:05"),
+		OpeningPrice:      t.OpeningPrice,
+		HighPrice:         t.HighPrice,
+		LowPrice:          t.LowPrice,
+		TradePrice:        t.TradePrice,
+		Timestamp:         t.Timestamp,
+	})
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"hbase-get/codec"
+	"hbase-get/diff"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuna/gohbase"
+)
+
+var (
+	diffOtherTable        string
+	diffOtherQuorum       string
+	diffReport            string
+	diffCompareTimestamps bool
+	diffMaxPrint          int
+
+	diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "compare the rows of the table with another table",
+		Long: `Compare the rows of --table with --other-table, on the cluster of
+--other-quorum, scanning both in key order.
+
+Rows missing on either side and rows whose cells differ are printed, followed
+by a summary. Only the latest version of each cell is compared, and --limit
+caps the number of distinct keys compared across both tables. --report
+writes every difference and the summary to a JSON file. The command fails
+when the tables differ.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	addRangeFlags(diffCmd)
+	diffCmd.Flags().StringVar(&diffOtherTable, "other-table", "", "table to compare with (default --table)")
+	diffCmd.Flags().StringVar(&diffOtherQuorum, "other-quorum", "", "zookeeper quorum of the other table (default --quorum)")
+	diffCmd.Flags().StringVar(&diffReport, "report", "", "write a JSON report of every difference to this file")
+	diffCmd.Flags().BoolVar(&diffCompareTimestamps, "compare-timestamps", false, "also report equal values written at different timestamps")
+	diffCmd.Flags().IntVar(&diffMaxPrint, "max-print", 100, "maximum number of differences printed (0 means no limit)")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+type reportCell struct {
+	Timestamp uint64      `json:"timestamp"`
+	Value     interface{} `json:"value"`
+}
+
+type reportCellDiff struct {
+	Family    string      `json:"family"`
+	Qualifier string      `json:"qualifier"`
+	Left      *reportCell `json:"left"`
+	Right     *reportCell `json:"right"`
+}
+
+type reportDifference struct {
+	Kind  diff.Kind        `json:"kind"`
+	Row   string           `json:"row"`
+	Cells []reportCellDiff `json:"cells,omitempty"`
+}
+
+// newReportDifference decodes the keys and values of a difference for
+// display.
+func newReportDifference(decoders *codec.Columns, d diff.Difference) reportDifference {
+	r := reportDifference{Kind: d.Kind, Row: codec.ToStringBinary(d.Row)}
+	cell := func(c *diff.Cell, family, qualifier []byte) *reportCell {
+		if c == nil {
+			return nil
+		}
+		return &reportCell{Timestamp: c.Timestamp, Value: decodeValue(decoders, d.Row, family, qualifier, c.Value)}
+	}
+	for _, c := range d.Cells {
+		r.Cells = append(r.Cells, reportCellDiff{
+			Family:    codec.ToStringBinary(c.Family),
+			Qualifier: codec.ToStringBinary(c.Qualifier),
+			Left:      cell(c.Left, c.Family, c.Qualifier),
+			Right:     cell(c.Right, c.Family, c.Qualifier),
+		})
+	}
+	return r
+}
+
+func formatReportCell(c *reportCell) string {
+	if c == nil {
+		return "(absent)"
+	}
+	return fmt.Sprintf("%s @%d", formatValue(c.Value), c.Timestamp)
+}
+
+// differenceWriter prints differences in the --output format.
+type differenceWriter struct {
+	out  io.Writer
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newDifferenceWriter(out io.Writer) *differenceWriter {
+	w := &differenceWriter{out: out}
+	switch output {
+	case "jsonl":
+		w.json = json.NewEncoder(out)
+	case "csv":
+		w.csv = csv.NewWriter(out)
+		w.csv.Write([]string{"kind", "row", "family", "qualifier", "left", "right"})
+	}
+	return w
+}
+
+func (w *differenceWriter) write(d reportDifference) error {
+	switch {
+	case w.json != nil:
+		return w.json.Encode(d)
+	case w.csv != nil:
+		if len(d.Cells) == 0 {
+			return w.csv.Write([]string{string(d.Kind), d.Row, "", "", "", ""})
+		}
+		for _, c := range d.Cells {
+			if err := w.csv.Write([]string{string(d.Kind), d.Row, c.Family, c.Qualifier, formatReportCell(c.Left), formatReportCell(c.Right)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := fmt.Fprintf(w.out, "%s %s\n", d.Kind, d.Row); err != nil {
+		return err
+	}
+	for _, c := range d.Cells {
+		if _, err := fmt.Fprintf(w.out, "  %s:%s: %s != %s\n", c.Family, c.Qualifier, formatReportCell(c.Left), formatReportCell(c.Right)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *differenceWriter) summary(s diff.Summary) error {
+	switch {
+	case w.json != nil:
+		return w.json.Encode(map[string]diff.Summary{"summary": s})
+	case w.csv != nil:
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	t := tabwriter.NewWriter(w.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(t)
+	for _, row := range [][]string{
+		{"left rows", strconv.Itoa(s.LeftRows)},
+		{"right rows", strconv.Itoa(s.RightRows)},
+		{"matching", strconv.Itoa(s.Matching)},
+		{"only left", strconv.Itoa(s.OnlyLeft)},
+		{"only right", strconv.Itoa(s.OnlyRight)},
+		{"changed", fmt.Sprintf("%d (%d cells)", s.Changed, s.ChangedCells)},
+	} {
+		fmt.Fprintf(t, "%s\t%s\n", row[0], row[1])
+	}
+	return t.Flush()
+}
+
+// reportWriter streams the JSON report, differences first since the
+// summary is only known at the end.
+type reportWriter struct {
+	file  *os.File
+	out   *bufio.Writer
+	count int
+}
+
+func newReportWriter(path string, left, right diff.Side) (*reportWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &reportWriter{file: f, out: bufio.NewWriter(f)}
+	l, _ := json.Marshal(left.Table)
+	r, _ := json.Marshal(right.Table)
+	fmt.Fprintf(w.out, "{\"left\":%s,\"right\":%s,\"differences\":[", l, r)
+	return w, nil
+}
+
+func (w *reportWriter) write(d reportDifference) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if w.count > 0 {
+		w.out.WriteByte(',')
+	}
+	w.count++
+	w.out.WriteString("\n")
+	_, err = w.out.Write(b)
+	return err
+}
+
+func (w *reportWriter) close(s diff.Summary) error {
+	b, _ := json.Marshal(s)
+	fmt.Fprintf(w.out, "\n],\"summary\":%s}\n", b)
+	if err := w.out.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+func runDiff(ctx context.Context) error {
+	otherTable, otherQuorum := diffOtherTable, diffOtherQuorum
+	if otherTable == "" {
+		otherTable = table
+	}
+	if otherQuorum == "" {
+		otherQuorum = quorum
+	}
+	if otherTable == table && otherQuorum == quorum {
+		return errors.New("--other-table or --other-quorum must name another table")
+	}
+	opts, err := rangeOptions()
+	if err != nil {
+		return err
+	}
+	decoders, err := newDecoders()
+	if err != nil {
+		return err
+	}
+
+	leftClient := newClient()
+	defer leftClient.Close()
+	rightClient := leftClient
+	if otherQuorum != quorum {
+		rightClient = gohbase.NewClient(otherQuorum)
+		defer rightClient.Close()
+	}
+	left := diff.Side{Client: leftClient, Table: table}
+	right := diff.Side{Client: rightClient, Table: otherTable}
+
+	var report *reportWriter
+	if diffReport != "" {
+		if report, err = newReportWriter(diffReport, left, right); err != nil {
+			return err
+		}
+	}
+	w := newDifferenceWriter(os.Stdout)
+	printed := 0
+	s, err := diff.Compare(ctx, left, right, diff.Options{Scan: opts, CompareTimestamps: diffCompareTimestamps}, func(d diff.Difference) error {
+		r := newReportDifference(decoders, d)
+		if report != nil {
+			if err := report.write(r); err != nil {
+				return err
+			}
+		}
+		if diffMaxPrint > 0 && printed >= diffMaxPrint {
+			return nil
+		}
+		printed++
+		return w.write(r)
+	})
+	if report != nil {
+		if cerr := report.close(s); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to write report: %w", cerr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.summary(s); err != nil {
+		return err
+	}
+	if !s.Equal() {
+		return fmt.Errorf("%d rows differ", s.Differing)
+	}
+	return nil
+}
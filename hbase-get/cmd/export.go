@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"hbase-get/dump"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOptions     dump.ExportOptions
+	exportMaxVersions uint32
+	importOptions     dump.ImportOptions
+	importDir         string
+
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "copy a range of the table to local files",
+		Long: `Copy a range of the table to local files, keeping every family, qualifier,
+timestamp and version of the cells.
+
+--dir receives a manifest.json and numbered parts, in jsonl format one row per
+line and in family-jsonl format one file per family with one cell per line.
+Exporting again to the same directory with the same range, --columns, --from,
+--to and --limit resumes an interrupted export after its last complete part.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if exportOptions.Dir == "" {
+				return errors.New(`required flag(s) "dir" not set`)
+			}
+			return runExport(cmd.Context())
+		},
+	}
+
+	importCmd = &cobra.Command{
+		Use:   "import",
+		Short: "write the rows of an export to the table",
+		Long: `Write the rows of an export to the table, which may be another table or on
+another cluster than the exported one, keeping the timestamps of the cells.
+
+Progress is checkpointed in the export directory per destination table, so
+importing again resumes an interrupted import. An interrupted export is
+refused unless --allow-incomplete is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if importDir == "" {
+				return errors.New(`required flag(s) "dir" not set`)
+			}
+			return runImport(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	addRangeFlags(exportCmd)
+	exportCmd.Flags().StringVar(&exportOptions.Dir, "dir", "", "directory to write the export to")
+	exportCmd.Flags().StringVar(&exportOptions.Format, "format", "jsonl", "file format: "+strings.Join(dump.Formats, " or "))
+	exportCmd.Flags().BoolVar(&exportOptions.Gzip, "gzip", false, "gzip the files")
+	exportCmd.Flags().IntVar(&exportOptions.RowsPerPart, "rows-per-part", 100000, "number of rows per part")
+	exportCmd.Flags().Uint32Var(&exportMaxVersions, "max-versions", 0, "number of versions per cell (default all)")
+
+	importCmd.Flags().StringVar(&importDir, "dir", "", "directory of the export")
+	importCmd.Flags().IntVar(&importOptions.BatchSize, "batch-size", 500, "number of lines written concurrently and between checkpoints")
+	importCmd.Flags().BoolVar(&importOptions.Restart, "restart", false, "ignore the checkpoint of a previous import")
+	importCmd.Flags().BoolVar(&importOptions.AllowIncomplete, "allow-incomplete", false, "import the complete parts of an interrupted export")
+
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
+
+func runExport(ctx context.Context) error {
+	opts, err := rangeOptions()
+	if err != nil {
+		return err
+	}
+	opts.MaxVersions = exportMaxVersions
+	exportOptions.Progress = func(m *dump.Manifest) {
+		log.Printf("part %d written, %d rows exported\n", len(m.Parts), m.Rows())
+	}
+
+	client := newClient()
+	defer client.Close()
+	m, err := dump.Export(ctx, client, table, opts, exportOptions)
+	if err != nil {
+		return err
+	}
+	log.Printf("export of %s complete, %d rows in %d parts\n", table, m.Rows(), len(m.Parts))
+	return nil
+}
+
+func runImport(ctx context.Context) error {
+	importOptions.Progress = func(lines int) {
+		log.Printf("%d lines imported\n", lines)
+	}
+	client := newClient()
+	defer client.Close()
+	lines, err := dump.Import(ctx, client, table, importDir, importOptions)
+	if err != nil {
+		return err
+	}
+	log.Printf("import into %s complete, %d lines imported\n", table, lines)
+	return nil
+}
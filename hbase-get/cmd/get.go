@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"hbase-get/scan"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+var (
+	getMaxVersions uint32
+
+	getCmd = &cobra.Command{
+		Use:   "get ROW...",
+		Short: "print rows by key",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGet(cmd.Context(), args)
+		},
+	}
+)
+
+func init() {
+	getCmd.Flags().Uint32Var(&getMaxVersions, "max-versions", 0, "number of versions per cell (default 1)")
+
+	rootCmd.AddCommand(getCmd)
+}
+
+func runGet(ctx context.Context, rows []string) (err error) {
+	cols, err := scan.ParseColumns(columns)
+	if err != nil {
+		return err
+	}
+	options := make([]func(hrpc.Call) error, 0)
+	if len(cols) > 0 {
+		options = append(options, hrpc.Families(cols))
+	}
+	if getMaxVersions > 0 {
+		options = append(options, hrpc.MaxVersions(getMaxVersions))
+	}
+
+	decoders, err := newDecoders()
+	if err != nil {
+		return err
+	}
+	client := newClient()
+	defer client.Close()
+	w := newRowWriter(os.Stdout, decoders)
+	defer func() {
+		if ferr := w.Flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+	}()
+	for _, key := range rows {
+		get, err := hrpc.NewGetStr(ctx, table, key, options...)
+		if err != nil {
+			return fmt.Errorf("invalid get of %s: %w", key, err)
+		}
+		result, err := client.Get(get)
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", key, err)
+		}
+		if len(result.Cells) == 0 {
+			log.Printf("row %s not found\n", key)
+			continue
+		}
+		if err := w.Write(scan.Row{Key: []byte(key), Cells: result.Cells}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"hbase-get/codec"
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// output is the --output flag.
+var output string
+
+func checkOutput() error {
+	switch output {
+	case "table", "jsonl", "csv":
+		return nil
+	}
+	return fmt.Errorf("unknown output %q", output)
+}
+
+// rowWriter prints rows in the --output format: one line per cell for table
+// and csv, one JSON object per row for jsonl.
+type rowWriter struct {
+	decoders *codec.Columns
+	table    *tabwriter.Writer
+	csv      *csv.Writer
+	json     *json.Encoder
+}
+
+func newRowWriter(w io.Writer, decoders *codec.Columns) *rowWriter {
+	rw := &rowWriter{decoders: decoders}
+	switch output {
+	case "jsonl":
+		rw.json = json.NewEncoder(w)
+	case "csv":
+		rw.csv = csv.NewWriter(w)
+		rw.csv.Write([]string{"row", "family", "qualifier", "timestamp", "value"})
+	default:
+		rw.table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(rw.table, "ROW\tCOLUMN\tTIMESTAMP\tVALUE")
+	}
+	return rw
+}
+
+type jsonCell struct {
+	Family    string      `json:"family"`
+	Qualifier string      `json:"qualifier"`
+	Timestamp uint64      `json:"timestamp"`
+	Value     interface{} `json:"value"`
+}
+
+type jsonRow struct {
+	Row   string     `json:"row"`
+	Cells []jsonCell `json:"cells"`
+}
+
+func timestamp(ts *uint64) uint64 {
+	if ts == nil {
+		return 0
+	}
+	return *ts
+}
+
+// decodeValue decodes a cell value with the decoder of its column. Values
+// the decoder rejects are logged and printed as binary strings.
+func decodeValue(decoders *codec.Columns, key, family, qualifier, value []byte) interface{} {
+	v, err := decoders.Decode(string(family), string(qualifier), value)
+	if err != nil {
+		log.Printf("failed to decode %s %s:%s: %v\n", codec.ToStringBinary(key), family, qualifier, err)
+		return codec.ToStringBinary(value)
+	}
+	return v
+}
+
+func (w *rowWriter) decode(key []byte, c *hrpc.Cell) interface{} {
+	return decodeValue(w.decoders, key, c.Family, c.Qualifier, c.Value)
+}
+
+// formatValue formats a decoded value for the table and csv outputs.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func (w *rowWriter) Write(row scan.Row) error {
+	key := codec.ToStringBinary(row.Key)
+	switch {
+	case w.json != nil:
+		r := jsonRow{Row: key, Cells: make([]jsonCell, 0, len(row.Cells))}
+		for _, c := range row.Cells {
+			r.Cells = append(r.Cells, jsonCell{
+				Family:    codec.ToStringBinary(c.Family),
+				Qualifier: codec.ToStringBinary(c.Qualifier),
+				Timestamp: timestamp(c.Timestamp),
+				Value:     w.decode(row.Key, c),
+			})
+		}
+		return w.json.Encode(r)
+	case w.csv != nil:
+		for _, c := range row.Cells {
+			ts := strconv.FormatUint(timestamp(c.Timestamp), 10)
+			record := []string{key, codec.ToStringBinary(c.Family), codec.ToStringBinary(c.Qualifier), ts, formatValue(w.decode(row.Key, c))}
+			if err := w.csv.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, c := range row.Cells {
+		fmt.Fprintf(w.table, "%s\t%s:%s\t%d\t%s\n", key, codec.ToStringBinary(c.Family), codec.ToStringBinary(c.Qualifier), timestamp(c.Timestamp), formatValue(w.decode(row.Key, c)))
+	}
+	return nil
+}
+
+func (w *rowWriter) Flush() error {
+	switch {
+	case w.csv != nil:
+		w.csv.Flush()
+		return w.csv.Error()
+	case w.table != nil:
+		return w.table.Flush()
+	}
+	return nil
+}
+
+// printValue prints a single value, such as a count, in the --output format.
+func printValue(name string, v interface{}) error {
+	switch output {
+	case "jsonl":
+		return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{name: v})
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{name})
+		w.Write([]string{fmt.Sprint(v)})
+		w.Flush()
+		return w.Error()
+	}
+	_, err := fmt.Printf("%v\n", v)
+	return err
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+var (
+	putCmd = &cobra.Command{
+		Use:   "put ROW FAMILY:QUALIFIER=VALUE...",
+		Short: "write cells of a row",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPut(cmd.Context(), args[0], args[1:])
+		},
+	}
+
+	deleteCmd = &cobra.Command{
+		Use:   "delete ROW [FAMILY[:QUALIFIER]...]",
+		Short: "delete a row, or all versions of some of its families or columns",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDelete(cmd.Context(), args[0], args[1:])
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(putCmd, deleteCmd)
+}
+
+// parseCells parses FAMILY:QUALIFIER=VALUE arguments.
+func parseCells(args []string) (map[string]map[string][]byte, error) {
+	values := make(map[string]map[string][]byte)
+	for _, arg := range args {
+		i := strings.IndexByte(arg, '=')
+		j := strings.IndexByte(arg, ':')
+		if i < 0 || j <= 0 || j > i {
+			return nil, fmt.Errorf("invalid cell %q, expected FAMILY:QUALIFIER=VALUE", arg)
+		}
+		family, qualifier := arg[:j], arg[j+1:i]
+		if values[family] == nil {
+			values[family] = make(map[string][]byte)
+		}
+		values[family][qualifier] = []byte(arg[i+1:])
+	}
+	return values, nil
+}
+
+func runPut(ctx context.Context, key string, args []string) error {
+	values, err := parseCells(args)
+	if err != nil {
+		return err
+	}
+	put, err := hrpc.NewPutStr(ctx, table, key, values)
+	if err != nil {
+		return fmt.Errorf("invalid put of %s: %w", key, err)
+	}
+	client := newClient()
+	defer client.Close()
+	if _, err := client.Put(put); err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	return nil
+}
+
+func runDelete(ctx context.Context, key string, args []string) error {
+	values := make(map[string]map[string][]byte)
+	for _, arg := range args {
+		family, qualifier := arg, ""
+		if i := strings.IndexByte(arg, ':'); i >= 0 {
+			family, qualifier = arg[:i], arg[i+1:]
+		}
+		if family == "" {
+			return fmt.Errorf("invalid column %q, expected FAMILY[:QUALIFIER]", arg)
+		}
+		if qualifier == "" {
+			// a family without qualifiers deletes the whole family
+			values[family] = nil
+			continue
+		}
+		if _, ok := values[family]; ok && values[family] == nil {
+			continue
+		}
+		if values[family] == nil {
+			values[family] = make(map[string][]byte)
+		}
+		values[family][qualifier] = nil
+	}
+	del, err := hrpc.NewDelStr(ctx, table, key, values)
+	if err != nil {
+		return fmt.Errorf("invalid delete of %s: %w", key, err)
+	}
+	client := newClient()
+	defer client.Close()
+	if _, err := client.Delete(del); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
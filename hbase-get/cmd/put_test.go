@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCells(t *testing.T) {
+	values, err := parseCells([]string{"cf:a=1", "cf:b=x=y", "meta:=empty qualifier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]map[string][]byte{
+		"cf":   {"a": []byte("1"), "b": []byte("x=y")},
+		"meta": {"": []byte("empty qualifier")},
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("expect %v but return %v", expect, values)
+	}
+	for _, arg := range []string{"cf:a", "a=1", ":a=1", "cf=a:1"} {
+		if _, err := parseCells([]string{arg}); err == nil {
+			t.Errorf("expect error for %q", arg)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"hbase-get/codec"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuna/gohbase"
+)
+
+// DefaultQuorum is the zookeeper quorum used without --quorum or
+// $HBASE_QUORUM.
+const DefaultQuorum = "localhost:2181"
+
+var (
+	quorum  string
+	table   string
+	columns string
+
+	decoderSpecs   []string
+	decoderOptions codec.Options
+
+	rootCmd = &cobra.Command{
+		Use:          "hbase-get",
+		Short:        "read and write rows of an HBase table",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if table == "" {
+				return errors.New(`required flag(s) "table" not set`)
+			}
+			return checkOutput()
+		},
+	}
+)
+
+// Execute runs the command line, cancelling the context of the running
+// command on interrupt.
+func Execute() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+func init() {
+	defaultQuorum := DefaultQuorum
+	if q, ok := os.LookupEnv("HBASE_QUORUM"); ok {
+		defaultQuorum = q
+	}
+	rootCmd.PersistentFlags().StringVarP(&quorum, "quorum", "q", defaultQuorum, "zookeeper quorum, as comma separated host:port ($HBASE_QUORUM)")
+	rootCmd.PersistentFlags().StringVarP(&table, "table", "t", "", "table")
+	rootCmd.PersistentFlags().StringVarP(&columns, "columns", "c", "", "columns to read, as comma separated family or family:qualifier (default all)")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "output format: table, jsonl or csv")
+	rootCmd.PersistentFlags().StringArrayVarP(&decoderSpecs, "decode", "d", []string{}, "value decoder, as NAME for all columns or FAMILY[:QUALIFIER]=NAME, one of "+strings.Join(codec.Names(), ", ")+" (repeatable, default string)")
+	rootCmd.PersistentFlags().StringVar(&decoderOptions.ProtoDescriptorSet, "proto-descriptor", "", "FileDescriptorSet file for the protobuf decoder")
+	rootCmd.PersistentFlags().StringVar(&decoderOptions.ProtoMessage, "proto-message", "", "full name of the message type for the protobuf decoder")
+}
+
+func newDecoders() (*codec.Columns, error) {
+	return codec.ParseColumns(decoderSpecs, decoderOptions)
+}
+
+func newClient() gohbase.Client {
+	return gohbase.NewClient(quorum)
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"hbase-get/codec"
+	"hbase-get/scan"
+
+	"github.com/spf13/cobra"
+	"github.com/tsuna/gohbase/filter"
+)
+
+var (
+	scanPrefix      string
+	scanStart       string
+	scanStop        string
+	scanFrom        string
+	scanTo          string
+	scanLimit       int
+	scanMaxVersions uint32
+
+	scanWorkers int
+	scanOrdered bool
+
+	scanKeyTimeFrom   string
+	scanKeyTimeTo     string
+	scanKeyTimeFormat string
+
+	scanCmd = &cobra.Command{
+		Use:   "scan",
+		Short: "print the rows of a range of the table",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScan(cmd.Context())
+		},
+	}
+
+	regionsCmd = &cobra.Command{
+		Use:   "regions",
+		Short: "list the regions of the table",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRegions(cmd.Context())
+		},
+	}
+
+	countCmd = &cobra.Command{
+		Use:   "count",
+		Short: "count the rows of a range of the table",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCount(cmd.Context())
+		},
+	}
+)
+
+// addRangeFlags adds the flags selecting the rows of scan and count.
+func addRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&scanPrefix, "prefix", "", "only rows starting with this prefix")
+	cmd.Flags().StringVar(&scanStart, "start", "", "first row, inclusive")
+	cmd.Flags().StringVar(&scanStop, "stop", "", "last row, exclusive")
+	cmd.Flags().StringVar(&scanFrom, "from", "", "only cells written at or after this time, as RFC3339 or epoch milliseconds")
+	cmd.Flags().StringVar(&scanTo, "to", "", "only cells written before this time, as RFC3339 or epoch milliseconds")
+	cmd.Flags().IntVarP(&scanLimit, "limit", "n", 0, "maximum number of rows (0 means no limit)")
+	cmd.Flags().StringVar(&scanKeyTimeFrom, "key-time-from", "", "for keys made of --prefix and a reverse timestamp, only rows at or after this time, as RFC3339 or epoch milliseconds")
+	cmd.Flags().StringVar(&scanKeyTimeTo, "key-time-to", "", "for keys made of --prefix and a reverse timestamp, only rows before this time, as RFC3339 or epoch milliseconds")
+	cmd.Flags().StringVar(&scanKeyTimeFormat, "key-time-format", "decimal", "reverse timestamp encoding in keys: decimal (20 digits) or binary (8 bytes)")
+}
+
+// addParallelFlags adds the flags scanning regions concurrently.
+func addParallelFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&scanWorkers, "workers", "w", 1, "number of regions scanned concurrently")
+}
+
+func init() {
+	addRangeFlags(scanCmd)
+	addParallelFlags(scanCmd)
+	scanCmd.Flags().BoolVar(&scanOrdered, "ordered", false, "with --workers, print rows in key order instead of as they arrive")
+	scanCmd.Flags().Uint32Var(&scanMaxVersions, "max-versions", 0, "number of versions per cell (default 1)")
+	addRangeFlags(countCmd)
+	addParallelFlags(countCmd)
+
+	rootCmd.AddCommand(scanCmd, countCmd, regionsCmd)
+}
+
+// parseTime parses a time given as RFC3339 or epoch milliseconds, the empty
+// string being the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, expected RFC3339 or epoch milliseconds", s)
+	}
+	return t, nil
+}
+
+// rangeOptions returns the scan options of the range flags and --columns.
+func rangeOptions() (scan.Options, error) {
+	cols, err := scan.ParseColumns(columns)
+	if err != nil {
+		return scan.Options{}, err
+	}
+	opts := scan.Options{
+		StartRow: []byte(scanStart),
+		StopRow:  []byte(scanStop),
+		Prefix:   []byte(scanPrefix),
+		Columns:  cols,
+		Limit:    scanLimit,
+	}
+	if opts.From, err = parseTime(scanFrom); err != nil {
+		return opts, err
+	}
+	if opts.To, err = parseTime(scanTo); err != nil {
+		return opts, err
+	}
+	if scanKeyTimeFrom != "" || scanKeyTimeTo != "" {
+		if scanStart != "" || scanStop != "" {
+			return opts, errors.New("--key-time-from and --key-time-to cannot be combined with --start and --stop")
+		}
+		format, err := codec.ParseReverseKeyFormat(scanKeyTimeFormat)
+		if err != nil {
+			return opts, err
+		}
+		from, err := parseTime(scanKeyTimeFrom)
+		if err != nil {
+			return opts, err
+		}
+		to, err := parseTime(scanKeyTimeTo)
+		if err != nil {
+			return opts, err
+		}
+		opts.StartRow, opts.StopRow = format.ReverseRange(opts.Prefix, from, to)
+	}
+	return opts, nil
+}
+
+// each scans with scan.Each, or scan.EachParallel with more than one worker,
+// logging the progress of regions.
+func each(ctx context.Context, client scan.Client, opts scan.Options, ordered bool, fn func(scan.Row) error) error {
+	if scanWorkers <= 1 {
+		return scan.Each(ctx, client, table, opts, fn)
+	}
+	popts := scan.ParallelOptions{
+		Workers: scanWorkers,
+		Ordered: ordered,
+		Progress: func(p scan.Progress) {
+			if p.Err != nil {
+				log.Printf("%d/%d regions, %d rows: %v\n", p.Done, p.Regions, p.Rows, p.Err)
+				return
+			}
+			log.Printf("%d/%d regions, %d rows\n", p.Done, p.Regions, p.Rows)
+		},
+	}
+	return scan.EachParallel(ctx, client, table, opts, popts, fn)
+}
+
+func runRegions(ctx context.Context) error {
+	client := newClient()
+	defer client.Close()
+	regions, err := scan.Regions(ctx, client, table)
+	if err != nil {
+		return err
+	}
+	switch output {
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range regions {
+			err := enc.Encode(map[string]string{
+				"name":      codec.ToStringBinary(r.Name),
+				"start_key": codec.ToStringBinary(r.StartKey),
+				"stop_key":  codec.ToStringBinary(r.StopKey),
+				"server":    r.Server,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"name", "start_key", "stop_key", "server"})
+		for _, r := range regions {
+			w.Write([]string{codec.ToStringBinary(r.Name), codec.ToStringBinary(r.StartKey), codec.ToStringBinary(r.StopKey), r.Server})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "START-KEY\tSTOP-KEY\tSERVER\tNAME")
+	for _, r := range regions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", codec.ToStringBinary(r.StartKey), codec.ToStringBinary(r.StopKey), r.Server, codec.ToStringBinary(r.Name))
+	}
+	return w.Flush()
+}
+
+func runScan(ctx context.Context) error {
+	opts, err := rangeOptions()
+	if err != nil {
+		return err
+	}
+	opts.MaxVersions = scanMaxVersions
+
+	decoders, err := newDecoders()
+	if err != nil {
+		return err
+	}
+	client := newClient()
+	defer client.Close()
+	w := newRowWriter(os.Stdout, decoders)
+	err = each(ctx, client, opts, scanOrdered, w.Write)
+	if ferr := w.Flush(); ferr != nil && err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func runCount(ctx context.Context) error {
+	opts, err := rangeOptions()
+	if err != nil {
+		return err
+	}
+	// only the first cell of each row, without its value, is needed
+	opts.Filter = filter.NewList(filter.MustPassAll, filter.NewFirstKeyOnlyFilter(), filter.NewKeyOnlyFilter(false))
+	opts.Caching = 1000
+
+	client := newClient()
+	defer client.Close()
+	count := 0
+	err = each(ctx, client, opts, false, func(row scan.Row) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return printValue("count", count)
+}
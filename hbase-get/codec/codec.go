@@ -0,0 +1,99 @@
+// Package codec decodes HBase cell values, stored as the bytes written by
+// the HBase Bytes utilities or as JSON and protobuf documents, into values
+// for display.
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Decoder converts a cell value to a value that can be printed and encoded
+// as JSON.
+type Decoder interface {
+	Decode(value []byte) (interface{}, error)
+}
+
+// DecoderFunc adapts a function to a Decoder.
+type DecoderFunc func(value []byte) (interface{}, error)
+
+func (f DecoderFunc) Decode(value []byte) (interface{}, error) {
+	return f(value)
+}
+
+// Options configures the decoders created by New.
+type Options struct {
+	// ProtoDescriptorSet is a FileDescriptorSet file, as written by
+	// protoc --include_imports --descriptor_set_out, and ProtoMessage the full
+	// name of the message type of the values.
+	ProtoDescriptorSet string
+	ProtoMessage       string
+}
+
+var decoders = map[string]Decoder{
+	"string": DecoderFunc(func(v []byte) (interface{}, error) { return string(v), nil }),
+	"binary": DecoderFunc(func(v []byte) (interface{}, error) { return ToStringBinary(v), nil }),
+	"short":  fixed(2, func(v []byte) interface{} { return int16(binary.BigEndian.Uint16(v)) }),
+	"int":    fixed(4, func(v []byte) interface{} { return int32(binary.BigEndian.Uint32(v)) }),
+	"long":   fixed(8, func(v []byte) interface{} { return int64(binary.BigEndian.Uint64(v)) }),
+	"float":  fixed(4, func(v []byte) interface{} { return math.Float32frombits(binary.BigEndian.Uint32(v)) }),
+	"double": fixed(8, func(v []byte) interface{} { return math.Float64frombits(binary.BigEndian.Uint64(v)) }),
+	"json":   DecoderFunc(decodeJSON),
+}
+
+// Names lists the decoders accepted by New.
+func Names() []string {
+	names := []string{"protobuf"}
+	for name := range decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the decoder called name.
+func New(name string, opts Options) (Decoder, error) {
+	if name == "protobuf" {
+		return NewProtobuf(opts.ProtoDescriptorSet, opts.ProtoMessage)
+	}
+	if d, ok := decoders[name]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("unknown decoder %q, expected one of %s", name, strings.Join(Names(), ", "))
+}
+
+// fixed decodes big endian numbers of size bytes, as written by
+// Bytes.toBytes.
+func fixed(size int, decode func([]byte) interface{}) Decoder {
+	return DecoderFunc(func(v []byte) (interface{}, error) {
+		if len(v) != size {
+			return nil, fmt.Errorf("expected %d bytes but got %d", size, len(v))
+		}
+		return decode(v), nil
+	})
+}
+
+func decodeJSON(v []byte) (interface{}, error) {
+	if !json.Valid(v) {
+		return nil, fmt.Errorf("invalid json: %s", ToStringBinary(v))
+	}
+	return json.RawMessage(v), nil
+}
+
+// ToStringBinary formats v like Bytes.toStringBinary of HBase: printable
+// ASCII as is and any other byte as \xHH.
+func ToStringBinary(v []byte) string {
+	var b strings.Builder
+	for _, c := range v {
+		if c >= ' ' && c <= '~' && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "\\x%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDecoders(t *testing.T) {
+	cases := []struct {
+		name   string
+		value  []byte
+		expect interface{}
+	}{
+		{"string", []byte("abc"), "abc"},
+		{"binary", []byte("a\x00\\\xff"), `a\x00\x5C\xFF`},
+		{"short", []byte{0xff, 0xfe}, int16(-2)},
+		{"int", []byte{0, 0, 1, 0}, int32(256)},
+		{"long", []byte{0, 0, 0, 0, 0, 0x98, 0xdb, 0x47}, int64(10017607)},
+		{"float", []byte{0x3f, 0xc0, 0, 0}, float32(1.5)},
+		{"double", []byte{0x40, 0x04, 0, 0, 0, 0, 0, 0}, float64(2.5)},
+	}
+	for _, c := range cases {
+		d, err := New(c.name, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := d.Decode(c.value)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if v != c.expect {
+			t.Errorf("%s: expect %#v but return %#v", c.name, c.expect, v)
+		}
+	}
+
+	long, _ := New("long", Options{})
+	if _, err := long.Decode([]byte{1, 2, 3}); err == nil {
+		t.Errorf("expect error for a 3 bytes long")
+	}
+	j, _ := New("json", Options{})
+	if v, err := j.Decode([]byte(`{"a":1}`)); err != nil || string(v.(json.RawMessage)) != `{"a":1}` {
+		t.Errorf("expect {\"a\":1} but return %v, %v", v, err)
+	}
+	if _, err := New("protobuf", Options{}); err == nil {
+		t.Errorf("expect error for protobuf without descriptor")
+	}
+	if _, err := New("varint", Options{}); err == nil {
+		t.Errorf("expect error for an unknown decoder")
+	}
+}
+
+func TestColumns(t *testing.T) {
+	c, err := ParseColumns([]string{"binary", "cf=long", "cf:name=string"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Decode("cf", "name", []byte("x")); v != "x" {
+		t.Errorf("expect x but return %v", v)
+	}
+	if v, _ := c.Decode("cf", "count", []byte{0, 0, 0, 0, 0, 0, 0, 7}); v != int64(7) {
+		t.Errorf("expect 7 but return %v", v)
+	}
+	if v, _ := c.Decode("meta", "x", []byte{1}); v != `\x01` {
+		t.Errorf("expect \\x01 but return %v", v)
+	}
+	if _, err := ParseColumns([]string{"cf=nope"}, Options{}); err == nil {
+		t.Errorf("expect error for an unknown decoder")
+	}
+}
+
+func TestReverseKey(t *testing.T) {
+	ts := time.Unix(0, 1612357354988*int64(time.Millisecond))
+	if r := ReverseTimestamp(ts); r != 9223370424497420819 {
+		t.Errorf("expect 9223370424497420819 but return %v", r)
+	}
+	key := Decimal.ReverseKey([]byte("973_"), ts)
+	if string(key) != "973_09223370424497420819" {
+		t.Errorf("expect 973_09223370424497420819 but return %s", key)
+	}
+	for _, f := range []ReverseKeyFormat{Decimal, Binary} {
+		key := append(f.ReverseKey([]byte("p"), ts), "_suffix"...)
+		parsed, err := f.ParseReverseKey(key, 1)
+		if err != nil || !parsed.Equal(ts) {
+			t.Errorf("expect %v but return %v, %v", ts, parsed, err)
+		}
+
+		from, to := ts.Add(-time.Hour), ts.Add(time.Hour)
+		start, stop := f.ReverseRange([]byte("p"), from, to)
+		for _, c := range []struct {
+			t      time.Time
+			within bool
+		}{
+			{ts, true},
+			{from, true},
+			{to.Add(-time.Millisecond), true},
+			{to, false},
+			{from.Add(-time.Millisecond), false},
+		} {
+			k := f.ReverseKey([]byte("p"), c.t)
+			within := bytes.Compare(k, start) >= 0 && bytes.Compare(k, stop) < 0
+			if within != c.within {
+				t.Errorf("expect %v within [from, to) to be %v", c.t, c.within)
+			}
+		}
+	}
+}
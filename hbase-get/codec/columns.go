@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Columns selects a decoder per column: the decoder of family:qualifier,
+// else the one of family, else the default.
+type Columns struct {
+	Default Decoder
+	columns map[string]Decoder
+}
+
+// ParseColumns parses decoder specs given as "family:qualifier=name",
+// "family=name" or "name", the latter setting the default.
+func ParseColumns(specs []string, opts Options) (*Columns, error) {
+	c := &Columns{Default: decoders["string"], columns: make(map[string]Decoder)}
+	for _, spec := range specs {
+		column, name := "", spec
+		if i := strings.LastIndexByte(spec, '='); i >= 0 {
+			column, name = spec[:i], spec[i+1:]
+		}
+		d, err := New(name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decoder %q: %w", spec, err)
+		}
+		if column == "" {
+			c.Default = d
+			continue
+		}
+		c.columns[column] = d
+	}
+	return c, nil
+}
+
+// Lookup returns the decoder of family:qualifier.
+func (c *Columns) Lookup(family, qualifier string) Decoder {
+	if d, ok := c.columns[family+":"+qualifier]; ok {
+		return d
+	}
+	if d, ok := c.columns[family]; ok {
+		return d
+	}
+	return c.Default
+}
+
+// Decode decodes the value of family:qualifier.
+func (c *Columns) Decode(family, qualifier string, value []byte) (interface{}, error) {
+	return c.Lookup(family, qualifier).Decode(value)
+}
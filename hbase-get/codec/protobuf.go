@@ -0,0 +1,61 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Protobuf decodes values holding a message of a type known by descriptor.
+type Protobuf struct {
+	message protoreflect.MessageDescriptor
+}
+
+// NewProtobuf loads messageName from a FileDescriptorSet file.
+func NewProtobuf(descriptorSetFile string, messageName string) (*Protobuf, error) {
+	if descriptorSetFile == "" || messageName == "" {
+		return nil, errors.New("protobuf decoder needs a descriptor set file and a message name")
+	}
+	b, err := ioutil.ReadFile(descriptorSetFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", descriptorSetFile, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", descriptorSetFile, err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("message %s not found in %s: %w", messageName, descriptorSetFile, err)
+	}
+	message, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", messageName)
+	}
+	return &Protobuf{message: message}, nil
+}
+
+// Decode returns the message as JSON.
+func (p *Protobuf) Decode(value []byte) (interface{}, error) {
+	msg := dynamicpb.NewMessage(p.message)
+	if err := proto.Unmarshal(value, msg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", p.message.FullName(), err)
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(b), nil
+}
@@ -0,0 +1,101 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Rows keyed by MaxInt64 - epoch milliseconds sort newest first. The reverse
+// timestamp is written either as 20 zero padded decimal digits, e.g.
+// 09223370424497420819 for 2021-02-03T13:02:34.988Z, or as 8 big endian
+// bytes. Both sort like the numbers they hold.
+
+// ReverseTimestamp returns MaxInt64 - t in epoch milliseconds.
+func ReverseTimestamp(t time.Time) int64 {
+	return math.MaxInt64 - t.UnixNano()/int64(time.Millisecond)
+}
+
+// FromReverseTimestamp returns the time of a reverse timestamp.
+func FromReverseTimestamp(r int64) time.Time {
+	return time.Unix(0, (math.MaxInt64-r)*int64(time.Millisecond))
+}
+
+// ReverseKeyFormat is how a reverse timestamp is written in a row key.
+type ReverseKeyFormat int
+
+const (
+	// Decimal writes 20 zero padded decimal digits.
+	Decimal ReverseKeyFormat = iota
+	// Binary writes 8 big endian bytes.
+	Binary
+)
+
+// ParseReverseKeyFormat parses "decimal" or "binary".
+func ParseReverseKeyFormat(s string) (ReverseKeyFormat, error) {
+	switch s {
+	case "decimal":
+		return Decimal, nil
+	case "binary":
+		return Binary, nil
+	}
+	return 0, fmt.Errorf("unknown reverse key format %q, expected decimal or binary", s)
+}
+
+func (f ReverseKeyFormat) size() int {
+	if f == Binary {
+		return 8
+	}
+	return 20
+}
+
+func (f ReverseKeyFormat) encode(r int64) []byte {
+	if f == Binary {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(r))
+		return b
+	}
+	return []byte(fmt.Sprintf("%020d", r))
+}
+
+// ReverseKey returns prefix followed by the reverse timestamp of t.
+func (f ReverseKeyFormat) ReverseKey(prefix []byte, t time.Time) []byte {
+	return append(append([]byte(nil), prefix...), f.encode(ReverseTimestamp(t))...)
+}
+
+// ParseReverseKey returns the time of a key made by ReverseKey with a
+// prefix of prefixLen bytes, ignoring anything after the reverse timestamp.
+func (f ReverseKeyFormat) ParseReverseKey(key []byte, prefixLen int) (time.Time, error) {
+	if len(key) < prefixLen+f.size() {
+		return time.Time{}, fmt.Errorf("key %s too short for a reverse timestamp", ToStringBinary(key))
+	}
+	b := key[prefixLen : prefixLen+f.size()]
+	var r int64
+	if f == Binary {
+		r = int64(binary.BigEndian.Uint64(b))
+	} else {
+		var err error
+		if r, err = strconv.ParseInt(string(b), 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid reverse timestamp in key %s: %w", ToStringBinary(key), err)
+		}
+	}
+	return FromReverseTimestamp(r), nil
+}
+
+// ReverseRange returns the start and stop rows of the keys of prefix whose
+// time is in [from, to). Newer times come first, so start is derived from
+// to and stop from from. A zero time leaves its side unbounded within the
+// prefix.
+func (f ReverseKeyFormat) ReverseRange(prefix []byte, from, to time.Time) (start, stop []byte) {
+	start = append([]byte(nil), prefix...)
+	if !to.IsZero() {
+		// the last millisecond before to
+		start = append(start, f.encode(ReverseTimestamp(to)+1)...)
+	}
+	if !from.IsZero() {
+		stop = append(append([]byte(nil), prefix...), f.encode(ReverseTimestamp(from)+1)...)
+	}
+	return start, stop
+}
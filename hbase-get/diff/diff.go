@@ -0,0 +1,235 @@
+// Package diff compares the rows of two HBase tables, possibly on two
+// clusters, by scanning both in key order.
+package diff
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// Side is a table to compare.
+type Side struct {
+	Client scan.Client
+	Table  string
+}
+
+// Kind is the kind of a Difference.
+type Kind string
+
+const (
+	// OnlyLeft is a row missing from the right table.
+	OnlyLeft Kind = "only-left"
+	// OnlyRight is a row missing from the left table.
+	OnlyRight Kind = "only-right"
+	// Changed is a row of both tables whose cells differ.
+	Changed Kind = "changed"
+)
+
+// Cell is the latest version of a cell, or nil when the column is absent.
+type Cell struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     []byte `json:"value"`
+}
+
+// CellDiff is a column whose cells differ.
+type CellDiff struct {
+	Family    []byte `json:"family"`
+	Qualifier []byte `json:"qualifier"`
+	Left      *Cell  `json:"left"`
+	Right     *Cell  `json:"right"`
+}
+
+// Difference is a row that differs between the tables.
+type Difference struct {
+	Kind  Kind       `json:"kind"`
+	Row   []byte     `json:"row"`
+	Cells []CellDiff `json:"cells,omitempty"`
+}
+
+// Summary counts the rows compared.
+type Summary struct {
+	LeftRows     int `json:"left_rows"`
+	RightRows    int `json:"right_rows"`
+	Matching     int `json:"matching"`
+	OnlyLeft     int `json:"only_left"`
+	OnlyRight    int `json:"only_right"`
+	Changed      int `json:"changed"`
+	ChangedCells int `json:"changed_cells"`
+	Differing    int `json:"differing"`
+}
+
+// Equal tells whether no difference was found.
+func (s Summary) Equal() bool {
+	return s.Differing == 0
+}
+
+// Options configures Compare.
+type Options struct {
+	// Scan selects the rows and columns compared on both sides. Only the
+	// latest version of each cell is compared. Its Limit is the number of
+	// distinct keys compared, whichever side they are on.
+	Scan scan.Options
+	// CompareTimestamps also reports cells of equal values written at
+	// different timestamps, which copies usually rewrite.
+	CompareTimestamps bool
+}
+
+// Compare scans left and right together and calls report with every
+// difference, in key order, until report returns an error.
+func Compare(ctx context.Context, left, right Side, opts Options, report func(Difference) error) (Summary, error) {
+	var s Summary
+	// both sides are scanned without limit, since a difference makes them
+	// reach the same key after a different number of rows
+	limit := opts.Scan.Limit
+	opts.Scan.MaxVersions, opts.Scan.Limit = 1, 0
+	l, err := scan.Open(ctx, left.Client, left.Table, opts.Scan)
+	if err != nil {
+		return s, err
+	}
+	defer l.Close()
+	r, err := scan.Open(ctx, right.Client, right.Table, opts.Scan)
+	if err != nil {
+		return s, err
+	}
+	defer r.Close()
+
+	lrow, lok, err := next(l)
+	if err != nil {
+		return s, err
+	}
+	rrow, rok, err := next(r)
+	if err != nil {
+		return s, err
+	}
+	for keys := 0; (lok || rok) && (limit <= 0 || keys < limit); keys++ {
+		var d *Difference
+		c := 0
+		switch {
+		case !rok:
+			c = -1
+		case !lok:
+			c = 1
+		default:
+			c = bytes.Compare(lrow.Key, rrow.Key)
+		}
+		switch {
+		case c < 0:
+			s.LeftRows++
+			s.OnlyLeft++
+			d = &Difference{Kind: OnlyLeft, Row: lrow.Key}
+		case c > 0:
+			s.RightRows++
+			s.OnlyRight++
+			d = &Difference{Kind: OnlyRight, Row: rrow.Key}
+		default:
+			s.LeftRows++
+			s.RightRows++
+			if cells := compareCells(lrow, rrow, opts.CompareTimestamps); len(cells) > 0 {
+				s.Changed++
+				s.ChangedCells += len(cells)
+				d = &Difference{Kind: Changed, Row: lrow.Key, Cells: cells}
+			} else {
+				s.Matching++
+			}
+		}
+		if d != nil {
+			s.Differing++
+			if err := report(*d); err != nil {
+				return s, err
+			}
+		}
+		if c <= 0 {
+			if lrow, lok, err = next(l); err != nil {
+				return s, err
+			}
+		}
+		if c >= 0 {
+			if rrow, rok, err = next(r); err != nil {
+				return s, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func next(it *scan.Iterator) (scan.Row, bool, error) {
+	row, err := it.Next()
+	if err == io.EOF {
+		return row, false, nil
+	}
+	return row, err == nil, err
+}
+
+type column struct {
+	family    string
+	qualifier string
+}
+
+// latest returns the latest version of each column of a row.
+func latest(row scan.Row) map[column]*hrpc.Cell {
+	cells := make(map[column]*hrpc.Cell, len(row.Cells))
+	for _, c := range row.Cells {
+		k := column{string(c.Family), string(c.Qualifier)}
+		if prev, ok := cells[k]; !ok || timestamp(c.Timestamp) > timestamp(prev.Timestamp) {
+			cells[k] = c
+		}
+	}
+	return cells
+}
+
+func timestamp(ts *uint64) uint64 {
+	if ts == nil {
+		return 0
+	}
+	return *ts
+}
+
+func toCell(c *hrpc.Cell) *Cell {
+	if c == nil {
+		return nil
+	}
+	return &Cell{Timestamp: timestamp(c.Timestamp), Value: c.Value}
+}
+
+// compareCells returns the columns of two rows with the same key that
+// differ, in column order.
+func compareCells(left, right scan.Row, compareTimestamps bool) []CellDiff {
+	l, r := latest(left), latest(right)
+	columns := make([]column, 0, len(l)+len(r))
+	for k := range l {
+		columns = append(columns, k)
+	}
+	for k := range r {
+		if _, ok := l[k]; !ok {
+			columns = append(columns, k)
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].family != columns[j].family {
+			return columns[i].family < columns[j].family
+		}
+		return columns[i].qualifier < columns[j].qualifier
+	})
+
+	diffs := make([]CellDiff, 0)
+	for _, k := range columns {
+		lc, rc := l[k], r[k]
+		if lc != nil && rc != nil && bytes.Equal(lc.Value, rc.Value) &&
+			(!compareTimestamps || timestamp(lc.Timestamp) == timestamp(rc.Timestamp)) {
+			continue
+		}
+		diffs = append(diffs, CellDiff{
+			Family:    []byte(k.family),
+			Qualifier: []byte(k.qualifier),
+			Left:      toCell(lc),
+			Right:     toCell(rc),
+		})
+	}
+	return diffs
+}
@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"hbase-get/scan"
+	"hbase-get/table"
+)
+
+// newClient returns a client of table name, with rows of cf cells built by
+// row.
+func newClient(t *testing.T, name string, rows ...[]cell) *table.Client {
+	t.Helper()
+	m := table.NewMemory(name, "cf")
+	for _, r := range rows {
+		for _, c := range r {
+			ts := time.Unix(0, int64(c.timestamp)*int64(time.Millisecond))
+			if err := m.Put(context.Background(), []byte(c.key), table.Values{"cf": {c.qualifier: []byte(c.value)}}, table.MutateOptions{Timestamp: ts}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return table.NewClient(m)
+}
+
+type cell struct {
+	key, qualifier string
+	timestamp      int
+	value          string
+}
+
+// row builds the cf cells of a row from qualifier, timestamp and value
+// triples.
+func row(key string, cells ...interface{}) []cell {
+	r := make([]cell, 0)
+	for i := 0; i < len(cells); i += 3 {
+		r = append(r, cell{key, cells[i].(string), cells[i+1].(int), cells[i+2].(string)})
+	}
+	return r
+}
+
+func format(d Difference) string {
+	s := fmt.Sprintf("%s %s", d.Kind, d.Row)
+	for _, c := range d.Cells {
+		s += fmt.Sprintf(" %s:", c.Qualifier)
+		for _, side := range []*Cell{c.Left, c.Right} {
+			if side == nil {
+				s += "-"
+			} else {
+				s += fmt.Sprintf("%s@%d", side.Value, side.Timestamp)
+			}
+			s += "/"
+		}
+	}
+	return s
+}
+
+func TestCompare(t *testing.T) {
+	left := newClient(t, "l",
+		row("a", "q", 1, "1"),
+		row("b", "q", 1, "1"),
+		row("c", "q", 1, "1", "x", 1, "x"),
+		row("d", "q", 1, "1"),
+		row("f", "q", 1, "1"),
+	)
+	right := newClient(t, "r",
+		row("b", "q", 1, "1"),
+		row("c", "q", 1, "2", "y", 1, "y"),
+		row("d", "q", 2, "1"),
+		row("e", "q", 1, "1"),
+	)
+
+	cases := []struct {
+		compareTimestamps bool
+		expect            []string
+		summary           Summary
+	}{
+		{false, []string{
+			"only-left a",
+			"changed c q:1@1/2@1/ x:x@1/-/ y:-/y@1/",
+			"only-right e",
+			"only-left f",
+		}, Summary{LeftRows: 5, RightRows: 4, Matching: 2, OnlyLeft: 2, OnlyRight: 1, Changed: 1, ChangedCells: 3, Differing: 4}},
+		{true, []string{
+			"only-left a",
+			"changed c q:1@1/2@1/ x:x@1/-/ y:-/y@1/",
+			"changed d q:1@1/1@2/",
+			"only-right e",
+			"only-left f",
+		}, Summary{LeftRows: 5, RightRows: 4, Matching: 1, OnlyLeft: 2, OnlyRight: 1, Changed: 2, ChangedCells: 4, Differing: 5}},
+	}
+	for _, c := range cases {
+		diffs := make([]string, 0)
+		s, err := Compare(context.Background(), Side{left, "l"}, Side{right, "r"}, Options{CompareTimestamps: c.compareTimestamps}, func(d Difference) error {
+			diffs = append(diffs, format(d))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(diffs, c.expect) {
+			t.Errorf("expect %q but return %q", c.expect, diffs)
+		}
+		if s != c.summary {
+			t.Errorf("expect %+v but return %+v", c.summary, s)
+		}
+	}
+
+	s, err := Compare(context.Background(), Side{left, "l"}, Side{left, "l"}, Options{}, func(d Difference) error {
+		t.Errorf("expect no difference but return %v", format(d))
+		return nil
+	})
+	if err != nil || !s.Equal() || s.Matching != 5 {
+		t.Errorf("expect 5 matching rows but return %+v, %v", s, err)
+	}
+}
+
+func TestCompareLimit(t *testing.T) {
+	left := newClient(t, "l", row("a", "q", 1, "1"), row("b", "q", 1, "1"), row("c", "q", 1, "1"), row("d", "q", 1, "1"))
+	right := newClient(t, "r", row("b", "q", 1, "1"), row("c", "q", 1, "1"), row("d", "q", 1, "1"))
+	diffs := make([]string, 0)
+	s, err := Compare(context.Background(), Side{left, "l"}, Side{right, "r"}, Options{Scan: scan.Options{Limit: 3}}, func(d Difference) error {
+		diffs = append(diffs, format(d))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"only-left a"}; !reflect.DeepEqual(diffs, expect) {
+		t.Errorf("expect %q but return %q", expect, diffs)
+	}
+	if expect := (Summary{LeftRows: 3, RightRows: 2, Matching: 2, OnlyLeft: 1, Differing: 1}); s != expect {
+		t.Errorf("expect %+v but return %+v", expect, s)
+	}
+}
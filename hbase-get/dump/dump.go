@@ -0,0 +1,280 @@
+// Package dump copies HBase tables to local files and back, keeping every
+// family, qualifier, timestamp and version of the cells.
+//
+// An export directory holds a manifest.json and numbered parts, each a
+// directory of JSON lines files:
+//
+//	<dir>/manifest.json
+//	<dir>/part-00000/rows.jsonl[.gz]       format jsonl, one row per line
+//	<dir>/part-00000/<family>.jsonl[.gz]   format family-jsonl, one cell per line
+//
+// family-jsonl only groups the cells of a part by family, it is not a
+// Parquet-like columnar layout: cells are still stored row by row, with no
+// per column encoding or statistics. No such format is implemented.
+//
+// Binary keys, qualifiers and values are base64 encoded. Parts are written
+// under an .inprogress name and added to the manifest once complete, so an
+// interrupted export or import resumes after the last complete part.
+package dump
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	manifestName     = "manifest.json"
+	inProgressSuffix = ".inprogress"
+	rowsFile         = "rows"
+)
+
+var (
+	errMismatch   = errors.New("export directory holds another export")
+	errIncomplete = errors.New("export is incomplete")
+)
+
+// Formats lists the export formats.
+var Formats = []string{"jsonl", "family-jsonl"}
+
+func checkFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q, expected %s", format, strings.Join(Formats, " or "))
+}
+
+// Cell is a version of a cell.
+type Cell struct {
+	Family    []byte `json:"family,omitempty"`
+	Qualifier []byte `json:"qualifier"`
+	Timestamp uint64 `json:"timestamp"`
+	Value     []byte `json:"value"`
+}
+
+// Row is a line of the jsonl format.
+type Row struct {
+	Key   []byte `json:"row"`
+	Cells []Cell `json:"cells"`
+}
+
+// familyCell is a line of a family file of the family-jsonl format.
+type familyCell struct {
+	Key       []byte `json:"row"`
+	Qualifier []byte `json:"qualifier"`
+	Timestamp uint64 `json:"timestamp"`
+	Value     []byte `json:"value"`
+}
+
+// Part is a complete part of an export.
+type Part struct {
+	Name     string   `json:"name"`
+	Files    []string `json:"files"`
+	Rows     int      `json:"rows"`
+	Cells    int      `json:"cells"`
+	FirstRow []byte   `json:"first_row"`
+	LastRow  []byte   `json:"last_row"`
+}
+
+// Manifest describes an export.
+type Manifest struct {
+	Table    string `json:"table"`
+	Format   string `json:"format"`
+	Gzip     bool   `json:"gzip"`
+	StartRow []byte `json:"start_row,omitempty"`
+	StopRow  []byte `json:"stop_row,omitempty"`
+	// Columns, From and To are the filters of the exported cells.
+	Columns map[string][]string `json:"columns,omitempty"`
+	// Limit is the maximum number of rows of the export, 0 means no limit.
+	Limit int        `json:"limit,omitempty"`
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+	Parts []Part     `json:"parts"`
+	// Complete is set once the whole range was exported.
+	Complete bool `json:"complete"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func sameTime(t *time.Time, u time.Time) bool {
+	if t == nil {
+		return u.IsZero()
+	}
+	return t.Equal(u)
+}
+
+// sortedColumns returns columns with sorted qualifiers, nil when empty, so
+// that equal selections compare equal.
+func sortedColumns(columns map[string][]string) map[string][]string {
+	if len(columns) == 0 {
+		return nil
+	}
+	sorted := make(map[string][]string, len(columns))
+	for family, qualifiers := range columns {
+		if len(qualifiers) > 0 {
+			qualifiers = append([]string(nil), qualifiers...)
+			sort.Strings(qualifiers)
+		} else {
+			qualifiers = nil
+		}
+		sorted[family] = qualifiers
+	}
+	return sorted
+}
+
+// sameColumns reports whether columns selects the cells of the export.
+func (m *Manifest) sameColumns(columns map[string][]string) bool {
+	return reflect.DeepEqual(sortedColumns(m.Columns), sortedColumns(columns))
+}
+
+// Rows returns the number of rows of the complete parts.
+func (m *Manifest) Rows() int {
+	rows := 0
+	for _, p := range m.Parts {
+		rows += p.Rows
+	}
+	return rows
+}
+
+// ReadManifest reads the manifest of an export directory.
+func ReadManifest(dir string) (*Manifest, error) {
+	var m Manifest
+	if err := readJSONFile(filepath.Join(dir, manifestName), &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// writeJSONFile replaces path with v, atomically.
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + inProgressSuffix
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return nil
+}
+
+func fileName(name string, gz bool) string {
+	if gz {
+		return name + ".jsonl.gz"
+	}
+	return name + ".jsonl"
+}
+
+// lineReader reads the JSON lines of a possibly gzipped file.
+type lineReader struct {
+	file    *os.File
+	gzip    *gzip.Reader
+	scanner *bufio.Scanner
+}
+
+func openLines(path string) (*lineReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &lineReader{file: f}
+	var in io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		if r.gzip, err = gzip.NewReader(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("invalid gzip file %s: %w", path, err)
+		}
+		in = r.gzip
+	}
+	r.scanner = bufio.NewScanner(in)
+	r.scanner.Buffer(make([]byte, 64*1024), 256<<20)
+	return r, nil
+}
+
+// next decodes the next line into v, returning io.EOF at the end.
+func (r *lineReader) next(v interface{}) error {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	return json.Unmarshal(r.scanner.Bytes(), v)
+}
+
+func (r *lineReader) Close() error {
+	if r.gzip != nil {
+		r.gzip.Close()
+	}
+	return r.file.Close()
+}
+
+// lineWriter writes the JSON lines of a possibly gzipped file.
+type lineWriter struct {
+	file   *os.File
+	buffer *bufio.Writer
+	gzip   *gzip.Writer
+	enc    *json.Encoder
+}
+
+func createLines(path string, gz bool) (*lineWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &lineWriter{file: f, buffer: bufio.NewWriter(f)}
+	var out io.Writer = w.buffer
+	if gz {
+		w.gzip = gzip.NewWriter(w.buffer)
+		out = w.gzip
+	}
+	w.enc = json.NewEncoder(out)
+	return w, nil
+}
+
+func (w *lineWriter) write(v interface{}) error {
+	return w.enc.Encode(v)
+}
+
+// Close flushes and syncs the file.
+func (w *lineWriter) Close() error {
+	var errs []error
+	if w.gzip != nil {
+		errs = append(errs, w.gzip.Close())
+	}
+	errs = append(errs, w.buffer.Flush(), w.file.Sync(), w.file.Close())
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"hbase-get/scan"
+	"hbase-get/table"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// newClient returns a client of table t holding 5 rows with several
+// versions and families, and of the empty table copy, with the cells of t as
+// listed by cells. Scans of t fail after failAfter rows when set.
+func newClient(t *testing.T, failAfter int) (*table.Client, []string) {
+	t.Helper()
+	source := table.NewMemory("t", "cf", "meta")
+	expect := make([]string, 0)
+	put := func(row, family, qualifier string, ts int64, value string) {
+		opts := table.MutateOptions{Timestamp: time.Unix(0, ts*int64(time.Millisecond))}
+		if err := source.Put(context.Background(), []byte(row), table.Values{family: {qualifier: []byte(value)}}, opts); err != nil {
+			t.Fatal(err)
+		}
+		expect = append(expect, fmt.Sprintf("%s/%s:%s/%d=%s", row, family, qualifier, ts, value))
+	}
+	for i := 0; i < 5; i++ {
+		row := fmt.Sprintf("r%d", i)
+		put(row, "cf", "a", 2, "new")
+		put(row, "cf", "a", 1, "old")
+		put(row, "meta", "b\x00", 1, "\xff")
+	}
+	sort.Strings(expect)
+
+	c := table.NewClient(source, table.NewMemory("copy", "cf", "meta"))
+	if failAfter > 0 {
+		c.Fail = func(req *hrpc.Scan, rows int) error {
+			if rows == failAfter {
+				return errors.New("region server gone")
+			}
+			return nil
+		}
+	}
+	return c, expect
+}
+
+// cells lists every version of the cells of table, sorted.
+func cells(t *testing.T, c *table.Client, name string) []string {
+	t.Helper()
+	list := make([]string, 0)
+	err := scan.Each(context.Background(), c, name, scan.Options{MaxVersions: math.MaxInt32}, func(row scan.Row) error {
+		for _, cell := range row.Cells {
+			list = append(list, fmt.Sprintf("%s/%s:%s/%d=%s", cell.Row, cell.Family, cell.Qualifier, *cell.Timestamp, cell.Value))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range Formats {
+		for _, gz := range []bool{false, true} {
+			dir, err := ioutil.TempDir("", "dump")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			// the first export fails after 3 rows, the second resumes
+			// after the part of the first 2
+			failing, _ := newClient(t, 3)
+			opts := ExportOptions{Dir: dir, Format: format, Gzip: gz, RowsPerPart: 2}
+			if _, err := Export(context.Background(), failing, "t", scan.Options{}, opts); err == nil {
+				t.Fatalf("%s: expect the export to fail", format)
+			}
+			client, expect := newClient(t, 0)
+			m, err := Export(context.Background(), client, "t", scan.Options{}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !m.Complete || len(m.Parts) != 3 || m.Rows() != 5 {
+				t.Errorf("%s: expect 5 rows in 3 parts but return %v in %v", format, m.Rows(), len(m.Parts))
+			}
+			if _, err := os.Stat(filepath.Join(dir, "part-00003")); !os.IsNotExist(err) {
+				t.Errorf("%s: expect no part-00003", format)
+			}
+
+			if _, err := Import(context.Background(), client, "copy", dir, ImportOptions{BatchSize: 2}); err != nil {
+				t.Fatal(err)
+			}
+			if copied := cells(t, client, "copy"); !reflect.DeepEqual(copied, expect) {
+				t.Errorf("%s: expect %q but return %q", format, expect, copied)
+			}
+
+			// a completed import has nothing left to do
+			again, _ := newClient(t, 0)
+			if lines, err := Import(context.Background(), again, "copy", dir, ImportOptions{}); err != nil || lines != 0 {
+				t.Errorf("%s: expect nothing to import but return %v, %v", format, lines, err)
+			}
+		}
+	}
+}
+
+func TestExportMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	client, _ := newClient(t, 0)
+	if _, err := Export(context.Background(), client, "t", scan.Options{}, ExportOptions{Dir: dir, Format: "jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Export(context.Background(), client, "other", scan.Options{}, ExportOptions{Dir: dir, Format: "jsonl"})
+	if !errors.Is(err, errMismatch) {
+		t.Errorf("expect %v but return %v", errMismatch, err)
+	}
+}
+
+func TestExportFiltersMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	client, _ := newClient(t, 0)
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	exported := scan.Options{Columns: map[string][]string{"cf": {"b", "a"}, "meta": nil}, From: from}
+	if _, err := Export(context.Background(), client, "t", exported, ExportOptions{Dir: dir, Format: "jsonl"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts     scan.Options
+		mismatch bool
+	}{
+		{scan.Options{Columns: map[string][]string{"meta": {}, "cf": {"a", "b"}}, From: from.In(time.Local)}, false},
+		{scan.Options{Columns: map[string][]string{"cf": {"a"}, "meta": nil}, From: from}, true},
+		{scan.Options{From: from}, true},
+		{scan.Options{Columns: exported.Columns}, true},
+		{scan.Options{Columns: exported.Columns, From: from, To: from.Add(time.Hour)}, true},
+		{scan.Options{Columns: exported.Columns, From: from, Limit: 2}, true},
+	}
+	for _, test := range tests {
+		_, err := Export(context.Background(), client, "t", test.opts, ExportOptions{Dir: dir, Format: "jsonl"})
+		if errors.Is(err, errMismatch) != test.mismatch {
+			t.Errorf("expect mismatch %v for %+v but return %v", test.mismatch, test.opts, err)
+		}
+	}
+}
+
+func TestExportLimitResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	failing, _ := newClient(t, 2)
+	opts := ExportOptions{Dir: dir, Format: "jsonl", RowsPerPart: 1}
+	if _, err := Export(context.Background(), failing, "t", scan.Options{Limit: 3}, opts); err == nil {
+		t.Fatal("expect the export to fail")
+	}
+
+	// the complete part of the interrupted export is only imported on demand
+	if _, err := Import(context.Background(), failing, "copy", dir, ImportOptions{}); !errors.Is(err, errIncomplete) {
+		t.Errorf("expect %v but return %v", errIncomplete, err)
+	}
+	if lines, err := Import(context.Background(), failing, "copy", dir, ImportOptions{AllowIncomplete: true}); err != nil || lines != 2 {
+		t.Errorf("expect 2 lines imported but return %v, %v", lines, err)
+	}
+
+	client, _ := newClient(t, 0)
+	m, err := Export(context.Background(), client, "t", scan.Options{Limit: 3}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Complete || m.Rows() != 3 || string(m.Parts[len(m.Parts)-1].LastRow) != "r2" {
+		t.Errorf("expect 3 rows up to r2 but return %+v", m)
+	}
+}
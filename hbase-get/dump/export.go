@@ -0,0 +1,277 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"hbase-get/scan"
+)
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	Dir string
+	// Format is jsonl or family-jsonl.
+	Format string
+	Gzip   bool
+	// RowsPerPart completes a part after this many rows, 0 means 100000.
+	RowsPerPart int
+	// Progress, when set, is called after each complete part.
+	Progress func(m *Manifest)
+}
+
+// Export writes the rows of table selected by scanOpts to opts.Dir. All
+// versions are exported unless scanOpts.MaxVersions is set. When opts.Dir
+// holds an incomplete export of the same table, format, range, columns, time
+// range and limit, it is resumed after its last complete part, with the limit
+// reduced by the rows already exported.
+func Export(ctx context.Context, client scan.Client, table string, scanOpts scan.Options, opts ExportOptions) (*Manifest, error) {
+	if err := checkFormat(opts.Format); err != nil {
+		return nil, err
+	}
+	if opts.RowsPerPart <= 0 {
+		opts.RowsPerPart = 100000
+	}
+	if scanOpts.MaxVersions == 0 {
+		scanOpts.MaxVersions = math.MaxInt32
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	start, stop := scanOpts.Range()
+	m, err := ReadManifest(opts.Dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		m = &Manifest{
+			Table:    table,
+			Format:   opts.Format,
+			Gzip:     opts.Gzip,
+			StartRow: start,
+			StopRow:  stop,
+			Columns:  sortedColumns(scanOpts.Columns),
+			From:     optionalTime(scanOpts.From),
+			To:       optionalTime(scanOpts.To),
+			Limit:    scanOpts.Limit,
+			Parts:    []Part{},
+		}
+		if err := writeJSONFile(filepath.Join(opts.Dir, manifestName), m); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case m.Table != table || m.Format != opts.Format || m.Gzip != opts.Gzip ||
+		string(m.StartRow) != string(start) || string(m.StopRow) != string(stop) ||
+		!m.sameColumns(scanOpts.Columns) || !sameTime(m.From, scanOpts.From) || !sameTime(m.To, scanOpts.To) ||
+		m.Limit != scanOpts.Limit:
+		return nil, fmt.Errorf("%w: %s of %s", errMismatch, m.Format, m.Table)
+	case m.Complete:
+		return m, nil
+	}
+	if err := removeIncompleteParts(opts.Dir, m); err != nil {
+		return nil, err
+	}
+	if len(m.Parts) > 0 {
+		// the smallest key after the last exported row
+		scanOpts.StartRow = append(append([]byte(nil), m.Parts[len(m.Parts)-1].LastRow...), 0)
+	}
+	if m.Limit > 0 {
+		if m.Rows() >= m.Limit {
+			// interrupted after the last part
+			m.Complete = true
+			return m, writeJSONFile(filepath.Join(opts.Dir, manifestName), m)
+		}
+		scanOpts.Limit = m.Limit - m.Rows()
+	}
+
+	var w *partWriter
+	defer func() {
+		if w != nil {
+			w.abort()
+		}
+	}()
+	err = scan.Each(ctx, client, table, scanOpts, func(row scan.Row) error {
+		if w == nil {
+			var err error
+			if w, err = newPartWriter(opts.Dir, fmt.Sprintf("part-%05d", len(m.Parts)), opts.Format, opts.Gzip); err != nil {
+				return err
+			}
+		}
+		if err := w.write(row); err != nil {
+			return err
+		}
+		if w.part.Rows >= opts.RowsPerPart {
+			err := completePart(opts, m, w)
+			w = nil
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return m, err
+	}
+	if w != nil {
+		err := completePart(opts, m, w)
+		w = nil
+		if err != nil {
+			return m, err
+		}
+	}
+	m.Complete = true
+	return m, writeJSONFile(filepath.Join(opts.Dir, manifestName), m)
+}
+
+// removeIncompleteParts removes the parts missing from the manifest, either
+// in progress or completed just before an interruption.
+func removeIncompleteParts(dir string, m *Manifest) error {
+	complete := make(map[string]bool, len(m.Parts))
+	for _, p := range m.Parts {
+		complete[p.Name] = true
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "part-*"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if complete[filepath.Base(name)] {
+			continue
+		}
+		if err := os.RemoveAll(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func completePart(opts ExportOptions, m *Manifest, w *partWriter) error {
+	part, err := w.complete()
+	if err != nil {
+		return err
+	}
+	m.Parts = append(m.Parts, part)
+	if err := writeJSONFile(filepath.Join(opts.Dir, manifestName), m); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		opts.Progress(m)
+	}
+	return nil
+}
+
+// partWriter writes a part under its in progress name.
+type partWriter struct {
+	dir    string
+	final  string
+	format string
+	gzip   bool
+	files  map[string]*lineWriter
+	part   Part
+}
+
+func newPartWriter(dir, name, format string, gz bool) (*partWriter, error) {
+	w := &partWriter{
+		dir:    filepath.Join(dir, name+inProgressSuffix),
+		final:  filepath.Join(dir, name),
+		format: format,
+		gzip:   gz,
+		files:  make(map[string]*lineWriter),
+		part:   Part{Name: name},
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *partWriter) file(name string) (*lineWriter, error) {
+	if f, ok := w.files[name]; ok {
+		return f, nil
+	}
+	f, err := createLines(filepath.Join(w.dir, fileName(name, w.gzip)), w.gzip)
+	if err != nil {
+		return nil, err
+	}
+	w.files[name] = f
+	return f, nil
+}
+
+func (w *partWriter) write(row scan.Row) error {
+	if w.part.Rows == 0 {
+		w.part.FirstRow = row.Key
+	}
+	w.part.LastRow = row.Key
+	w.part.Rows++
+	w.part.Cells += len(row.Cells)
+
+	if w.format == "jsonl" {
+		r := Row{Key: row.Key, Cells: make([]Cell, 0, len(row.Cells))}
+		for _, c := range row.Cells {
+			r.Cells = append(r.Cells, Cell{Family: c.Family, Qualifier: c.Qualifier, Timestamp: timestamp(c.Timestamp), Value: c.Value})
+		}
+		f, err := w.file(rowsFile)
+		if err != nil {
+			return err
+		}
+		return f.write(r)
+	}
+	for _, c := range row.Cells {
+		if err := checkFamilyName(string(c.Family)); err != nil {
+			return err
+		}
+		f, err := w.file(string(c.Family))
+		if err != nil {
+			return err
+		}
+		if err := f.write(familyCell{Key: row.Key, Qualifier: c.Qualifier, Timestamp: timestamp(c.Timestamp), Value: c.Value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func timestamp(ts *uint64) uint64 {
+	if ts == nil {
+		return 0
+	}
+	return *ts
+}
+
+// checkFamilyName rejects families that cannot name a file. HBase only
+// allows word characters, '-' and '.' in families.
+func checkFamilyName(family string) error {
+	if family == "" || family == rowsFile || family[0] == '.' {
+		return fmt.Errorf("unsupported family name %q", family)
+	}
+	for _, c := range family {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return fmt.Errorf("unsupported family name %q", family)
+		}
+	}
+	return nil
+}
+
+func (w *partWriter) complete() (Part, error) {
+	for name, f := range w.files {
+		if err := f.Close(); err != nil {
+			return w.part, err
+		}
+		w.part.Files = append(w.part.Files, fileName(name, w.gzip))
+	}
+	w.files = nil
+	sort.Strings(w.part.Files)
+	if err := os.Rename(w.dir, w.final); err != nil {
+		return w.part, err
+	}
+	return w.part, nil
+}
+
+func (w *partWriter) abort() {
+	for _, f := range w.files {
+		f.Close()
+	}
+	os.RemoveAll(w.dir)
+}
@@ -0,0 +1,226 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// Putter is the part of gohbase.Client used to import.
+type Putter interface {
+	Put(p *hrpc.Mutate) (*hrpc.Result, error)
+}
+
+// ImportOptions configures Import.
+type ImportOptions struct {
+	// BatchSize is the number of puts sent concurrently, and the number of
+	// lines between checkpoints. 0 means 500.
+	BatchSize int
+	// Restart ignores the checkpoint of a previous import.
+	Restart bool
+	// AllowIncomplete imports the complete parts of an export that was
+	// interrupted, instead of failing.
+	AllowIncomplete bool
+	// Progress, when set, is called after each batch with the number of
+	// lines imported so far.
+	Progress func(lines int)
+}
+
+// checkpoint records how far an import into a table went: the files done
+// and the lines of the current file.
+type checkpoint struct {
+	Table string          `json:"table"`
+	Done  map[string]bool `json:"done"`
+	File  string          `json:"file,omitempty"`
+	Lines int             `json:"lines"`
+}
+
+func checkpointPath(dir, table string) string {
+	return filepath.Join(dir, "import-"+table+".checkpoint")
+}
+
+// Import writes the complete parts of the export in dir to table, keeping
+// the timestamps of the cells. An interrupted export is refused unless
+// opts.AllowIncomplete is set. It checkpoints after every batch so that an
+// interrupted import resumes where it stopped. Lines are put at least once,
+// which rewrites identical cells on resume.
+func Import(ctx context.Context, client Putter, table string, dir string, opts ImportOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return 0, err
+	}
+	if !m.Complete && !opts.AllowIncomplete {
+		return 0, fmt.Errorf("%w: %d rows of %s in %s, export again to complete it", errIncomplete, m.Rows(), m.Table, dir)
+	}
+
+	path := checkpointPath(dir, table)
+	c := checkpoint{Table: table, Done: make(map[string]bool)}
+	if !opts.Restart {
+		if err := readJSONFile(path, &c); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+	}
+
+	imported := 0
+	for _, part := range m.Parts {
+		for _, f := range part.Files {
+			name := part.Name + "/" + f
+			if c.Done[name] {
+				continue
+			}
+			skip := 0
+			if c.File == name {
+				skip = c.Lines
+			}
+			c.File, c.Lines = name, skip
+			err := importFile(ctx, client, table, filepath.Join(dir, part.Name, f), skip, opts.BatchSize, func(lines int) error {
+				imported += lines
+				c.Lines += lines
+				if opts.Progress != nil {
+					opts.Progress(imported)
+				}
+				return writeJSONFile(path, c)
+			})
+			if err != nil {
+				return imported, fmt.Errorf("failed to import %s: %w", name, err)
+			}
+			c.Done[name] = true
+			c.File, c.Lines = "", 0
+			if err := writeJSONFile(path, c); err != nil {
+				return imported, err
+			}
+		}
+	}
+	return imported, nil
+}
+
+// importFile puts the lines of a file after the first skip, by batches,
+// calling done with the number of lines of each batch once written.
+func importFile(ctx context.Context, client Putter, table, path string, skip, batchSize int, done func(lines int) error) error {
+	r, err := openLines(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	family := ""
+	if base := filepath.Base(path); base != fileName(rowsFile, false) && base != fileName(rowsFile, true) {
+		family = trimExt(base)
+	}
+
+	batch := make([]*hrpc.Mutate, 0, batchSize)
+	lines := 0
+	for line := 0; ; line++ {
+		var row Row
+		if family == "" {
+			err = r.next(&row)
+		} else {
+			var c familyCell
+			if err = r.next(&c); err == nil {
+				row = Row{Key: c.Key, Cells: []Cell{{Family: []byte(family), Qualifier: c.Qualifier, Timestamp: c.Timestamp, Value: c.Value}}}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line+1, err)
+		}
+		if line < skip {
+			continue
+		}
+		puts, err := rowPuts(ctx, table, row)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line+1, err)
+		}
+		batch = append(batch, puts...)
+		lines++
+		if lines >= batchSize {
+			if err := putAll(client, batch); err != nil {
+				return err
+			}
+			if err := done(lines); err != nil {
+				return err
+			}
+			batch, lines = batch[:0], 0
+		}
+	}
+	if lines > 0 {
+		if err := putAll(client, batch); err != nil {
+			return err
+		}
+		return done(lines)
+	}
+	return nil
+}
+
+func trimExt(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".jsonl")
+}
+
+// rowPuts returns the puts of a row, one per timestamp since a put carries
+// a single timestamp.
+func rowPuts(ctx context.Context, table string, row Row) ([]*hrpc.Mutate, error) {
+	byTimestamp := make(map[uint64]map[string]map[string][]byte)
+	for _, c := range row.Cells {
+		values := byTimestamp[c.Timestamp]
+		if values == nil {
+			values = make(map[string]map[string][]byte)
+			byTimestamp[c.Timestamp] = values
+		}
+		if values[string(c.Family)] == nil {
+			values[string(c.Family)] = make(map[string][]byte)
+		}
+		values[string(c.Family)][string(c.Qualifier)] = c.Value
+	}
+	timestamps := make([]uint64, 0, len(byTimestamp))
+	for ts := range byTimestamp {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	puts := make([]*hrpc.Mutate, 0, len(timestamps))
+	for _, ts := range timestamps {
+		put, err := hrpc.NewPut(ctx, []byte(table), row.Key, byTimestamp[ts], hrpc.TimestampUint64(ts))
+		if err != nil {
+			return nil, err
+		}
+		puts = append(puts, put)
+	}
+	return puts, nil
+}
+
+// putAll sends puts concurrently, letting the client batch them by region,
+// and returns the first error.
+func putAll(client Putter, puts []*hrpc.Mutate) error {
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	for _, put := range puts {
+		wg.Add(1)
+		go func(put *hrpc.Mutate) {
+			defer wg.Done()
+			if _, err := client.Put(put); err != nil {
+				mutex.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to put %s: %w", put.Key(), err)
+				}
+				mutex.Unlock()
+			}
+		}(put)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -2,4 +2,8 @@ module hbase-get
 
 go 1.15
 
-require github.com/tsuna/gohbase v0.0.0-20201125011725-348991136365
+require (
+	github.com/spf13/cobra v1.1.3
+	github.com/tsuna/gohbase v0.0.0-20201125011725-348991136365
+	google.golang.org/protobuf v1.21.0
+)
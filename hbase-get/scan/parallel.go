@@ -0,0 +1,234 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ParallelOptions configures EachParallel.
+type ParallelOptions struct {
+	// Workers is the number of regions scanned concurrently, 0 means 4.
+	Workers int
+	// Ordered delivers rows in key order, holding back the rows of regions
+	// ahead of the one being delivered. Otherwise rows are delivered as
+	// they arrive.
+	Ordered bool
+	// Buffer is the number of rows held per region in order, where at most
+	// Workers+1 regions are held at once, or in total otherwise. 0 means
+	// 1000.
+	Buffer int
+	// Progress, when set, is called each time a region completes.
+	Progress func(Progress)
+}
+
+// Progress reports the completion of a region.
+type Progress struct {
+	Region  Region
+	Err     error
+	Regions int
+	Done    int
+	Failed  int
+	// Rows is the number of rows scanned so far.
+	Rows int64
+}
+
+// RegionError is the failure of the scan of a region.
+type RegionError struct {
+	Region Region
+	Err    error
+}
+
+func (e *RegionError) Error() string {
+	return fmt.Sprintf("region %s: %v", e.Region.Name, e.Err)
+}
+
+func (e *RegionError) Unwrap() error {
+	return e.Err
+}
+
+// RegionErrors lists the regions that failed, the others having been
+// scanned completely.
+type RegionErrors []*RegionError
+
+func (e RegionErrors) Error() string {
+	s := make([]string, 0, len(e))
+	for _, err := range e {
+		s = append(s, err.Error())
+	}
+	return fmt.Sprintf("%d regions failed: %s", len(e), strings.Join(s, "; "))
+}
+
+// EachParallel is like Each but scans the regions of the range concurrently.
+// fn is called from a single goroutine. A region that fails does not stop
+// the others, their errors are returned together as RegionErrors once all
+// regions completed. An error from fn stops the scan and is returned.
+func EachParallel(ctx context.Context, client Client, table string, opts Options, popts ParallelOptions, fn func(Row) error) error {
+	if popts.Workers <= 0 {
+		popts.Workers = 4
+	}
+	if popts.Buffer <= 0 {
+		popts.Buffer = 1000
+	}
+	all, err := Regions(ctx, client, table)
+	if err != nil {
+		return err
+	}
+	start, stop := opts.Range()
+	regions := SplitRange(all, start, stop)
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// in order, the channels of regions are queued for delivery as their
+	// scans start, at most Workers ahead of the one being delivered, which
+	// bounds the rows held to (Workers+1)*Buffer
+	var shared chan Row
+	queue := make(chan chan Row, popts.Workers)
+	if !popts.Ordered {
+		shared = make(chan Row, popts.Buffer)
+		queue <- shared
+		close(queue)
+	}
+
+	var (
+		rows     int64
+		mutex    sync.Mutex
+		failures RegionErrors
+		progress = Progress{Regions: len(regions)}
+		wg       sync.WaitGroup
+	)
+	type job struct {
+		region int
+		rows   chan Row
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		if popts.Ordered {
+			defer close(queue)
+		}
+		for i := range regions {
+			ch := shared
+			if popts.Ordered {
+				ch = make(chan Row, popts.Buffer)
+				select {
+				case queue <- ch:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- job{i, ch}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < popts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := regions[j.region]
+				ropts := opts
+				ropts.StartRow, ropts.StopRow, ropts.Prefix, ropts.Limit = r.StartKey, r.StopKey, nil, 0
+				err := Each(ctx, client, table, ropts, func(row Row) error {
+					select {
+					case j.rows <- row:
+						atomic.AddInt64(&rows, 1)
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+				if popts.Ordered {
+					close(j.rows)
+				}
+				if ctx.Err() != nil {
+					// stopped by fn or the limit, or cancelled
+					continue
+				}
+
+				mutex.Lock()
+				progress.Region, progress.Err = r, nil
+				progress.Done++
+				if err != nil {
+					rerr := &RegionError{Region: r, Err: err}
+					failures = append(failures, rerr)
+					progress.Err = rerr
+					progress.Failed++
+				}
+				progress.Rows = atomic.LoadInt64(&rows)
+				if popts.Progress != nil {
+					popts.Progress(progress)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	if !popts.Ordered {
+		go func() {
+			wg.Wait()
+			close(shared)
+		}()
+	}
+
+	err = deliver(ctx, queue, opts.Limit, fn)
+	cancel()
+	wg.Wait()
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// deliver calls fn with the rows of the channels of queue, one channel after
+// the other, until limit rows were delivered or ctx is done.
+func deliver(ctx context.Context, queue <-chan chan Row, limit int, fn func(Row) error) error {
+	count := 0
+	for {
+		var ch chan Row
+		var ok bool
+		select {
+		case ch, ok = <-queue:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if !ok {
+			return nil
+		}
+		for {
+			var row Row
+			var ok bool
+			select {
+			case row, ok = <-ch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !ok {
+				break
+			}
+			if err := fn(row); err != nil {
+				if errors.Is(err, Stop) {
+					return nil
+				}
+				return err
+			}
+			count++
+			if limit > 0 && count >= limit {
+				return nil
+			}
+		}
+	}
+}
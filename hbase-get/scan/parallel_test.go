@@ -0,0 +1,166 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"hbase-get/scan"
+	"hbase-get/table"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// cluster is a client of table t split at c, f and m, counting the scans of
+// the table.
+type cluster struct {
+	*table.Client
+	rows []string
+
+	mutex sync.Mutex
+	scans int
+}
+
+func newCluster(t *testing.T) *cluster {
+	t.Helper()
+	m := table.NewMemory("t", "cf")
+	c := &cluster{Client: table.NewClient(m)}
+	for _, r := range "abcdefghijklmnopqrstuvwxyz" {
+		c.rows = append(c.rows, string(r), string(r)+"1")
+	}
+	for _, r := range c.rows {
+		if err := m.Put(context.Background(), []byte(r), table.Values{"cf": {"q": []byte(r)}}, table.MutateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Split("t", "c", "f", "m")
+	c.failAt("")
+	return c
+}
+
+// failAt fails the scans of the region starting at start after their first
+// row, none when start is empty.
+func (c *cluster) failAt(start string) {
+	c.Fail = func(req *hrpc.Scan, rows int) error {
+		if rows == 0 {
+			c.mutex.Lock()
+			c.scans++
+			c.mutex.Unlock()
+		}
+		if start != "" && string(req.StartRow()) == start && rows == 1 {
+			return errors.New("region server gone")
+		}
+		return nil
+	}
+}
+
+func TestRegions(t *testing.T) {
+	regions, err := scan.Regions(context.Background(), newCluster(t), "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := make([]string, 0)
+	for _, r := range regions {
+		bounds = append(bounds, string(r.StartKey)+"-"+string(r.StopKey))
+	}
+	if !reflect.DeepEqual(bounds, []string{"-c", "c-f", "f-m", "m-"}) {
+		t.Errorf("expect [-c c-f f-m m-] but return %v", bounds)
+	}
+
+	split := scan.SplitRange(regions, []byte("d"), []byte("g"))
+	bounds = bounds[:0]
+	for _, r := range split {
+		bounds = append(bounds, string(r.StartKey)+"-"+string(r.StopKey))
+	}
+	if !reflect.DeepEqual(bounds, []string{"d-f", "f-g"}) {
+		t.Errorf("expect [d-f f-g] but return %v", bounds)
+	}
+}
+
+func TestEachParallel(t *testing.T) {
+	for _, ordered := range []bool{true, false} {
+		c := newCluster(t)
+		keys := make([]string, 0)
+		progress := make([]scan.Progress, 0)
+		popts := scan.ParallelOptions{Workers: 3, Ordered: ordered, Buffer: 2, Progress: func(p scan.Progress) {
+			progress = append(progress, p)
+		}}
+		err := scan.EachParallel(context.Background(), c, "t", scan.Options{StartRow: []byte("b")}, popts, func(row scan.Row) error {
+			keys = append(keys, string(row.Key))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ordered {
+			sort.Strings(keys)
+		}
+		if !reflect.DeepEqual(keys, c.rows[2:]) {
+			t.Errorf("ordered %v: expect %v but return %v", ordered, c.rows[2:], keys)
+		}
+		if c.scans != 4 || len(progress) != 4 || progress[3].Done != 4 || progress[3].Rows != int64(len(c.rows)-2) {
+			t.Errorf("ordered %v: expect 4 scans and progress of 4 regions but return %v, %+v", ordered, c.scans, progress)
+		}
+	}
+}
+
+func TestEachParallelLimit(t *testing.T) {
+	keys := make([]string, 0)
+	err := scan.EachParallel(context.Background(), newCluster(t), "t", scan.Options{Prefix: []byte("e"), Limit: 1}, scan.ParallelOptions{Ordered: true}, func(row scan.Row) error {
+		keys = append(keys, string(row.Key))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(keys, []string{"e"}) {
+		t.Errorf("expect [e] but return %v, %v", keys, err)
+	}
+}
+
+func TestEachParallelRegionError(t *testing.T) {
+	c := newCluster(t)
+	c.failAt("f")
+	keys := make([]string, 0)
+	err := scan.EachParallel(context.Background(), c, "t", scan.Options{}, scan.ParallelOptions{Ordered: true}, func(row scan.Row) error {
+		keys = append(keys, string(row.Key))
+		return nil
+	})
+	var failures scan.RegionErrors
+	if !errors.As(err, &failures) || len(failures) != 1 || string(failures[0].Region.StartKey) != "f" {
+		t.Fatalf("expect the failure of region f but return %v", err)
+	}
+	// every region but f completely, f up to its failure
+	if len(keys) != len(c.rows)-13 {
+		t.Errorf("expect %v rows but return %v", len(c.rows)-13, len(keys))
+	}
+}
+
+func TestEachParallelOrderedWindow(t *testing.T) {
+	c := newCluster(t)
+	splits := make([]string, 0)
+	for _, r := range "bcdefghijklmnopqrstuvwxyz" {
+		splits = append(splits, string(r))
+	}
+	c.Split("t", splits...)
+	// every region fits in its buffer, so scans could run ahead of the
+	// delivery to the last region if they were not held back
+	scans := -1
+	popts := scan.ParallelOptions{Workers: 2, Ordered: true, Buffer: 2}
+	err := scan.EachParallel(context.Background(), c, "t", scan.Options{}, popts, func(row scan.Row) error {
+		if scans < 0 {
+			time.Sleep(50 * time.Millisecond)
+			c.mutex.Lock()
+			scans = c.scans
+			c.mutex.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scans > popts.Workers+1 {
+		t.Errorf("expect at most %d regions scanned ahead but return %d", popts.Workers+1, scans)
+	}
+}
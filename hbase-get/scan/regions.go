@@ -0,0 +1,85 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/tsuna/gohbase/hrpc"
+	"github.com/tsuna/gohbase/region"
+)
+
+// metaTable lists the regions of every table, keyed by
+// "<table>,<start key>,<region id>.<hash>.".
+const metaTable = "hbase:meta"
+
+// Region is the row range of a table served by a region server.
+type Region struct {
+	Name     []byte
+	StartKey []byte
+	// StopKey is empty for the last region.
+	StopKey []byte
+	Server  string
+}
+
+// Regions returns the online regions of table in key order.
+func Regions(ctx context.Context, client Client, table string) ([]Region, error) {
+	// ';' follows ',' so the range holds the rows of table only
+	req, err := hrpc.NewScanRange(ctx, []byte(metaTable), []byte(table+","), []byte(table+";"),
+		hrpc.Families(map[string][]string{"info": nil}))
+	if err != nil {
+		return nil, err
+	}
+	scanner := client.Scan(req)
+	defer scanner.Close()
+	regions := make([]Region, 0)
+	for {
+		row, err := scanner.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list regions of %s: %w", table, err)
+		}
+		info, addr, err := region.ParseRegionInfo(row)
+		var offline region.OfflineRegionError
+		if errors.As(err, &offline) {
+			// the parent of a split
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list regions of %s: %w", table, err)
+		}
+		regions = append(regions, Region{Name: info.Name(), StartKey: info.StartKey(), StopKey: info.StopKey(), Server: addr})
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	sort.Slice(regions, func(i, j int) bool { return bytes.Compare(regions[i].StartKey, regions[j].StartKey) < 0 })
+	return regions, nil
+}
+
+// SplitRange returns the regions overlapping [start, stop), their keys
+// narrowed to the range.
+func SplitRange(regions []Region, start, stop []byte) []Region {
+	result := make([]Region, 0, len(regions))
+	for _, r := range regions {
+		if len(stop) > 0 && bytes.Compare(r.StartKey, stop) >= 0 {
+			continue
+		}
+		if len(r.StopKey) > 0 && bytes.Compare(r.StopKey, start) <= 0 {
+			continue
+		}
+		if bytes.Compare(r.StartKey, start) < 0 {
+			r.StartKey = start
+		}
+		if len(stop) > 0 && (len(r.StopKey) == 0 || bytes.Compare(r.StopKey, stop) > 0) {
+			r.StopKey = stop
+		}
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,235 @@
+// Package scan reads rows of an HBase table, delivered one at a time through
+// an Iterator or a callback so that tables larger than memory can be
+// scanned.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/tsuna/gohbase/filter"
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// Client is the part of gohbase.Client used to scan.
+type Client interface {
+	Scan(s *hrpc.Scan) hrpc.Scanner
+}
+
+// Options narrows a scan. The zero value scans every cell of the latest
+// version of every row.
+type Options struct {
+	// StartRow is the first row, inclusive. Empty means the first row of
+	// the table.
+	StartRow []byte
+	// StopRow is the last row, exclusive. Empty means the end of the table.
+	StopRow []byte
+	// Prefix scans only the rows starting with it. It narrows StartRow and
+	// StopRow to the rows of the prefix, so that only the regions holding
+	// them are read.
+	Prefix []byte
+	// Columns maps families to their qualifiers to read, a family without
+	// qualifiers reads all of them. Empty means all families.
+	Columns map[string][]string
+	// From and To restrict cells to the timestamps in [From, To), a zero
+	// time leaving its side unbounded.
+	From time.Time
+	To   time.Time
+	// MaxVersions is the number of versions read per cell, 0 means 1.
+	MaxVersions uint32
+	// Limit stops after this many rows, 0 means no limit.
+	Limit int
+	// Caching is the number of rows fetched per RPC, 0 means the gohbase
+	// default.
+	Caching uint32
+	// Filter is applied by the region servers in addition to the above.
+	Filter filter.Filter
+}
+
+// ParseColumns parses columns given as "family" or "family:qualifier",
+// separated by commas.
+func ParseColumns(s string) (map[string][]string, error) {
+	columns := make(map[string][]string)
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		family, qualifier := c, ""
+		if i := strings.IndexByte(c, ':'); i >= 0 {
+			family, qualifier = c[:i], c[i+1:]
+		}
+		if family == "" {
+			return nil, fmt.Errorf("invalid column %q", c)
+		}
+		if _, ok := columns[family]; !ok {
+			columns[family] = nil
+		}
+		if qualifier != "" {
+			columns[family] = append(columns[family], qualifier)
+		}
+	}
+	return columns, nil
+}
+
+// PrefixStopRow returns the first row after every row starting with prefix,
+// or nil when there is none, the prefix being all 0xff.
+func PrefixStopRow(prefix []byte) []byte {
+	stop := append([]byte(nil), prefix...)
+	for i := len(stop) - 1; i >= 0; i-- {
+		if stop[i] < 0xff {
+			stop[i]++
+			return stop[:i+1]
+		}
+	}
+	return nil
+}
+
+// Range returns the start and stop rows of the scan, the intersection of
+// StartRow, StopRow and the rows of Prefix.
+func (o Options) Range() (start, stop []byte) {
+	start, stop = o.StartRow, o.StopRow
+	if len(o.Prefix) == 0 {
+		return start, stop
+	}
+	if string(o.Prefix) > string(start) {
+		start = o.Prefix
+	}
+	if prefixStop := PrefixStopRow(o.Prefix); prefixStop != nil && (len(stop) == 0 || string(prefixStop) < string(stop)) {
+		stop = prefixStop
+	}
+	return start, stop
+}
+
+// NewRequest builds the scan request of opts.
+func NewRequest(ctx context.Context, table string, opts Options) (*hrpc.Scan, error) {
+	options := make([]func(hrpc.Call) error, 0)
+	if len(opts.Columns) > 0 {
+		options = append(options, hrpc.Families(opts.Columns))
+	}
+	if !opts.From.IsZero() || !opts.To.IsZero() {
+		var from, to uint64 = 0, math.MaxInt64
+		if !opts.From.IsZero() {
+			from = uint64(opts.From.UnixNano() / 1e6)
+		}
+		if !opts.To.IsZero() {
+			to = uint64(opts.To.UnixNano() / 1e6)
+		}
+		options = append(options, hrpc.TimeRangeUint64(from, to))
+	}
+	if opts.MaxVersions > 0 {
+		options = append(options, hrpc.MaxVersions(opts.MaxVersions))
+	}
+	if opts.Caching > 0 {
+		options = append(options, hrpc.NumberOfRows(opts.Caching))
+	}
+	if opts.Filter != nil {
+		options = append(options, hrpc.Filters(opts.Filter))
+	}
+	start, stop := opts.Range()
+	req, err := hrpc.NewScanRange(ctx, []byte(table), start, stop, options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan of %s: %w", table, err)
+	}
+	return req, nil
+}
+
+// Row is a row with its cells, in family, qualifier and newest first
+// timestamp order.
+type Row struct {
+	Key   []byte
+	Cells []*hrpc.Cell
+}
+
+// Value returns the latest value of family:qualifier, nil when absent.
+func (r Row) Value(family, qualifier string) []byte {
+	for _, c := range r.Cells {
+		if string(c.Family) == family && string(c.Qualifier) == qualifier {
+			return c.Value
+		}
+	}
+	return nil
+}
+
+// Iterator delivers the rows of a scan in order.
+type Iterator struct {
+	table   string
+	scanner hrpc.Scanner
+	limit   int
+	count   int
+	done    bool
+}
+
+// Open starts scanning table. The iterator must be closed, unless Next
+// returned an error.
+func Open(ctx context.Context, client Client, table string, opts Options) (*Iterator, error) {
+	req, err := NewRequest(ctx, table, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{table: table, scanner: client.Scan(req), limit: opts.Limit}, nil
+}
+
+// Next returns the next row, or io.EOF once all rows were returned.
+func (it *Iterator) Next() (Row, error) {
+	for !it.done {
+		if it.limit > 0 && it.count >= it.limit {
+			it.Close()
+			break
+		}
+		result, err := it.scanner.Next()
+		if err == io.EOF {
+			it.done = true
+			break
+		}
+		if err != nil {
+			it.done = true
+			return Row{}, fmt.Errorf("failed to scan %s: %w", it.table, err)
+		}
+		if len(result.Cells) == 0 {
+			continue
+		}
+		it.count++
+		return Row{Key: result.Cells[0].Row, Cells: result.Cells}, nil
+	}
+	return Row{}, io.EOF
+}
+
+// Close stops the scan, it may be called more than once.
+func (it *Iterator) Close() error {
+	it.done = true
+	return it.scanner.Close()
+}
+
+// Stop may be returned by the callback of Each to end the scan early
+// without an error.
+var Stop = errors.New("stop scan")
+
+// Each calls fn with every row of the scan, until fn returns an error.
+func Each(ctx context.Context, client Client, table string, opts Options, fn func(Row) error) error {
+	it, err := Open(ctx, client, table, opts)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for {
+		row, err := it.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			if errors.Is(err, Stop) {
+				return nil
+			}
+			return err
+		}
+	}
+}
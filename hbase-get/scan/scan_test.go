@@ -0,0 +1,112 @@
+package scan_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"hbase-get/scan"
+	"hbase-get/table"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// newClient returns a client of table t whose rows hold value in cf:q, given
+// as key and value pairs.
+func newClient(t *testing.T, rows ...string) (*table.Client, *table.Memory) {
+	t.Helper()
+	m := table.NewMemory("t", "cf")
+	for i := 0; i < len(rows); i += 2 {
+		if err := m.Put(context.Background(), []byte(rows[i]), table.Values{"cf": {"q": []byte(rows[i+1])}}, table.MutateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return table.NewClient(m), m
+}
+
+func TestPrefixStopRow(t *testing.T) {
+	cases := map[string]string{"abc": "abd", "ab\xff": "ac", "\xff\xff": "", "": ""}
+	for prefix, expect := range cases {
+		if stop := string(scan.PrefixStopRow([]byte(prefix))); stop != expect {
+			t.Errorf("expect %q but return %q", expect, stop)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	cases := []struct {
+		opts        scan.Options
+		start, stop string
+	}{
+		{scan.Options{StartRow: []byte("a"), StopRow: []byte("z")}, "a", "z"},
+		{scan.Options{Prefix: []byte("k")}, "k", "l"},
+		{scan.Options{Prefix: []byte("k"), StartRow: []byte("k5"), StopRow: []byte("z")}, "k5", "l"},
+		{scan.Options{Prefix: []byte("k"), StartRow: []byte("a"), StopRow: []byte("k5")}, "k", "k5"},
+	}
+	for _, c := range cases {
+		start, stop := c.opts.Range()
+		if string(start) != c.start || string(stop) != c.stop {
+			t.Errorf("expect [%q, %q) but return [%q, %q)", c.start, c.stop, start, stop)
+		}
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := scan.ParseColumns("cf:a, cf:b,meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string][]string{"cf": {"a", "b"}, "meta": nil}
+	if !reflect.DeepEqual(columns, expect) {
+		t.Errorf("expect %v but return %v", expect, columns)
+	}
+	if _, err := scan.ParseColumns(":q"); err == nil {
+		t.Errorf("expect error for a column without family")
+	}
+}
+
+func TestEach(t *testing.T) {
+	client, _ := newClient(t, "a", "0", "r1", "1", "r2", "2", "r3", "3", "s", "4")
+	var req *hrpc.Scan
+	client.Fail = func(r *hrpc.Scan, rows int) error {
+		req = r
+		return nil
+	}
+	keys := make([]string, 0)
+	err := scan.Each(context.Background(), client, "t", scan.Options{Prefix: []byte("r"), Limit: 2}, func(row scan.Row) error {
+		keys = append(keys, string(row.Key)+"="+string(row.Value("cf", "q")))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"r1=1", "r2=2"}) {
+		t.Errorf("expect [r1=1 r2=2] but return %v", keys)
+	}
+	if client.OpenScanners() != 0 || string(req.StartRow()) != "r" || string(req.StopRow()) != "s" {
+		t.Errorf("expect closed scan of [r, s) but return %v open of [%q, %q)", client.OpenScanners(), req.StartRow(), req.StopRow())
+	}
+
+	client.Fail = nil
+	count := 0
+	err = scan.Each(context.Background(), client, "t", scan.Options{}, func(row scan.Row) error {
+		count++
+		return scan.Stop
+	})
+	if err != nil || count != 1 {
+		t.Errorf("expect 1 row without error but return %v, %v", count, err)
+	}
+
+	failure := errors.New("region unavailable")
+	client.Fail = func(r *hrpc.Scan, rows int) error {
+		if rows == 1 {
+			return failure
+		}
+		return nil
+	}
+	err = scan.Each(context.Background(), client, "t", scan.Options{}, func(row scan.Row) error { return nil })
+	if !errors.Is(err, failure) {
+		t.Errorf("expect %v but return %v", failure, err)
+	}
+}
@@ -0,0 +1,254 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase/hrpc"
+	"github.com/tsuna/gohbase/pb"
+	"github.com/tsuna/gohbase/region"
+	"google.golang.org/protobuf/proto"
+)
+
+// metaTable is the table listing the regions of every table.
+const metaTable = "hbase:meta"
+
+// Client serves the scans and puts of gohbase requests from Memory tables,
+// for tests of the code taking a scan.Client, such as scan.Each,
+// scan.EachParallel, diff.Compare and dump.Export and Import. hbase:meta is
+// served from the tables and their splits.
+type Client struct {
+	// Fail, when set, is called before each result of a scan of a table
+	// other than hbase:meta, and before its end, with the number of rows
+	// returned so far. An error fails the scan.
+	Fail func(req *hrpc.Scan, rows int) error
+
+	mutex  sync.Mutex
+	tables map[string]*Memory
+	splits map[string][]string
+	open   int
+}
+
+// NewClient returns a client of tables, each a single region until split.
+func NewClient(tables ...*Memory) *Client {
+	c := &Client{tables: make(map[string]*Memory, len(tables)), splits: make(map[string][]string)}
+	for _, t := range tables {
+		c.tables[t.Name()] = t
+	}
+	return c
+}
+
+// Split splits table into regions starting at the given keys.
+func (c *Client) Split(table string, keys ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+	c.splits[table] = keys
+}
+
+// OpenScanners returns the number of scanners not closed yet.
+func (c *Client) OpenScanners() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.open
+}
+
+func (c *Client) table(name []byte) (*Memory, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t, ok := c.tables[string(name)]
+	if !ok {
+		return nil, fmt.Errorf("table %s not found", name)
+	}
+	return t, nil
+}
+
+// fakeRegion is set on requests before converting them to their protobuf
+// message, which names their region.
+func fakeRegion(table []byte) hrpc.RegionInfo {
+	return region.NewInfo(0, nil, table, table, nil, nil)
+}
+
+func fromMillis(ms uint64) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond))
+}
+
+// scanOptions returns the options of a scan request.
+func scanOptions(req *hrpc.Scan) (scan.Options, error) {
+	req.SetRegion(fakeRegion(req.Table()))
+	s := req.ToProto().(*pb.ScanRequest).Scan
+	if s.Filter != nil {
+		return scan.Options{}, errors.New("filters are not supported by the in-memory table")
+	}
+	opts := scan.Options{StartRow: s.StartRow, StopRow: s.StopRow, MaxVersions: s.GetMaxVersions()}
+	if len(s.Column) > 0 {
+		opts.Columns = make(map[string][]string, len(s.Column))
+		for _, col := range s.Column {
+			qualifiers := make([]string, 0, len(col.Qualifier))
+			for _, q := range col.Qualifier {
+				qualifiers = append(qualifiers, string(q))
+			}
+			if len(qualifiers) == 0 {
+				qualifiers = nil
+			}
+			opts.Columns[string(col.Family)] = qualifiers
+		}
+	}
+	if s.TimeRange.From != nil {
+		opts.From = fromMillis(s.TimeRange.GetFrom())
+	}
+	if s.TimeRange.To != nil && s.TimeRange.GetTo() != math.MaxInt64 {
+		opts.To = fromMillis(s.TimeRange.GetTo())
+	}
+	return opts, nil
+}
+
+// Scan serves a scan request of a table or of hbase:meta.
+func (c *Client) Scan(req *hrpc.Scan) hrpc.Scanner {
+	c.mutex.Lock()
+	c.open++
+	c.mutex.Unlock()
+	s := &clientScanner{client: c, req: req}
+	if string(req.Table()) == metaTable {
+		s.results = c.metaRows(req.StartRow(), req.StopRow())
+		return s
+	}
+	t, err := c.table(req.Table())
+	if err != nil {
+		s.err = err
+		return s
+	}
+	opts, err := scanOptions(req)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	s.rows, s.err = t.Scan(req.Context(), opts)
+	return s
+}
+
+// metaRows returns the rows of hbase:meta in [start, stop).
+func (c *Client) metaRows(start, stop []byte) []*hrpc.Result {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	names := make([]string, 0, len(c.tables))
+	for name := range c.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results := make([]*hrpc.Result, 0)
+	for _, name := range names {
+		bounds := append(append([]string{""}, c.splits[name]...), "")
+		for i := 0; i < len(bounds)-1; i++ {
+			key := fmt.Sprintf("%s,%s,%d.hash.", name, bounds[i], i)
+			if key < string(start) || len(stop) > 0 && key >= string(stop) {
+				continue
+			}
+			info, _ := proto.Marshal(&pb.RegionInfo{
+				RegionId:  proto.Uint64(uint64(i)),
+				TableName: &pb.TableName{Namespace: []byte("default"), Qualifier: []byte(name)},
+				StartKey:  []byte(bounds[i]),
+				EndKey:    []byte(bounds[i+1]),
+			})
+			results = append(results, &hrpc.Result{Cells: []*hrpc.Cell{
+				{Row: []byte(key), Family: []byte("info"), Qualifier: []byte("regioninfo"), Value: append([]byte("PBUF"), info...)},
+				{Row: []byte(key), Family: []byte("info"), Qualifier: []byte("server"), Value: []byte("memory:16020")},
+			}})
+		}
+	}
+	return results
+}
+
+// clientScanner returns the results of hbase:meta, or the rows of a table.
+type clientScanner struct {
+	client   *Client
+	req      *hrpc.Scan
+	results  []*hrpc.Result
+	rows     Scanner
+	err      error
+	returned int
+	closed   bool
+}
+
+func (s *clientScanner) Next() (*hrpc.Result, error) {
+	if s.closed {
+		return nil, io.EOF
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.rows == nil {
+		if len(s.results) == 0 {
+			return nil, io.EOF
+		}
+		r := s.results[0]
+		s.results = s.results[1:]
+		return r, nil
+	}
+	if s.client.Fail != nil {
+		if err := s.client.Fail(s.req, s.returned); err != nil {
+			return nil, err
+		}
+	}
+	row, err := s.rows.Next()
+	if err != nil {
+		return nil, err
+	}
+	s.returned++
+	return &hrpc.Result{Cells: row.Cells}, nil
+}
+
+func (s *clientScanner) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.client.mutex.Lock()
+	s.client.open--
+	s.client.mutex.Unlock()
+	if s.rows != nil {
+		return s.rows.Close()
+	}
+	return nil
+}
+
+// Put serves a put request, writing its cells at its timestamp or at the
+// current time of the table.
+func (c *Client) Put(put *hrpc.Mutate) (*hrpc.Result, error) {
+	t, err := c.table(put.Table())
+	if err != nil {
+		return nil, err
+	}
+	put.SetRegion(fakeRegion(put.Table()))
+	m := put.ToProto().(*pb.MutateRequest).Mutation
+	if m.GetMutateType() != pb.MutationProto_PUT {
+		return nil, fmt.Errorf("unsupported mutation %v", m.GetMutateType())
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, cv := range m.ColumnValue {
+		if err := t.checkFamily(string(cv.Family)); err != nil {
+			return nil, err
+		}
+	}
+	now := millis(t.Now())
+	for _, cv := range m.ColumnValue {
+		for _, qv := range cv.QualifierValue {
+			ts := now
+			if qv.Timestamp != nil {
+				ts = qv.GetTimestamp()
+			}
+			t.put(string(m.Row), string(cv.Family), string(qv.Qualifier), ts, qv.Value)
+		}
+	}
+	return &hrpc.Result{}, nil
+}
@@ -0,0 +1,94 @@
+package table
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase/filter"
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+func TestClientScan(t *testing.T) {
+	ctx := context.Background()
+	table, advance := newTestTable()
+	for _, key := range []string{"a", "b", "c"} {
+		if err := table.Put(ctx, []byte(key), Values{"cf": {"q": []byte(key + "1")}, "meta": {"m": []byte("x")}}, MutateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		advance(time.Second)
+	}
+	table.Put(ctx, []byte("b"), Values{"cf": {"q": []byte("b2")}}, MutateOptions{})
+	c := NewClient(table)
+
+	cells := make([]string, 0)
+	opts := scan.Options{StartRow: []byte("b"), Columns: map[string][]string{"cf": {"q"}}, MaxVersions: 2}
+	err := scan.Each(ctx, c, "test", opts, func(row scan.Row) error {
+		for _, cell := range row.Cells {
+			cells = append(cells, string(cell.Row)+"="+string(cell.Value))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"b=b2", "b=b1", "c=c1"}; !reflect.DeepEqual(cells, expect) {
+		t.Errorf("expect %v but return %v", expect, cells)
+	}
+	if c.OpenScanners() != 0 {
+		t.Errorf("expect every scanner closed but return %d open", c.OpenScanners())
+	}
+
+	if err := scan.Each(ctx, c, "missing", scan.Options{}, func(scan.Row) error { return nil }); err == nil {
+		t.Errorf("expect an error scanning a missing table")
+	}
+	opts = scan.Options{Filter: filter.NewKeyOnlyFilter(true)}
+	if err := scan.Each(ctx, c, "test", opts, func(scan.Row) error { return nil }); err == nil {
+		t.Errorf("expect an error scanning with a filter")
+	}
+}
+
+func TestClientRegions(t *testing.T) {
+	table, _ := newTestTable()
+	c := NewClient(table, NewMemory("other", "cf"))
+	c.Split("test", "m", "f")
+	regions, err := scan.Regions(context.Background(), c, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := make([]string, 0)
+	for _, r := range regions {
+		bounds = append(bounds, string(r.StartKey)+"-"+string(r.StopKey))
+	}
+	if expect := []string{"-f", "f-m", "m-"}; !reflect.DeepEqual(bounds, expect) {
+		t.Errorf("expect %v but return %v", expect, bounds)
+	}
+	if _, err := scan.Regions(context.Background(), c, "missing"); err == nil {
+		t.Errorf("expect an error listing the regions of a missing table")
+	}
+}
+
+func TestClientPut(t *testing.T) {
+	ctx := context.Background()
+	table, _ := newTestTable()
+	c := NewClient(table)
+	put, err := hrpc.NewPutStr(ctx, "test", "k", map[string]map[string][]byte{"cf": {"q": []byte("v")}}, hrpc.Timestamp(time.Unix(5, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Put(put); err != nil {
+		t.Fatal(err)
+	}
+	row, err := table.Get(ctx, []byte("k"), GetOptions{})
+	if err != nil || len(row.Cells) != 1 || string(row.Cells[0].Value) != "v" || *row.Cells[0].Timestamp != 5000 {
+		t.Errorf("expect v at 5000 but return %v, %v", row.Cells, err)
+	}
+
+	put, _ = hrpc.NewPutStr(ctx, "test", "k", map[string]map[string][]byte{"unknown": {"q": []byte("v")}})
+	if _, err := c.Put(put); err == nil {
+		t.Errorf("expect an error putting to an unknown family")
+	}
+}
@@ -0,0 +1,101 @@
+package table
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase"
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// HBase is a Table of a cluster.
+type HBase struct {
+	client gohbase.Client
+	name   string
+}
+
+// NewHBase returns the table called name of the cluster of client.
+func NewHBase(client gohbase.Client, name string) *HBase {
+	return &HBase{client: client, name: name}
+}
+
+func (t *HBase) Name() string {
+	return t.name
+}
+
+func (t *HBase) Get(ctx context.Context, key []byte, opts GetOptions) (scan.Row, error) {
+	options := make([]func(hrpc.Call) error, 0)
+	if len(opts.Columns) > 0 {
+		options = append(options, hrpc.Families(opts.Columns))
+	}
+	if !opts.From.IsZero() || !opts.To.IsZero() {
+		var from, to uint64 = 0, math.MaxInt64
+		if !opts.From.IsZero() {
+			from = uint64(opts.From.UnixNano() / 1e6)
+		}
+		if !opts.To.IsZero() {
+			to = uint64(opts.To.UnixNano() / 1e6)
+		}
+		options = append(options, hrpc.TimeRangeUint64(from, to))
+	}
+	if opts.MaxVersions > 0 {
+		options = append(options, hrpc.MaxVersions(opts.MaxVersions))
+	}
+	get, err := hrpc.NewGet(ctx, []byte(t.name), key, options...)
+	if err != nil {
+		return scan.Row{}, fmt.Errorf("invalid get of %s: %w", key, err)
+	}
+	result, err := t.client.Get(get)
+	if err != nil {
+		return scan.Row{}, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return scan.Row{Key: key, Cells: result.Cells}, nil
+}
+
+func (t *HBase) Scan(ctx context.Context, opts scan.Options) (Scanner, error) {
+	return scan.Open(ctx, t.client, t.name, opts)
+}
+
+func mutateOptions(opts MutateOptions) []func(hrpc.Call) error {
+	if opts.Timestamp.IsZero() {
+		return nil
+	}
+	return []func(hrpc.Call) error{hrpc.Timestamp(opts.Timestamp)}
+}
+
+func (t *HBase) Put(ctx context.Context, key []byte, values Values, opts MutateOptions) error {
+	put, err := hrpc.NewPut(ctx, []byte(t.name), key, values, mutateOptions(opts)...)
+	if err != nil {
+		return fmt.Errorf("invalid put of %s: %w", key, err)
+	}
+	if _, err := t.client.Put(put); err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (t *HBase) Delete(ctx context.Context, key []byte, values Values, opts MutateOptions) error {
+	del, err := hrpc.NewDel(ctx, []byte(t.name), key, values, mutateOptions(opts)...)
+	if err != nil {
+		return fmt.Errorf("invalid delete of %s: %w", key, err)
+	}
+	if _, err := t.client.Delete(del); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (t *HBase) Increment(ctx context.Context, key []byte, family, qualifier string, amount int64) (int64, error) {
+	inc, err := hrpc.NewIncSingle(ctx, []byte(t.name), key, family, qualifier, amount)
+	if err != nil {
+		return 0, fmt.Errorf("invalid increment of %s: %w", key, err)
+	}
+	v, err := t.client.Increment(inc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+	return v, nil
+}
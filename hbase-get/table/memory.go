@@ -0,0 +1,320 @@
+package table
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase/hrpc"
+)
+
+// Memory is a Table held in memory, for tests. It keeps rows in key order
+// and every version of the cells, and rejects families it was not created
+// with, like a cluster would. Server side filters are not supported.
+type Memory struct {
+	// Now returns the time of writes without a timestamp, time.Now by
+	// default.
+	Now func() time.Time
+
+	mutex    sync.RWMutex
+	name     string
+	families map[string]bool
+	keys     []string
+	rows     map[string]map[string]map[string][]version
+}
+
+// version is a version of a cell, versions of a cell being kept newest
+// first.
+type version struct {
+	timestamp uint64
+	value     []byte
+}
+
+// NewMemory returns an empty table with the given families.
+func NewMemory(name string, families ...string) *Memory {
+	t := &Memory{
+		Now:      time.Now,
+		name:     name,
+		families: make(map[string]bool, len(families)),
+		rows:     make(map[string]map[string]map[string][]version),
+	}
+	for _, f := range families {
+		t.families[f] = true
+	}
+	return t
+}
+
+func (t *Memory) Name() string {
+	return t.name
+}
+
+func millis(t time.Time) uint64 {
+	return uint64(t.UnixNano() / int64(time.Millisecond))
+}
+
+// cells returns the cells of a row selected by the options, in family,
+// qualifier and newest first order.
+func (t *Memory) cells(key string, columns map[string][]string, from, to time.Time, maxVersions uint32) []*hrpc.Cell {
+	row := t.rows[key]
+	if maxVersions == 0 {
+		maxVersions = 1
+	}
+	families := make([]string, 0, len(row))
+	for f := range row {
+		if _, ok := columns[f]; ok || len(columns) == 0 {
+			families = append(families, f)
+		}
+	}
+	sort.Strings(families)
+
+	cells := make([]*hrpc.Cell, 0)
+	for _, f := range families {
+		selected := make(map[string]bool, len(columns[f]))
+		for _, q := range columns[f] {
+			selected[q] = true
+		}
+		qualifiers := make([]string, 0, len(row[f]))
+		for q := range row[f] {
+			if len(selected) == 0 || selected[q] {
+				qualifiers = append(qualifiers, q)
+			}
+		}
+		sort.Strings(qualifiers)
+		for _, q := range qualifiers {
+			n := uint32(0)
+			for _, v := range row[f][q] {
+				if !from.IsZero() && v.timestamp < millis(from) || !to.IsZero() && v.timestamp >= millis(to) {
+					continue
+				}
+				ts := v.timestamp
+				cells = append(cells, &hrpc.Cell{
+					Row:       []byte(key),
+					Family:    []byte(f),
+					Qualifier: []byte(q),
+					Timestamp: &ts,
+					Value:     v.value,
+				})
+				if n++; n >= maxVersions {
+					break
+				}
+			}
+		}
+	}
+	return cells
+}
+
+func (t *Memory) Get(ctx context.Context, key []byte, opts GetOptions) (scan.Row, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if err := t.checkColumns(opts.Columns); err != nil {
+		return scan.Row{}, err
+	}
+	cells := t.cells(string(key), opts.Columns, opts.From, opts.To, opts.MaxVersions)
+	if len(cells) == 0 {
+		cells = nil
+	}
+	return scan.Row{Key: key, Cells: cells}, nil
+}
+
+// Scan returns the rows selected by opts as of the call.
+func (t *Memory) Scan(ctx context.Context, opts scan.Options) (Scanner, error) {
+	if opts.Filter != nil {
+		return nil, errors.New("filters are not supported by the in-memory table")
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if err := t.checkColumns(opts.Columns); err != nil {
+		return nil, err
+	}
+	start, stop := opts.Range()
+	i := sort.SearchStrings(t.keys, string(start))
+	rows := make([]scan.Row, 0)
+	for ; i < len(t.keys); i++ {
+		key := t.keys[i]
+		if len(stop) > 0 && key >= string(stop) || opts.Limit > 0 && len(rows) >= opts.Limit {
+			break
+		}
+		if cells := t.cells(key, opts.Columns, opts.From, opts.To, opts.MaxVersions); len(cells) > 0 {
+			rows = append(rows, scan.Row{Key: []byte(key), Cells: cells})
+		}
+	}
+	return &memoryScanner{rows: rows}, nil
+}
+
+type memoryScanner struct {
+	rows []scan.Row
+}
+
+func (s *memoryScanner) Next() (scan.Row, error) {
+	if len(s.rows) == 0 {
+		return scan.Row{}, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func (s *memoryScanner) Close() error {
+	s.rows = nil
+	return nil
+}
+
+func (t *Memory) checkFamily(family string) error {
+	if !t.families[family] {
+		return fmt.Errorf("unknown family %s of table %s", family, t.name)
+	}
+	return nil
+}
+
+func (t *Memory) checkColumns(columns map[string][]string) error {
+	for f := range columns {
+		if err := t.checkFamily(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put writes a version, replacing the version of the same timestamp.
+func (t *Memory) put(key, family, qualifier string, ts uint64, value []byte) {
+	row, ok := t.rows[key]
+	if !ok {
+		row = make(map[string]map[string][]version)
+		t.rows[key] = row
+		i := sort.SearchStrings(t.keys, key)
+		t.keys = append(t.keys, "")
+		copy(t.keys[i+1:], t.keys[i:])
+		t.keys[i] = key
+	}
+	if row[family] == nil {
+		row[family] = make(map[string][]version)
+	}
+	versions := row[family][qualifier]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].timestamp <= ts })
+	v := version{timestamp: ts, value: append([]byte(nil), value...)}
+	if i < len(versions) && versions[i].timestamp == ts {
+		versions[i] = v
+	} else {
+		versions = append(versions, version{})
+		copy(versions[i+1:], versions[i:])
+		versions[i] = v
+	}
+	row[family][qualifier] = versions
+}
+
+func (t *Memory) timestamp(opts MutateOptions) uint64 {
+	if opts.Timestamp.IsZero() {
+		return millis(t.Now())
+	}
+	return millis(opts.Timestamp)
+}
+
+func (t *Memory) Put(ctx context.Context, key []byte, values Values, opts MutateOptions) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for f := range values {
+		if err := t.checkFamily(f); err != nil {
+			return err
+		}
+	}
+	ts := t.timestamp(opts)
+	for f, qualifiers := range values {
+		for q, v := range qualifiers {
+			t.put(string(key), f, q, ts, v)
+		}
+	}
+	return nil
+}
+
+// deleteVersions removes the versions of a cell up to ts, all of them when
+// ts is 0.
+func deleteVersions(versions []version, ts uint64) []version {
+	if ts == 0 {
+		return nil
+	}
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].timestamp <= ts })
+	return versions[:i]
+}
+
+func (t *Memory) Delete(ctx context.Context, key []byte, values Values, opts MutateOptions) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for f := range values {
+		if err := t.checkFamily(f); err != nil {
+			return err
+		}
+	}
+	row, ok := t.rows[string(key)]
+	if !ok {
+		return nil
+	}
+	var ts uint64
+	if !opts.Timestamp.IsZero() {
+		ts = millis(opts.Timestamp)
+	}
+	for f, family := range row {
+		qualifiers, ok := values[f]
+		if !ok && len(values) > 0 {
+			continue
+		}
+		for q, versions := range family {
+			if _, ok := qualifiers[q]; ok || qualifiers == nil {
+				family[q] = deleteVersions(versions, ts)
+			}
+			if len(family[q]) == 0 {
+				delete(family, q)
+			}
+		}
+		if len(family) == 0 {
+			delete(row, f)
+		}
+	}
+	if len(row) == 0 {
+		delete(t.rows, string(key))
+		i := sort.SearchStrings(t.keys, string(key))
+		t.keys = append(t.keys[:i], t.keys[i+1:]...)
+	}
+	return nil
+}
+
+func (t *Memory) Increment(ctx context.Context, key []byte, family, qualifier string, amount int64) (int64, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if err := t.checkFamily(family); err != nil {
+		return 0, err
+	}
+	var current int64
+	if versions := t.rows[string(key)][family][qualifier]; len(versions) > 0 {
+		v := versions[0].value
+		if len(v) != 8 {
+			return 0, fmt.Errorf("%s %s:%s is not a counter, %d bytes long", key, family, qualifier, len(v))
+		}
+		current = int64(binary.BigEndian.Uint64(v))
+	}
+	current += amount
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(current))
+	t.put(string(key), family, qualifier, millis(t.Now()), b)
+	return current, nil
+}
+
+// String lists the latest cells of every row, for test failures.
+func (t *Memory) String() string {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	var b strings.Builder
+	for _, key := range t.keys {
+		for _, c := range t.cells(key, nil, time.Time{}, time.Time{}, 1) {
+			fmt.Fprintf(&b, "%s %s:%s=%q\n", key, c.Family, c.Qualifier, c.Value)
+		}
+	}
+	return b.String()
+}
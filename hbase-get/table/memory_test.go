@@ -0,0 +1,151 @@
+package table
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"hbase-get/scan"
+
+	"github.com/tsuna/gohbase/filter"
+)
+
+func newTestTable() (*Memory, func(d time.Duration)) {
+	now := time.Unix(1600000000, 0)
+	t := NewMemory("test", "cf", "meta")
+	t.Now = func() time.Time { return now }
+	return t, func(d time.Duration) { now = now.Add(d) }
+}
+
+func scanKeys(t *testing.T, table Table, opts scan.Options) []string {
+	t.Helper()
+	s, err := table.Scan(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	keys := make([]string, 0)
+	for {
+		row, err := s.Next()
+		if err == io.EOF {
+			return keys
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, string(row.Key))
+	}
+}
+
+func TestMemoryScanOrder(t *testing.T) {
+	ctx := context.Background()
+	table, _ := newTestTable()
+	for _, key := range []string{"b2", "a1", "c3", "b1", "a2"} {
+		if err := table.Put(ctx, []byte(key), Values{"cf": {"q": []byte(key)}}, MutateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		opts   scan.Options
+		expect []string
+	}{
+		{scan.Options{}, []string{"a1", "a2", "b1", "b2", "c3"}},
+		{scan.Options{Prefix: []byte("b")}, []string{"b1", "b2"}},
+		{scan.Options{StartRow: []byte("a2"), StopRow: []byte("c")}, []string{"a2", "b1", "b2"}},
+		{scan.Options{Limit: 2}, []string{"a1", "a2"}},
+		{scan.Options{Columns: map[string][]string{"meta": nil}}, []string{}},
+	}
+	for _, test := range tests {
+		if keys := scanKeys(t, table, test.opts); !reflect.DeepEqual(keys, test.expect) {
+			t.Errorf("expect %v but return %v", test.expect, keys)
+		}
+	}
+}
+
+func TestMemoryVersions(t *testing.T) {
+	ctx := context.Background()
+	table, advance := newTestTable()
+	key := []byte("row")
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := table.Put(ctx, key, Values{"cf": {"q": []byte(v)}, "meta": {"m": []byte(v)}}, MutateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		advance(time.Second)
+	}
+
+	row, err := table.Get(ctx, key, GetOptions{Columns: map[string][]string{"cf": {"q"}}, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]string, 0)
+	for _, c := range row.Cells {
+		values = append(values, string(c.Value))
+	}
+	if expect := []string{"v3", "v2"}; !reflect.DeepEqual(values, expect) {
+		t.Errorf("expect %v but return %v", expect, values)
+	}
+
+	start := time.Unix(1600000000, 0)
+	row, _ = table.Get(ctx, key, GetOptions{From: start, To: start.Add(time.Second)})
+	if len(row.Cells) != 2 || string(row.Cells[0].Family) != "cf" || string(row.Cells[1].Family) != "meta" || string(row.Cells[0].Value) != "v1" {
+		t.Errorf("expect v1 of cf:q and meta:m but return %v", row.Cells)
+	}
+
+	if err := table.Delete(ctx, key, Values{"cf": {"q": nil}}, MutateOptions{Timestamp: start.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	row, _ = table.Get(ctx, key, GetOptions{Columns: map[string][]string{"cf": nil}, MaxVersions: 3})
+	if len(row.Cells) != 1 || string(row.Cells[0].Value) != "v3" {
+		t.Errorf("expect only v3 left but return %v", row.Cells)
+	}
+
+	if err := table.Delete(ctx, key, Values{"meta": nil}, MutateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Delete(ctx, key, Values{"cf": nil}, MutateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if keys := scanKeys(t, table, scan.Options{}); len(keys) != 0 {
+		t.Errorf("expect no rows but return %v", keys)
+	}
+}
+
+func TestMemoryIncrement(t *testing.T) {
+	ctx := context.Background()
+	table, advance := newTestTable()
+	for _, test := range []struct{ amount, expect int64 }{{5, 5}, {-2, 3}} {
+		v, err := table.Increment(ctx, []byte("row"), "cf", "count", test.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != test.expect {
+			t.Errorf("expect %v but return %v", test.expect, v)
+		}
+		advance(time.Millisecond)
+	}
+
+	table.Put(ctx, []byte("row"), Values{"cf": {"text": []byte("abc")}}, MutateOptions{})
+	if _, err := table.Increment(ctx, []byte("row"), "cf", "text", 1); err == nil {
+		t.Errorf("expect an error incrementing a non counter")
+	}
+	if _, err := table.Increment(ctx, []byte("row"), "unknown", "count", 1); err == nil {
+		t.Errorf("expect an error for an unknown family")
+	}
+}
+
+func TestMemoryUnknownFamilyAndFilter(t *testing.T) {
+	ctx := context.Background()
+	table, _ := newTestTable()
+	if err := table.Put(ctx, []byte("row"), Values{"unknown": {"q": []byte("v")}}, MutateOptions{}); err == nil {
+		t.Errorf("expect an error for an unknown family")
+	}
+	if _, err := table.Scan(ctx, scan.Options{Columns: map[string][]string{"unknown": nil}}); err == nil {
+		t.Errorf("expect an error for an unknown family")
+	}
+	if _, err := table.Scan(ctx, scan.Options{Filter: filter.NewKeyOnlyFilter(false)}); err == nil {
+		t.Errorf("expect an error for a filter")
+	}
+}
@@ -0,0 +1,63 @@
+// Package table abstracts the row operations of an HBase table, so that
+// code built on them can run against a cluster through gohbase or against
+// the in-memory Memory table in tests. Client serves Memory tables to the
+// code taking a gohbase client instead, such as the scan package.
+package table
+
+import (
+	"context"
+	"time"
+
+	"hbase-get/scan"
+)
+
+// Values maps families to qualifiers to values, as gohbase mutations do.
+// For Delete, a family mapped to nil stands for the whole family.
+type Values map[string]map[string][]byte
+
+// GetOptions narrows a Get. The zero value reads every cell of the latest
+// version.
+type GetOptions struct {
+	// Columns maps families to their qualifiers to read, a family without
+	// qualifiers reads all of them. Empty means all families.
+	Columns map[string][]string
+	// From and To restrict cells to the timestamps in [From, To), a zero
+	// time leaving its side unbounded.
+	From time.Time
+	To   time.Time
+	// MaxVersions is the number of versions read per cell, 0 means 1.
+	MaxVersions uint32
+}
+
+// MutateOptions configures Put and Delete.
+type MutateOptions struct {
+	// Timestamp is the version written by Put, or the newest version
+	// removed by Delete. Zero means the current time for Put and every
+	// version for Delete.
+	Timestamp time.Time
+}
+
+// Scanner delivers the rows of a scan in key order. Next returns io.EOF
+// once all rows were returned.
+type Scanner interface {
+	Next() (scan.Row, error)
+	Close() error
+}
+
+// Table reads and writes the rows of a table.
+type Table interface {
+	// Name returns the name of the table.
+	Name() string
+	// Get returns a row, with no cells when it does not exist.
+	Get(ctx context.Context, key []byte, opts GetOptions) (scan.Row, error)
+	// Scan starts scanning the rows selected by opts.
+	Scan(ctx context.Context, opts scan.Options) (Scanner, error)
+	// Put writes values to a row.
+	Put(ctx context.Context, key []byte, values Values, opts MutateOptions) error
+	// Delete removes every version of the given columns or families of a
+	// row, or the whole row when values is empty.
+	Delete(ctx context.Context, key []byte, values Values, opts MutateOptions) error
+	// Increment adds amount to a counter stored as a big endian int64,
+	// missing counters starting at 0, and returns the new value.
+	Increment(ctx context.Context, key []byte, family, qualifier string, amount int64) (int64, error)
+}
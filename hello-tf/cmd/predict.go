@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ipoemi/hello-tf/feed"
+	"ipoemi/hello-tf/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	signature string
+	input     string
+	format    string
+	batchSize int
+	idField   string
+
+	predictCmd = &cobra.Command{
+		Use:   "predict",
+		Short: "run a signature on CSV or JSON lines rows, printing outputs as JSON lines",
+		Long: `Run a signature on rows read from --input, by batches of --batch-size rows.
+
+CSV input has a header line and a column per value of the single input of
+the signature, flattened in row major order. JSON lines input has an object
+per line of the values of each input as nested arrays, or a bare array for
+a signature of a single input. Rows are checked against the input shapes
+before they are run.
+
+Outputs are printed as a JSON object per row, with the line and the id of
+the row.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPredict(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	predictCmd.Flags().StringVar(&signature, "signature", model.DefaultSignature, "signature to run")
+	predictCmd.Flags().StringVarP(&input, "input", "i", "-", "file of rows, - for stdin")
+	predictCmd.Flags().StringVar(&format, "format", "", "format of rows: csv or jsonl (default from the extension of --input, else jsonl)")
+	predictCmd.Flags().IntVarP(&batchSize, "batch-size", "b", 64, "number of rows run at once")
+	predictCmd.Flags().StringVar(&idField, "id", "", "column or field identifying rows, copied to the output")
+
+	rootCmd.AddCommand(predictCmd)
+}
+
+type prediction struct {
+	Line    int                    `json:"line"`
+	ID      string                 `json:"id,omitempty"`
+	Outputs map[string]interface{} `json:"outputs"`
+}
+
+func newReader(r io.Reader, inputs []feed.Input) (feed.Reader, error) {
+	f := format
+	if f == "" {
+		f = "jsonl"
+		if strings.EqualFold(filepath.Ext(input), ".csv") {
+			f = "csv"
+		}
+	}
+	switch f {
+	case "csv":
+		return feed.NewCSVReader(r, inputs, idField)
+	case "jsonl", "json":
+		return feed.NewJSONReader(r, inputs, idField)
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+func runPredict(ctx context.Context) (err error) {
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	m, err := loadModel()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	sig, err := m.Signature(signature)
+	if err != nil {
+		return err
+	}
+	if err := checkSignature(sig); err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	r, err := newReader(in, sig.FeedInputs())
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer func() {
+		if ferr := w.Flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+	}()
+	enc := json.NewEncoder(w)
+	count := 0
+	for ctx.Err() == nil {
+		batch, err := feed.ReadBatch(r, batchSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		outputs, err := m.Run(sig.Name, batch)
+		if err != nil {
+			return fmt.Errorf("batch of lines %d to %d: %w", batch[0].Line, batch[len(batch)-1].Line, err)
+		}
+		for i, row := range batch {
+			if err := enc.Encode(prediction{Line: row.Line, ID: row.ID, Outputs: outputs[i]}); err != nil {
+				return err
+			}
+		}
+		count += len(batch)
+	}
+	log.Printf("%d rows predicted\n", count)
+	return ctx.Err()
+}
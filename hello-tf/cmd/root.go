@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ipoemi/hello-tf/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelDir string
+	tags     []string
+
+	rootCmd = &cobra.Command{
+		Use:          "hello-tf",
+		Short:        "run the signatures of a TensorFlow SavedModel on the CPU",
+		SilenceUsage: true,
+	}
+)
+
+// Execute runs the command line. Its context is cancelled on SIGINT or
+// SIGTERM so that commands can stop between batches.
+func Execute() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&modelDir, "model", "m", "", "directory of the SavedModel")
+	rootCmd.PersistentFlags().StringSliceVar(&tags, "tags", []string{"serve"}, "tags of the graph to load, comma separated")
+	rootCmd.MarkPersistentFlagRequired("model")
+}
+
+func loadModel() (*model.Model, error) {
+	return model.Load(modelDir, tags)
+}
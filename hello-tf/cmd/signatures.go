@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"ipoemi/hello-tf/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	output string
+
+	signaturesCmd = &cobra.Command{
+		Use:   "signatures",
+		Short: "list the signatures of the model with their inputs and outputs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSignatures()
+		},
+	}
+)
+
+func init() {
+	signaturesCmd.Flags().StringVarP(&output, "output", "o", "text", "output format: text or json")
+
+	rootCmd.AddCommand(signaturesCmd)
+}
+
+func shapeString(shape []int64) string {
+	if shape == nil {
+		return "unknown"
+	}
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		if d < 0 {
+			dims[i] = "?"
+		} else {
+			dims[i] = fmt.Sprint(d)
+		}
+	}
+	return "[" + strings.Join(dims, " ") + "]"
+}
+
+func runSignatures() error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output %q", output)
+	}
+	m, err := loadModel()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	sigs := m.Signatures()
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sigs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SIGNATURE\tKIND\tKEY\tTENSOR\tDTYPE\tSHAPE")
+	for _, sig := range sigs {
+		for _, t := range sig.Inputs {
+			fmt.Fprintf(w, "%s\tinput\t%s\t%s\t%s\t%s\n", sig.Name, t.Key, t.Name, t.DType, shapeString(t.Shape))
+		}
+		for _, t := range sig.Outputs {
+			fmt.Fprintf(w, "%s\toutput\t%s\t%s\t%s\t%s\n", sig.Name, t.Key, t.Name, t.DType, shapeString(t.Shape))
+		}
+	}
+	return w.Flush()
+}
+
+// checkSignature checks that the inputs of sig can be fed from rows.
+func checkSignature(sig model.SignatureSpec) error {
+	for _, t := range sig.Inputs {
+		switch t.DType {
+		case "float32", "float64", "int32", "int64":
+		default:
+			return fmt.Errorf("input %s of signature %s is %s, only numeric inputs are supported", t.Key, sig.Name, t.DType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+// Package features turns candles into fixed windows of normalized features,
+// the input of price prediction models.
+//
+// The features of a candle are the log returns of its open, high, low and
+// close prices over the previous close, the relative strength index of its
+// close and the volatility of the close returns. A window is the features of
+// consecutive candles, a tensor of shape [window, len(Names)].
+package features
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"ipoemi/candle-loader/trade/candle"
+)
+
+// Names are the names of the features, in their order in a window.
+var Names = []string{"open_return", "high_return", "low_return", "close_return", "rsi", "volatility"}
+
+// Normalization is how the features of a window are scaled.
+type Normalization int
+
+const (
+	// None keeps features as computed, the RSI being scaled to [0, 1].
+	None Normalization = iota
+	// ZScore centers each feature of a window on its mean and scales it by
+	// its standard deviation.
+	ZScore
+	// MinMax scales each feature of a window to [0, 1].
+	MinMax
+)
+
+var normalizations = []string{"none", "zscore", "minmax"}
+
+func (n Normalization) String() string {
+	if n < 0 || int(n) >= len(normalizations) {
+		return fmt.Sprintf("normalization(%d)", int(n))
+	}
+	return normalizations[n]
+}
+
+// ParseNormalization returns the Normalization called s.
+func ParseNormalization(s string) (Normalization, error) {
+	for i, name := range normalizations {
+		if strings.EqualFold(s, name) {
+			return Normalization(i), nil
+		}
+	}
+	return None, fmt.Errorf("unknown normalization %q, expected one of %s", s, strings.Join(normalizations, ", "))
+}
+
+// Config configures windows.
+type Config struct {
+	// Window is the number of candles of a window.
+	Window int
+	// Stride is the number of candles between the ends of two windows.
+	Stride int
+	// RSIPeriod is the period of the RSI, in candles.
+	RSIPeriod int
+	// VolatilityPeriod is the number of close returns of the volatility.
+	VolatilityPeriod int
+	Normalization    Normalization
+}
+
+// DefaultConfig is a config for windows of 32 candles.
+var DefaultConfig = Config{
+	Window:           32,
+	Stride:           1,
+	RSIPeriod:        14,
+	VolatilityPeriod: 20,
+	Normalization:    ZScore,
+}
+
+func (c Config) validate() error {
+	if c.Window <= 0 {
+		return fmt.Errorf("invalid window %d", c.Window)
+	}
+	if c.Stride <= 0 {
+		return fmt.Errorf("invalid stride %d", c.Stride)
+	}
+	if c.RSIPeriod <= 0 {
+		return fmt.Errorf("invalid rsi period %d", c.RSIPeriod)
+	}
+	if c.VolatilityPeriod <= 1 {
+		return fmt.Errorf("invalid volatility period %d", c.VolatilityPeriod)
+	}
+	if c.Normalization < None || c.Normalization > MinMax {
+		return fmt.Errorf("invalid %v", c.Normalization)
+	}
+	return nil
+}
+
+// Warmup returns the number of candles before the first one with all its
+// features, those of the periods of the RSI and of the volatility.
+func (c Config) Warmup() int {
+	warmup := c.RSIPeriod
+	if c.VolatilityPeriod > warmup {
+		warmup = c.VolatilityPeriod
+	}
+	return warmup
+}
+
+// Windows are windows of features, flattened in row major order in Data.
+type Windows struct {
+	Data []float32
+	// Shape is [windows, window, len(Names)].
+	Shape []int64
+	// Timestamps are the trade timestamps of the last candle of each window.
+	Timestamps []int64
+}
+
+// Len returns the number of windows.
+func (w Windows) Len() int {
+	return len(w.Timestamps)
+}
+
+// Window returns window i, a row per candle.
+func (w Windows) Window(i int) [][]float32 {
+	size, width := int(w.Shape[1]), int(w.Shape[2])
+	rows := make([][]float32, size)
+	for j := range rows {
+		start := (i*size + j) * width
+		rows[j] = w.Data[start : start+width]
+	}
+	return rows
+}
+
+// Compute returns the features of candles, sorted by trade timestamp, a
+// row per candle from the first after the warmup of c.
+func Compute(candles []candle.Candle, c Config) ([][]float64, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	for i, cd := range candles {
+		if cd.GetOpeningPrice() <= 0 || cd.GetHighPrice() <= 0 || cd.GetLowPrice() <= 0 || cd.GetTradePrice() <= 0 {
+			return nil, fmt.Errorf("candle %d of %s at %d has a non positive price", i, cd.GetMarketId(), cd.GetTradeTimestamp())
+		}
+		if i > 0 && cd.GetTradeTimestamp() <= candles[i-1].GetTradeTimestamp() {
+			return nil, fmt.Errorf("candle %d of %s at %d is not after the previous one at %d", i, cd.GetMarketId(), cd.GetTradeTimestamp(), candles[i-1].GetTradeTimestamp())
+		}
+	}
+	warmup := c.Warmup()
+	if len(candles) <= warmup {
+		return nil, nil
+	}
+
+	rsi := RSI(candles, c.RSIPeriod)
+	returns := make([]float64, len(candles))
+	for i := 1; i < len(candles); i++ {
+		returns[i] = math.Log(candles[i].GetTradePrice() / candles[i-1].GetTradePrice())
+	}
+	rows := make([][]float64, 0, len(candles)-warmup)
+	for i := warmup; i < len(candles); i++ {
+		prev := candles[i-1].GetTradePrice()
+		rows = append(rows, []float64{
+			math.Log(candles[i].GetOpeningPrice() / prev),
+			math.Log(candles[i].GetHighPrice() / prev),
+			math.Log(candles[i].GetLowPrice() / prev),
+			returns[i],
+			rsi[i] / 100,
+			stddev(returns[i-c.VolatilityPeriod+1 : i+1]),
+		})
+	}
+	return rows, nil
+}
+
+// RSI returns the relative strength index of the close prices of candles
+// over period, with the smoothing of Wilder. The RSI of the first period
+// candles is undefined and zero.
+func RSI(candles []candle.Candle, period int) []float64 {
+	rsi := make([]float64, len(candles))
+	if len(candles) <= period {
+		return rsi
+	}
+	var gain, loss float64
+	for i := 1; i < len(candles); i++ {
+		change := candles[i].GetTradePrice() - candles[i-1].GetTradePrice()
+		g, l := math.Max(change, 0), math.Max(-change, 0)
+		if i <= period {
+			gain += g / float64(period)
+			loss += l / float64(period)
+			if i < period {
+				continue
+			}
+		} else {
+			gain = (gain*float64(period-1) + g) / float64(period)
+			loss = (loss*float64(period-1) + l) / float64(period)
+		}
+		switch {
+		case gain == 0 && loss == 0:
+			rsi[i] = 50
+		case loss == 0:
+			rsi[i] = 100
+		default:
+			rsi[i] = 100 - 100/(1+gain/loss)
+		}
+	}
+	return rsi
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stddev returns the sample standard deviation of values.
+func stddev(values []float64) float64 {
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// Build returns the windows of features of candles, sorted by trade
+// timestamp, the last window ending with the last candle. Features are
+// normalized by window, so that a window does not depend on later candles.
+func Build(candles []candle.Candle, c Config) (Windows, error) {
+	rows, err := Compute(candles, c)
+	if err != nil {
+		return Windows{}, err
+	}
+	width := len(Names)
+	w := Windows{Shape: []int64{0, int64(c.Window), int64(width)}}
+	if len(rows) < c.Window {
+		return w, nil
+	}
+	n := (len(rows)-c.Window)/c.Stride + 1
+	w.Data = make([]float32, 0, n*c.Window*width)
+	w.Timestamps = make([]int64, 0, n)
+	// windows end with the last candle, skipping the oldest rows
+	first := (len(rows) - c.Window) % c.Stride
+	for end := first + c.Window; end <= len(rows); end += c.Stride {
+		for _, row := range normalize(rows[end-c.Window:end], c.Normalization) {
+			for _, v := range row {
+				w.Data = append(w.Data, float32(v))
+			}
+		}
+		w.Timestamps = append(w.Timestamps, candles[c.Warmup()+end-1].GetTradeTimestamp())
+	}
+	w.Shape[0] = int64(len(w.Timestamps))
+	return w, nil
+}
+
+// normalize returns the rows of a window normalized by feature.
+func normalize(rows [][]float64, n Normalization) [][]float64 {
+	if n == None {
+		return rows
+	}
+	result := make([][]float64, len(rows))
+	for i := range rows {
+		result[i] = make([]float64, len(rows[i]))
+	}
+	column := make([]float64, len(rows))
+	for j := range rows[0] {
+		for i := range rows {
+			column[i] = rows[i][j]
+		}
+		var offset, scale float64
+		switch n {
+		case ZScore:
+			offset, scale = mean(column), 0
+			if len(column) > 1 {
+				scale = stddev(column)
+			}
+		case MinMax:
+			min, max := column[0], column[0]
+			for _, v := range column {
+				min, max = math.Min(min, v), math.Max(max, v)
+			}
+			offset, scale = min, max-min
+		}
+		for i := range rows {
+			// constant features are all zero
+			if scale > 0 {
+				result[i][j] = (column[i] - offset) / scale
+			}
+		}
+	}
+	return result
+}
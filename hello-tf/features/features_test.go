@@ -0,0 +1,156 @@
+package features
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"ipoemi/candle-loader/trade/candle"
+)
+
+func newCandles(closes ...float64) []candle.Candle {
+	candles := make([]candle.Candle, len(closes))
+	open := closes[0]
+	for i, c := range closes {
+		candles[i] = candle.MinuteCandle{
+			MarketId:       "KRW-BTC",
+			Unit:           1,
+			TradeTimestamp: int64(60 * i),
+			OpeningPrice:   open,
+			HighPrice:      math.Max(open, c) + 1,
+			LowPrice:       math.Min(open, c) - 1,
+			TradePrice:     c,
+		}
+		open = c
+	}
+	return candles
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestRSI(t *testing.T) {
+	tests := []struct {
+		closes []float64
+		expect float64
+	}{
+		{[]float64{10, 11, 12, 13}, 100},
+		{[]float64{13, 12, 11, 10}, 0},
+		{[]float64{10, 10, 10, 10}, 50},
+		// gains 1, 2 and a loss of 1 average to 1 and 1/3
+		{[]float64{10, 11, 13, 12}, 75},
+	}
+	for _, test := range tests {
+		rsi := RSI(newCandles(test.closes...), 3)
+		if rsi[2] != 0 || !almostEqual(rsi[3], test.expect) {
+			t.Errorf("expect %v but return %v", test.expect, rsi)
+		}
+	}
+
+	// a loss of 2 then smoothed: gain (1*2+0)/3, loss (1/3*2+2)/3
+	rsi := RSI(newCandles(10, 11, 13, 12, 10), 3)
+	gain, loss := 2.0/3, (2.0/3+2)/3
+	if expect := 100 - 100/(1+gain/loss); !almostEqual(rsi[4], expect) {
+		t.Errorf("expect %v but return %v", expect, rsi[4])
+	}
+}
+
+func TestCompute(t *testing.T) {
+	c := Config{Window: 2, Stride: 1, RSIPeriod: 2, VolatilityPeriod: 3}
+	candles := newCandles(100, 110, 99, 108.9, 108.9)
+	rows, err := Compute(candles, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expect 2 rows but return %d", len(rows))
+	}
+	row := rows[0]
+	returns := []float64{math.Log(1.1), math.Log(0.9), math.Log(1.1)}
+	if !almostEqual(row[0], 0) || !almostEqual(row[3], returns[2]) || !almostEqual(row[1], math.Log(109.9/99)) || !almostEqual(row[2], math.Log(98.0/99)) {
+		t.Errorf("expect returns over 99 but return %v", row)
+	}
+	m := (returns[0] + returns[1] + returns[2]) / 3
+	sd := math.Sqrt(((returns[0]-m)*(returns[0]-m) + (returns[1]-m)*(returns[1]-m) + (returns[2]-m)*(returns[2]-m)) / 2)
+	if !almostEqual(row[5], sd) {
+		t.Errorf("expect %v but return %v", sd, row[5])
+	}
+	if rsi := RSI(candles, 2); !almostEqual(row[4], rsi[3]/100) {
+		t.Errorf("expect %v but return %v", rsi[3]/100, row[4])
+	}
+
+	tests := [][]candle.Candle{
+		{candle.MinuteCandle{TradeTimestamp: 1, OpeningPrice: 1, HighPrice: 1, LowPrice: 0, TradePrice: 1}},
+		{candles[1], candles[0]},
+	}
+	for _, test := range tests {
+		if _, err := Compute(test, c); err == nil {
+			t.Errorf("expect an error for %v", test)
+		}
+	}
+	if _, err := Compute(candles, Config{Window: 2, Stride: 1, RSIPeriod: 2, VolatilityPeriod: 1}); err == nil {
+		t.Errorf("expect an error for a volatility period of 1")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	closes := make([]float64, 0)
+	for i := 0; i < 12; i++ {
+		closes = append(closes, 100+float64(i%3))
+	}
+	candles := newCandles(closes...)
+	c := Config{Window: 4, Stride: 3, RSIPeriod: 2, VolatilityPeriod: 2, Normalization: MinMax}
+
+	w, err := Build(candles, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 rows after the warmup of 2, windows end at rows 10, 7 and 4
+	if expect := []int64{3, 4, int64(len(Names))}; !reflect.DeepEqual(w.Shape, expect) {
+		t.Errorf("expect %v but return %v", expect, w.Shape)
+	}
+	if expect := []int64{5 * 60, 8 * 60, 11 * 60}; !reflect.DeepEqual(w.Timestamps, expect) {
+		t.Errorf("expect %v but return %v", expect, w.Timestamps)
+	}
+	if len(w.Data) != 3*4*len(Names) {
+		t.Errorf("expect %d values but return %d", 3*4*len(Names), len(w.Data))
+	}
+	for i := 0; i < w.Len(); i++ {
+		for _, row := range w.Window(i) {
+			for _, v := range row {
+				if v < 0 || v > 1 {
+					t.Errorf("expect values in [0, 1] but return %v", w.Window(i))
+				}
+			}
+		}
+	}
+
+	c.Normalization = ZScore
+	w, _ = Build(candles, c)
+	for j := range Names {
+		sum := 0.0
+		for _, row := range w.Window(0) {
+			sum += float64(row[j])
+		}
+		if !almostEqual(sum, 0) {
+			t.Errorf("expect feature %s centered but return a sum of %v", Names[j], sum)
+		}
+	}
+
+	w, _ = Build(candles[:5], c)
+	if w.Len() != 0 || w.Shape[0] != 0 {
+		t.Errorf("expect no windows but return %v", w)
+	}
+}
+
+func TestParseNormalization(t *testing.T) {
+	for _, n := range []Normalization{None, ZScore, MinMax} {
+		if parsed, err := ParseNormalization(n.String()); err != nil || parsed != n {
+			t.Errorf("expect %v but return %v, %v", n, parsed, err)
+		}
+	}
+	if _, err := ParseNormalization("log"); err == nil {
+		t.Errorf("expect an error for an unknown normalization")
+	}
+}
@@ -0,0 +1,90 @@
+package feed
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type csvReader struct {
+	r     *csv.Reader
+	input Input
+	shape []int64
+	cols  []int
+	id    int
+	line  int
+}
+
+// NewCSVReader returns a Reader of CSV rows, for models of a single input.
+// The first line is a header. Columns other than idColumn are the values
+// of the input flattened in row major order, so their count must match its
+// shape. idColumn may be empty. Quoted values must not span lines, so
+// that rows are reported by line.
+func NewCSVReader(r io.Reader, inputs []Input, idColumn string) (Reader, error) {
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("csv input needs a model of a single input, got %d inputs", len(inputs))
+	}
+	shape, err := inputs[0].RowShape()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range shape {
+		if d < 0 {
+			return nil, fmt.Errorf("input %s: csv input needs a fully known shape, got %s", inputs[0].Name, shapeString(shape))
+		}
+	}
+
+	c := &csvReader{r: csv.NewReader(r), input: inputs[0], shape: shape, id: -1, line: 1}
+	c.r.ReuseRecord = true
+	header, err := c.r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv input without header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	for i, name := range header {
+		if idColumn != "" && name == idColumn {
+			c.id = i
+			continue
+		}
+		c.cols = append(c.cols, i)
+	}
+	if idColumn != "" && c.id < 0 {
+		return nil, fmt.Errorf("id column %s not in csv header", idColumn)
+	}
+	if n := numElements(shape); int64(len(c.cols)) != n {
+		return nil, fmt.Errorf("input %s of shape %s needs %d columns, got %d", c.input.Name, shapeString(shape), n, len(c.cols))
+	}
+	return c, nil
+}
+
+func (c *csvReader) Read() (Row, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return Row{}, io.EOF
+	}
+	c.line++
+	line := c.line
+	if err != nil {
+		return Row{}, fmt.Errorf("failed to read csv: %w", err)
+	}
+	values := make([]float64, len(c.cols))
+	for i, col := range c.cols {
+		v, err := strconv.ParseFloat(record[col], 64)
+		if err != nil {
+			return Row{}, fmt.Errorf("line %d: invalid value %q in column %d", line, record[col], col+1)
+		}
+		values[i] = v
+	}
+	row := Row{
+		Line:   line,
+		Values: map[string][]float64{c.input.Name: values},
+		Shapes: map[string][]int64{c.input.Name: c.shape},
+	}
+	if c.id >= 0 {
+		row.ID = record[c.id]
+	}
+	return row, nil
+}
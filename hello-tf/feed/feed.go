@@ -0,0 +1,142 @@
+// Package feed reads the input rows of a model from CSV or JSON lines and
+// validates them against the shapes of the model inputs, so that shape
+// errors are reported by row before anything runs.
+package feed
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Input is an input of a model. Shape is the shape of the input tensor,
+// its first dimension being the batch, -1 for dimensions of unknown size
+// and nil for an unknown rank.
+type Input struct {
+	Name  string
+	Shape []int64
+}
+
+// RowShape returns the shape of the input for a single row, the shape
+// without its batch dimension.
+func (in Input) RowShape() ([]int64, error) {
+	if in.Shape == nil {
+		return nil, fmt.Errorf("input %s has an unknown rank", in.Name)
+	}
+	if len(in.Shape) == 0 {
+		return nil, fmt.Errorf("input %s is a scalar, without a batch dimension", in.Name)
+	}
+	return in.Shape[1:], nil
+}
+
+// Row is an input row, each input flattened in row major order.
+type Row struct {
+	// Line is the line of the row in its file, from 1.
+	Line int
+	// ID identifies the row in the output, empty when not configured.
+	ID     string
+	Values map[string][]float64
+	Shapes map[string][]int64
+}
+
+func numElements(shape []int64) int64 {
+	n := int64(1)
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+// shapeString formats a shape as [2 3], ? standing for unknown sizes.
+func shapeString(shape []int64) string {
+	dims := make([]string, 0, len(shape))
+	for _, d := range shape {
+		if d < 0 {
+			dims = append(dims, "?")
+		} else {
+			dims = append(dims, fmt.Sprint(d))
+		}
+	}
+	return "[" + strings.Join(dims, " ") + "]"
+}
+
+// checkShape checks the shape of a row value against the expected one.
+func checkShape(name string, expect, shape []int64) error {
+	if len(expect) != len(shape) {
+		return fmt.Errorf("input %s: expect shape %s but got %s", name, shapeString(expect), shapeString(shape))
+	}
+	for i := range expect {
+		if expect[i] >= 0 && expect[i] != shape[i] {
+			return fmt.Errorf("input %s: expect shape %s but got %s", name, shapeString(expect), shapeString(shape))
+		}
+	}
+	return nil
+}
+
+// Batch groups rows fed to a model at once. Rows of a batch must have the
+// same shape for each input, which only differ for dimensions of unknown
+// size.
+type Batch []Row
+
+// Values returns the values of an input over the batch.
+func (b Batch) Values(name string) []float64 {
+	n := 0
+	for _, r := range b {
+		n += len(r.Values[name])
+	}
+	values := make([]float64, 0, n)
+	for _, r := range b {
+		values = append(values, r.Values[name]...)
+	}
+	return values
+}
+
+// Shape returns the shape of the tensor of an input over the batch.
+func (b Batch) Shape(name string) []int64 {
+	if len(b) == 0 {
+		return nil
+	}
+	return append([]int64{int64(len(b))}, b[0].Shapes[name]...)
+}
+
+// Check checks that rows share their shapes. An empty batch is valid.
+func (b Batch) Check() error {
+	if len(b) == 0 {
+		return nil
+	}
+	for _, r := range b[1:] {
+		for name, shape := range r.Shapes {
+			if err := checkShape(name, b[0].Shapes[name], shape); err != nil {
+				return fmt.Errorf("line %d: %v, as the previous rows of the batch", r.Line, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Reader reads rows. Read returns io.EOF after the last row.
+type Reader interface {
+	Read() (Row, error)
+}
+
+// ReadBatch reads up to size rows. It returns io.EOF only with no row.
+func ReadBatch(r Reader, size int) (Batch, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", size)
+	}
+	b := make(Batch, 0, size)
+	for len(b) < size {
+		row, err := r.Read()
+		if err != nil {
+			if len(b) > 0 && err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		b = append(b, row)
+	}
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,142 @@
+package feed
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, r Reader) []Row {
+	t.Helper()
+	rows := make([]Row, 0)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	inputs := []Input{{Name: "x", Shape: []int64{-1, 2, 2}}}
+	r, err := NewCSVReader(strings.NewReader("id,a,b,c,d\nr1,1,2,3,4\nr2,5,6,7,8\n"), inputs, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readAll(t, r)
+	if len(rows) != 2 {
+		t.Fatalf("expect 2 rows but return %d", len(rows))
+	}
+	if rows[1].ID != "r2" || rows[1].Line != 3 {
+		t.Errorf("expect r2 at line 3 but return %s at line %d", rows[1].ID, rows[1].Line)
+	}
+	if expect := []float64{5, 6, 7, 8}; !reflect.DeepEqual(rows[1].Values["x"], expect) {
+		t.Errorf("expect %v but return %v", expect, rows[1].Values["x"])
+	}
+	if expect := []int64{2, 2}; !reflect.DeepEqual(rows[1].Shapes["x"], expect) {
+		t.Errorf("expect %v but return %v", expect, rows[1].Shapes["x"])
+	}
+
+	tests := []struct {
+		inputs []Input
+		data   string
+	}{
+		{inputs, "a,b,c\n1,2,3\n"},
+		{[]Input{{Name: "x", Shape: []int64{-1, -1}}}, "a,b\n1,2\n"},
+		{[]Input{{Name: "x", Shape: []int64{-1, 1}}, {Name: "y", Shape: []int64{-1, 1}}}, "a,b\n1,2\n"},
+		{[]Input{{Name: "x"}}, "a\n1\n"},
+	}
+	for _, test := range tests {
+		if _, err := NewCSVReader(strings.NewReader(test.data), test.inputs, ""); err == nil {
+			t.Errorf("expect an error for %v and %q", test.inputs, test.data)
+		}
+	}
+
+	r, _ = NewCSVReader(strings.NewReader("a,b,c,d\n1,2,x,4\n"), inputs, "")
+	if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expect an error at line 2 but return %v", err)
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	inputs := []Input{{Name: "x", Shape: []int64{-1, 2, -1}}, {Name: "y", Shape: []int64{-1}}}
+	data := `{"id": "r1", "x": [[1, 2, 3], [4, 5, 6]], "y": 7}
+
+{"id": 2, "x": [[1], [2]], "y": 3}
+`
+	r, err := NewJSONReader(strings.NewReader(data), inputs, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readAll(t, r)
+	if len(rows) != 2 {
+		t.Fatalf("expect 2 rows but return %d", len(rows))
+	}
+	if expect := []float64{1, 2, 3, 4, 5, 6}; !reflect.DeepEqual(rows[0].Values["x"], expect) {
+		t.Errorf("expect %v but return %v", expect, rows[0].Values["x"])
+	}
+	if expect := []int64{2, 3}; !reflect.DeepEqual(rows[0].Shapes["x"], expect) {
+		t.Errorf("expect %v but return %v", expect, rows[0].Shapes["x"])
+	}
+	if expect := []int64{}; !reflect.DeepEqual(rows[0].Shapes["y"], expect) {
+		t.Errorf("expect %v but return %v", expect, rows[0].Shapes["y"])
+	}
+	if rows[1].ID != "2" || rows[1].Line != 3 {
+		t.Errorf("expect 2 at line 3 but return %s at line %d", rows[1].ID, rows[1].Line)
+	}
+
+	for _, line := range []string{
+		`{"x": [[1, 2], [3]], "y": 1}`,
+		`{"x": [[1, 2]], "y": 1}`,
+		`{"x": [[1], [2]], "y": [1]}`,
+		`{"x": [[1], [2]]}`,
+		`{"x": [[1], [2]], "y": 1, "z": 2}`,
+		`{"x": [["a"], [2]], "y": 1}`,
+		`[1, 2]`,
+	} {
+		r, _ := NewJSONReader(strings.NewReader(line), inputs, "id")
+		if _, err := r.Read(); err == nil {
+			t.Errorf("expect an error for %s", line)
+		}
+	}
+
+	r, _ = NewJSONReader(strings.NewReader("[[1, 2], [3, 4]]\n"), []Input{{Name: "x", Shape: []int64{-1, 2, 2}}}, "")
+	if row, err := r.Read(); err != nil || !reflect.DeepEqual(row.Values["x"], []float64{1, 2, 3, 4}) {
+		t.Errorf("expect [1 2 3 4] but return %v, %v", row.Values["x"], err)
+	}
+}
+
+func TestReadBatch(t *testing.T) {
+	inputs := []Input{{Name: "x", Shape: []int64{-1, -1}}}
+	r, _ := NewJSONReader(strings.NewReader("[1, 2]\n[3, 4]\n[5, 6]\n[7]\n"), inputs, "")
+
+	b, err := ReadBatch(r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []float64{1, 2, 3, 4}; !reflect.DeepEqual(b.Values("x"), expect) {
+		t.Errorf("expect %v but return %v", expect, b.Values("x"))
+	}
+	if expect := []int64{2, 2}; !reflect.DeepEqual(b.Shape("x"), expect) {
+		t.Errorf("expect %v but return %v", expect, b.Shape("x"))
+	}
+	if _, err := ReadBatch(r, 2); err == nil {
+		t.Errorf("expect an error for rows of different shapes")
+	}
+	if _, err := ReadBatch(r, 2); err != io.EOF {
+		t.Errorf("expect io.EOF but return %v", err)
+	}
+	for _, size := range []int{0, -1} {
+		if _, err := ReadBatch(r, size); err == nil {
+			t.Errorf("expect an error for a batch size of %d", size)
+		}
+	}
+	if err := (Batch{}).Check(); err != nil {
+		t.Errorf("expect an empty batch to be valid but return %v", err)
+	}
+}
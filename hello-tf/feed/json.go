@@ -0,0 +1,156 @@
+package feed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type jsonReader struct {
+	s      *bufio.Scanner
+	inputs []Input
+	shapes [][]int64
+	id     string
+	line   int
+}
+
+// NewJSONReader returns a Reader of JSON lines. Each line is an object of
+// the values of each input, as nested arrays of numbers, and of the row id
+// under idField when not empty. Models of a single input also accept a bare
+// array per line. Empty lines are skipped.
+func NewJSONReader(r io.Reader, inputs []Input, idField string) (Reader, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("model without inputs")
+	}
+	shapes := make([][]int64, len(inputs))
+	for i, in := range inputs {
+		if in.Name == idField {
+			return nil, fmt.Errorf("id field %s is an input of the model", idField)
+		}
+		shape, err := in.RowShape()
+		if err != nil {
+			return nil, err
+		}
+		shapes[i] = shape
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return &jsonReader{s: s, inputs: inputs, shapes: shapes, id: idField}, nil
+}
+
+func (j *jsonReader) Read() (Row, error) {
+	for j.s.Scan() {
+		j.line++
+		line := bytes.TrimSpace(j.s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		row, err := j.parse(line)
+		if err != nil {
+			return Row{}, fmt.Errorf("line %d: %v", j.line, err)
+		}
+		row.Line = j.line
+		return row, nil
+	}
+	if err := j.s.Err(); err != nil {
+		return Row{}, fmt.Errorf("failed to read json lines: %w", err)
+	}
+	return Row{}, io.EOF
+}
+
+func (j *jsonReader) parse(line []byte) (Row, error) {
+	fields := make(map[string]json.RawMessage)
+	if line[0] == '[' {
+		if len(j.inputs) != 1 {
+			return Row{}, fmt.Errorf("bare array for a model of %d inputs", len(j.inputs))
+		}
+		fields[j.inputs[0].Name] = line
+	} else if err := json.Unmarshal(line, &fields); err != nil {
+		return Row{}, fmt.Errorf("invalid json: %v", err)
+	}
+
+	row := Row{Values: make(map[string][]float64), Shapes: make(map[string][]int64)}
+	if j.id != "" {
+		if raw, ok := fields[j.id]; ok {
+			var id interface{}
+			if err := json.Unmarshal(raw, &id); err != nil {
+				return Row{}, fmt.Errorf("invalid id: %v", err)
+			}
+			if s, ok := id.(string); ok {
+				row.ID = s
+			} else {
+				row.ID = string(raw)
+			}
+			delete(fields, j.id)
+		}
+	}
+	for i, in := range j.inputs {
+		raw, ok := fields[in.Name]
+		if !ok {
+			return Row{}, fmt.Errorf("missing input %s", in.Name)
+		}
+		delete(fields, in.Name)
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return Row{}, fmt.Errorf("input %s: invalid json: %v", in.Name, err)
+		}
+		values, shape, err := flatten(v)
+		if err != nil {
+			return Row{}, fmt.Errorf("input %s: %v", in.Name, err)
+		}
+		if err := checkShape(in.Name, j.shapes[i], shape); err != nil {
+			return Row{}, err
+		}
+		row.Values[in.Name] = values
+		row.Shapes[in.Name] = shape
+	}
+	for name := range fields {
+		return Row{}, fmt.Errorf("unknown input %s", name)
+	}
+	return row, nil
+}
+
+// flatten flattens nested arrays of numbers in row major order and returns
+// their shape, failing unless they are rectangular.
+func flatten(v interface{}) ([]float64, []int64, error) {
+	switch v := v.(type) {
+	case float64:
+		return []float64{v}, []int64{}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, nil, fmt.Errorf("empty array")
+		}
+		var values []float64
+		var shape []int64
+		for i, e := range v {
+			ev, es, err := flatten(e)
+			if err != nil {
+				return nil, nil, err
+			}
+			if i == 0 {
+				shape = append([]int64{int64(len(v))}, es...)
+				values = make([]float64, 0, len(v)*len(ev))
+			} else if !equalShape(shape[1:], es) {
+				return nil, nil, fmt.Errorf("ragged array, element %d has shape %s but element 0 %s", i, shapeString(es), shapeString(shape[1:]))
+			}
+			values = append(values, ev...)
+		}
+		return values, shape, nil
+	default:
+		return nil, nil, fmt.Errorf("unexpected value %v, expected numbers", v)
+	}
+}
+
+func equalShape(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -4,5 +4,9 @@ go 1.16
 
 require (
 	github.com/golang/protobuf v1.5.1 // indirect
-	github.com/tensorflow/tensorflow v2.4.1+incompatible // indirect
+	github.com/spf13/cobra v1.1.3
+	github.com/tensorflow/tensorflow v2.4.1+incompatible
+	ipoemi/candle-loader v0.0.0
 )
+
+replace ipoemi/candle-loader => ../candle-loader
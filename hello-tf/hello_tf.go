@@ -1,28 +1,13 @@
 package main
 
 import (
-  tf "github.com/tensorflow/tensorflow/tensorflow/go"
-  "github.com/tensorflow/tensorflow/tensorflow/go/op"
-  "fmt"
+	"os"
+
+	"ipoemi/hello-tf/cmd"
 )
 
 func main() {
-  // Construct a graph with an operation that produces a string constant.
-  s := op.NewScope()
-  c := op.Const(s, "Hello from TensorFlow version " + tf.Version())
-  graph, err := s.Finalize()
-  if err != nil {
-    panic(err)
-  }
-
-  // Execute the graph in a session.
-  sess, err := tf.NewSession(graph, nil)
-  if err != nil {
-    panic(err)
-  }
-  output, err := sess.Run(nil, []tf.Output{c}, nil)
-  if err != nil {
-    panic(err)
-  }
-  fmt.Println(output[0].Value())
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
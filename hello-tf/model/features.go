@@ -0,0 +1,18 @@
+package model
+
+import (
+	"fmt"
+
+	"ipoemi/hello-tf/features"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+// WindowsTensor returns windows of candle features as a float32 tensor of
+// shape [windows, window, len(features.Names)].
+func WindowsTensor(w features.Windows) (*tf.Tensor, error) {
+	if w.Len() == 0 {
+		return nil, fmt.Errorf("no windows of features")
+	}
+	return readTensor(tf.Float, w.Shape, w.Data)
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"ipoemi/candle-loader/trade/candle"
+	"ipoemi/hello-tf/features"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+	"github.com/tensorflow/tensorflow/tensorflow/go/op"
+)
+
+func newCandles(n int) []candle.Candle {
+	candles := make([]candle.Candle, n)
+	price := 100.0
+	for i := range candles {
+		next := price * (1 + 0.01*math.Sin(float64(i)))
+		candles[i] = candle.MinuteCandle{
+			MarketId:       "KRW-BTC",
+			Unit:           1,
+			TradeTimestamp: int64(60 * i),
+			OpeningPrice:   price,
+			HighPrice:      math.Max(price, next) * 1.002,
+			LowPrice:       math.Min(price, next) * 0.998,
+			TradePrice:     next,
+		}
+		price = next
+	}
+	return candles
+}
+
+// TestWindowsTensorRoundTrip feeds windows to a graph on the CPU, which
+// returns them and their mean over each window.
+func TestWindowsTensorRoundTrip(t *testing.T) {
+	c := features.DefaultConfig
+	c.Window, c.Stride = 8, 4
+	w, err := features.Build(newCandles(64), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tensor, err := WindowsTensor(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tensor.Shape(), w.Shape) {
+		t.Errorf("expect %v but return %v", w.Shape, tensor.Shape())
+	}
+
+	s := op.NewScope()
+	input := op.Placeholder(s, tf.Float, op.PlaceholderShape(tf.MakeShape(-1, int64(c.Window), int64(len(features.Names)))))
+	identity := op.Identity(s, input)
+	mean := op.Mean(s, input, op.Const(s, int32(1)))
+	graph, err := s.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := tf.NewSession(graph, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	results, err := sess.Run(map[tf.Output]*tf.Tensor{input: tensor}, []tf.Output{identity, mean}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windows := results[0].Value().([][][]float32)
+	means := results[1].Value().([][]float32)
+	if len(windows) != w.Len() || len(means) != w.Len() {
+		t.Fatalf("expect %d windows but return %d and %d means", w.Len(), len(windows), len(means))
+	}
+	for i := range windows {
+		if !reflect.DeepEqual(windows[i], w.Window(i)) {
+			t.Errorf("expect %v but return %v", w.Window(i), windows[i])
+		}
+		// zscore normalized features are centered
+		for j, m := range means[i] {
+			if math.Abs(float64(m)) > 1e-5 {
+				t.Errorf("expect a mean of 0 for %s but return %v", features.Names[j], m)
+			}
+		}
+	}
+
+	if _, err := WindowsTensor(features.Windows{}); err == nil {
+		t.Errorf("expect an error without windows")
+	}
+}
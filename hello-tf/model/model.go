@@ -0,0 +1,257 @@
+// Package model runs the signatures of a SavedModel on batches of rows.
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ipoemi/hello-tf/feed"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+// DefaultSignature is the signature exported by default by TensorFlow.
+const DefaultSignature = "serving_default"
+
+// TensorSpec describes a tensor of a signature. Key is its key in the
+// signature and Name the name of the graph output, as op:index.
+type TensorSpec struct {
+	Key   string  `json:"key"`
+	Name  string  `json:"name"`
+	DType string  `json:"dtype"`
+	Shape []int64 `json:"shape"`
+}
+
+// SignatureSpec describes a signature, its tensors sorted by key.
+type SignatureSpec struct {
+	Name    string       `json:"name"`
+	Method  string       `json:"method"`
+	Inputs  []TensorSpec `json:"inputs"`
+	Outputs []TensorSpec `json:"outputs"`
+}
+
+// FeedInputs returns the inputs of the signature to read rows with.
+func (s SignatureSpec) FeedInputs() []feed.Input {
+	inputs := make([]feed.Input, len(s.Inputs))
+	for i, t := range s.Inputs {
+		inputs[i] = feed.Input{Name: t.Key, Shape: t.Shape}
+	}
+	return inputs
+}
+
+var dtypes = map[tf.DataType]string{
+	tf.Float:  "float32",
+	tf.Double: "float64",
+	tf.Int32:  "int32",
+	tf.Int64:  "int64",
+	tf.Uint8:  "uint8",
+	tf.Int16:  "int16",
+	tf.Int8:   "int8",
+	tf.String: "string",
+	tf.Bool:   "bool",
+	tf.Half:   "float16",
+}
+
+func dtypeName(dt tf.DataType) string {
+	if name, ok := dtypes[dt]; ok {
+		return name
+	}
+	return fmt.Sprintf("dtype(%d)", dt)
+}
+
+func tensorSpecs(infos map[string]tf.TensorInfo) []TensorSpec {
+	specs := make([]TensorSpec, 0, len(infos))
+	for key, info := range infos {
+		var shape []int64
+		if n := info.Shape.NumDimensions(); n >= 0 {
+			shape = make([]int64, n)
+			for i := range shape {
+				shape[i] = info.Shape.Size(i)
+			}
+		}
+		specs = append(specs, TensorSpec{Key: key, Name: info.Name, DType: dtypeName(info.DType), Shape: shape})
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Key < specs[j].Key })
+	return specs
+}
+
+// Model is a SavedModel loaded in a session.
+type Model struct {
+	saved *tf.SavedModel
+}
+
+// Load loads the SavedModel exported to dir, of the graph tagged tags.
+func Load(dir string, tags []string) (*Model, error) {
+	saved, err := tf.LoadSavedModel(dir, tags, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model %s with tags %s: %w", dir, strings.Join(tags, ","), err)
+	}
+	return &Model{saved: saved}, nil
+}
+
+// Close closes the session of the model.
+func (m *Model) Close() error {
+	return m.saved.Session.Close()
+}
+
+// Signatures returns the signatures of the model sorted by name.
+func (m *Model) Signatures() []SignatureSpec {
+	specs := make([]SignatureSpec, 0, len(m.saved.Signatures))
+	for name := range m.saved.Signatures {
+		spec, _ := m.Signature(name)
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Signature returns the signature called name.
+func (m *Model) Signature(name string) (SignatureSpec, error) {
+	sig, ok := m.saved.Signatures[name]
+	if !ok {
+		names := make([]string, 0, len(m.saved.Signatures))
+		for n := range m.saved.Signatures {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return SignatureSpec{}, fmt.Errorf("signature %s not found, the model has %s", name, strings.Join(names, ", "))
+	}
+	return SignatureSpec{
+		Name:    name,
+		Method:  sig.MethodName,
+		Inputs:  tensorSpecs(sig.Inputs),
+		Outputs: tensorSpecs(sig.Outputs),
+	}, nil
+}
+
+// output returns the graph output called name, as op:index.
+func (m *Model) output(name string) (tf.Output, error) {
+	op, index := name, 0
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		n, err := strconv.Atoi(name[i+1:])
+		if err != nil {
+			return tf.Output{}, fmt.Errorf("invalid tensor name %s", name)
+		}
+		op, index = name[:i], n
+	}
+	operation := m.saved.Graph.Operation(op)
+	if operation == nil {
+		return tf.Output{}, fmt.Errorf("operation %s not found in the graph", op)
+	}
+	return operation.Output(index), nil
+}
+
+// Run runs the signature called name on a batch, and returns the outputs
+// of each row by output key.
+func (m *Model) Run(name string, batch feed.Batch) ([]map[string]interface{}, error) {
+	sig, ok := m.saved.Signatures[name]
+	if !ok {
+		_, err := m.Signature(name)
+		return nil, err
+	}
+
+	feeds := make(map[tf.Output]*tf.Tensor, len(sig.Inputs))
+	for key, info := range sig.Inputs {
+		output, err := m.output(info.Name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := newTensor(info.DType, batch.Shape(key), batch.Values(key))
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %w", key, err)
+		}
+		feeds[output] = t
+	}
+	keys := make([]string, 0, len(sig.Outputs))
+	fetches := make([]tf.Output, 0, len(sig.Outputs))
+	for key, info := range sig.Outputs {
+		output, err := m.output(info.Name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		fetches = append(fetches, output)
+	}
+
+	results, err := m.saved.Session.Run(feeds, fetches, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run signature %s: %w", name, err)
+	}
+	rows := make([]map[string]interface{}, len(batch))
+	for i := range rows {
+		rows[i] = make(map[string]interface{}, len(keys))
+	}
+	for i, t := range results {
+		if err := split(keys[i], t, rows); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// newTensor returns a tensor of type dt and shape from values converted to
+// the type.
+func newTensor(dt tf.DataType, shape []int64, values []float64) (*tf.Tensor, error) {
+	var data interface{}
+	switch dt {
+	case tf.Float:
+		v := make([]float32, len(values))
+		for i := range values {
+			v[i] = float32(values[i])
+		}
+		data = v
+	case tf.Double:
+		data = values
+	case tf.Int32:
+		v := make([]int32, len(values))
+		for i := range values {
+			v[i] = int32(values[i])
+		}
+		data = v
+	case tf.Int64:
+		v := make([]int64, len(values))
+		for i := range values {
+			v[i] = int64(values[i])
+		}
+		data = v
+	default:
+		return nil, fmt.Errorf("unsupported dtype %s", dtypeName(dt))
+	}
+	return readTensor(dt, shape, data)
+}
+
+// readTensor returns a tensor of type dt and shape from data, a flat slice
+// of the Go type of dt. Tensors are read in the native byte order of the
+// host, little endian on the platforms TensorFlow builds for.
+func readTensor(dt tf.DataType, shape []int64, data interface{}) (*tf.Tensor, error) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+		return nil, err
+	}
+	return tf.ReadTensor(dt, shape, &buf)
+}
+
+// split splits the output key of a batch between its rows. Outputs without
+// a batch dimension are the same for all rows.
+func split(key string, t *tf.Tensor, rows []map[string]interface{}) error {
+	value := t.Value()
+	if len(t.Shape()) == 0 {
+		for _, row := range rows {
+			row[key] = value
+		}
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Len() != len(rows) {
+		return fmt.Errorf("output %s has %d rows for a batch of %d", key, v.Len(), len(rows))
+	}
+	for i, row := range rows {
+		row[key] = v.Index(i).Interface()
+	}
+	return nil
+}
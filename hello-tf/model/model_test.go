@@ -0,0 +1,108 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ipoemi/hello-tf/feed"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+	"github.com/tensorflow/tensorflow/tensorflow/go/op"
+)
+
+func tensorInfo(o tf.Output, dt tf.DataType, shape tf.Shape) tf.TensorInfo {
+	return tf.TensorInfo{Name: fmt.Sprintf("%s:%d", o.Op.Name(), o.Index), DType: dt, Shape: shape}
+}
+
+// newModel returns a model of a graph, as loaded from a SavedModel, whose
+// default signature returns its input x, its mean over each row and a scalar.
+func newModel(t *testing.T) *Model {
+	t.Helper()
+	s := op.NewScope()
+	x := op.Placeholder(s, tf.Float, op.PlaceholderShape(tf.MakeShape(-1, 2)))
+	same := op.Identity(s, x)
+	mean := op.Mean(s, x, op.Const(s, int32(1)))
+	scale := op.Const(s, float32(2))
+	graph, err := s.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := tf.NewSession(graph, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Model{saved: &tf.SavedModel{
+		Session: sess,
+		Graph:   graph,
+		Signatures: map[string]tf.Signature{DefaultSignature: {
+			MethodName: "tensorflow/serving/predict",
+			Inputs:     map[string]tf.TensorInfo{"x": tensorInfo(x, tf.Float, tf.MakeShape(-1, 2))},
+			Outputs: map[string]tf.TensorInfo{
+				"same":  tensorInfo(same, tf.Float, tf.MakeShape(-1, 2)),
+				"mean":  tensorInfo(mean, tf.Float, tf.MakeShape(-1)),
+				"scale": tensorInfo(scale, tf.Float, tf.ScalarShape()),
+			},
+		}},
+	}}
+}
+
+func TestRun(t *testing.T) {
+	m := newModel(t)
+	defer m.Close()
+	sig, err := m.Signature(DefaultSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := feed.NewJSONReader(strings.NewReader("[1, 3]\n[2, 4]\n[5, 5]\n"), sig.FeedInputs(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// batches of 2 rows, the last one shorter
+	expect := []map[string]interface{}{
+		{"same": []float32{1, 3}, "mean": float32(2), "scale": float32(2)},
+		{"same": []float32{2, 4}, "mean": float32(3), "scale": float32(2)},
+		{"same": []float32{5, 5}, "mean": float32(5), "scale": float32(2)},
+	}
+	outputs := make([]map[string]interface{}, 0)
+	for {
+		batch, err := feed.ReadBatch(r, 2)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := m.Run(DefaultSignature, batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != len(batch) {
+			t.Fatalf("expect %d rows but return %d", len(batch), len(rows))
+		}
+		outputs = append(outputs, rows...)
+	}
+	if !reflect.DeepEqual(outputs, expect) {
+		t.Errorf("expect %v but return %v", expect, outputs)
+	}
+
+	if _, err := m.Run("missing", feed.Batch{}); err == nil {
+		t.Errorf("expect an error for a missing signature")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := Load(dir, []string{"serve"}); err == nil {
+		t.Errorf("expect an error loading a directory without a SavedModel")
+	}
+}
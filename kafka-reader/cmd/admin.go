@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func newClient() *kafka.Client {
+	return &kafka.Client{
+		Addr:      kafka.TCP(brokers...),
+		Timeout:   10 * time.Second,
+		Transport: transport,
+	}
+}
+
+// topicPartitions returns the sorted partition ids of each topic, or of all
+// topics when none are given.
+func topicPartitions(ctx context.Context, client *kafka.Client, topics ...string) (map[string][]int, error) {
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	result := make(map[string][]int, len(meta.Topics))
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return nil, fmt.Errorf("topic %s: %w", t.Name, t.Error)
+		}
+		ids := make([]int, 0, len(t.Partitions))
+		for _, p := range t.Partitions {
+			ids = append(ids, p.ID)
+		}
+		sort.Ints(ids)
+		result[t.Name] = ids
+	}
+	return result, nil
+}
+
+// listOffsets resolves timestamp, which is kafka.FirstOffset, kafka.LastOffset
+// or a time in milliseconds, to an offset for each partition of topic.
+// Partitions with no message at or after a time resolve to the last offset.
+func listOffsets(ctx context.Context, client *kafka.Client, topic string, partitions []int, timestamp int64) (map[int]int64, error) {
+	requests := make([]kafka.OffsetRequest, 0, len(partitions))
+	for _, p := range partitions {
+		requests = append(requests, kafka.OffsetRequest{Partition: p, Timestamp: timestamp})
+	}
+	resp, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of %s: %w", topic, err)
+	}
+	result := make(map[int]int64, len(partitions))
+	var missing []int
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets of %s/%d: %w", topic, p.Partition, p.Error)
+		}
+		switch timestamp {
+		case kafka.FirstOffset:
+			result[p.Partition] = p.FirstOffset
+		case kafka.LastOffset:
+			result[p.Partition] = p.LastOffset
+		default:
+			result[p.Partition] = -1
+			for offset := range p.Offsets {
+				result[p.Partition] = offset
+			}
+			if result[p.Partition] < 0 {
+				missing = append(missing, p.Partition)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		last, err := listOffsets(ctx, client, topic, missing, kafka.LastOffset)
+		if err != nil {
+			return nil, err
+		}
+		for p, offset := range last {
+			result[p] = offset
+		}
+	}
+	return result, nil
+}
+
+// committedOffsets returns the offsets committed by group for each partition
+// of the given topics. Partitions without a commit are left out.
+func committedOffsets(ctx context.Context, client *kafka.Client, group string, topics map[string][]int) (map[string]map[int]int64, error) {
+	resp, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: group, Topics: topics})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", group, err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", group, resp.Error)
+	}
+	result := make(map[string]map[int]int64)
+	for t, partitions := range resp.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return nil, fmt.Errorf("failed to fetch offset of group %s at %s/%d: %w", group, t, p.Partition, p.Error)
+			}
+			if p.CommittedOffset < 0 {
+				continue
+			}
+			if result[t] == nil {
+				result[t] = make(map[int]int64)
+			}
+			result[t][p.Partition] = p.CommittedOffset
+		}
+	}
+	return result, nil
+}
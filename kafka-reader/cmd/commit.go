@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	atLeastOnce = "at-least-once"
+	atMostOnce  = "at-most-once"
+	noCommit    = "none"
+)
+
+// messageCommitter is the part of *kafka.Reader committing offsets.
+type messageCommitter interface {
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// committer commits the offsets of handled messages of a consumer group.
+//
+// With at-least-once a message is committed after it was handled, every
+// `every` messages, so a crash replays at most one batch. With at-most-once a
+// message is committed before it is handled, so a crash loses at most the
+// message being handled. With none nothing is committed.
+type committer struct {
+	r       messageCommitter
+	mode    string
+	every   int
+	pending []kafka.Message
+}
+
+func newCommitter(r messageCommitter, mode string, every int) (*committer, error) {
+	if mode != atLeastOnce && mode != atMostOnce && mode != noCommit {
+		return nil, fmt.Errorf("unknown commit mode %q", mode)
+	}
+	if every < 1 {
+		every = 1
+	}
+	return &committer{r: r, mode: mode, every: every}, nil
+}
+
+// before is called with a message about to be handled.
+func (c *committer) before(ctx context.Context, m kafka.Message) error {
+	if c.mode != atMostOnce {
+		return nil
+	}
+	if err := c.r.CommitMessages(ctx, m); err != nil {
+		return fmt.Errorf("failed to commit offset %d/%d: %w", m.Partition, m.Offset, err)
+	}
+	return nil
+}
+
+// after is called with a message once it was handled.
+func (c *committer) after(ctx context.Context, m kafka.Message) error {
+	if c.mode != atLeastOnce {
+		return nil
+	}
+	c.pending = append(c.pending, m)
+	if len(c.pending) < c.every {
+		return nil
+	}
+	return c.flush(ctx)
+}
+
+// flush commits the handled messages not committed yet.
+func (c *committer) flush(ctx context.Context) error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	if err := c.r.CommitMessages(ctx, c.pending...); err != nil {
+		return fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	c.pending = c.pending[:0]
+	return nil
+}
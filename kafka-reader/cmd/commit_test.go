@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeCommitter logs commits, the test logging the handling of messages in
+// between.
+type fakeCommitter struct {
+	log []string
+	err error
+}
+
+func (f *fakeCommitter) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	offsets := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		offsets = append(offsets, fmt.Sprint(m.Offset))
+	}
+	f.log = append(f.log, "commit "+strings.Join(offsets, ","))
+	return nil
+}
+
+// handle runs messages of offsets 0 to n-1 through c as runStreaming does,
+// then flushes.
+func handle(t *testing.T, f *fakeCommitter, c *committer, n int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		m := kafka.Message{Offset: int64(i)}
+		if err := c.before(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+		f.log = append(f.log, fmt.Sprint("handle ", i))
+		if err := c.after(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommitter(t *testing.T) {
+	tests := []struct {
+		mode   string
+		every  int
+		n      int
+		expect []string
+	}{
+		{atLeastOnce, 1, 2, []string{"handle 0", "commit 0", "handle 1", "commit 1"}},
+		{atMostOnce, 1, 2, []string{"commit 0", "handle 0", "commit 1", "handle 1"}},
+		// batches of 2, the last message committed by the final flush
+		{atLeastOnce, 2, 5, []string{"handle 0", "handle 1", "commit 0,1", "handle 2", "handle 3", "commit 2,3", "handle 4", "commit 4"}},
+		// every only applies to at-least-once
+		{atMostOnce, 3, 2, []string{"commit 0", "handle 0", "commit 1", "handle 1"}},
+		{noCommit, 1, 2, []string{"handle 0", "handle 1"}},
+		{atLeastOnce, 0, 1, []string{"handle 0", "commit 0"}},
+	}
+	for _, test := range tests {
+		f := &fakeCommitter{}
+		c, err := newCommitter(f, test.mode, test.every)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handle(t, f, c, test.n)
+		if !reflect.DeepEqual(f.log, test.expect) {
+			t.Errorf("expect %v but return %v with %s every %d", test.expect, f.log, test.mode, test.every)
+		}
+	}
+
+	if _, err := newCommitter(&fakeCommitter{}, "exactly-once", 1); err == nil {
+		t.Errorf("expect an error for an unknown mode")
+	}
+}
+
+func TestCommitterError(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeCommitter{err: errors.New("rebalance")}
+	c, _ := newCommitter(f, atLeastOnce, 2)
+	m := kafka.Message{Offset: 7}
+	if err := c.after(ctx, m); err != nil {
+		t.Errorf("expect no commit before a full batch but return %v", err)
+	}
+	if err := c.flush(ctx); !errors.Is(err, f.err) {
+		t.Errorf("expect %v but return %v", f.err, err)
+	}
+	// messages of a failed commit are committed by the next one
+	f.err = nil
+	if err := c.flush(ctx); err != nil || !reflect.DeepEqual(f.log, []string{"commit 7"}) {
+		t.Errorf("expect commit 7 but return %v, %v", f.log, err)
+	}
+
+	c, _ = newCommitter(&fakeCommitter{err: errors.New("rebalance")}, atMostOnce, 1)
+	if err := c.before(ctx, m); err == nil || !strings.Contains(err.Error(), "0/7") {
+		t.Errorf("expect a failed commit of 0/7 but return %v", err)
+	}
+}
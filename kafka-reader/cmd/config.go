@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"ipoemi/kafka-reader/config"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configPath  string
+	profileName string
+
+	// profile is the resolved cluster profile, after environment overrides.
+	profile config.Profile
+	// dialer and transport connect to the brokers with the SASL and TLS
+	// settings of the profile.
+	dialer    = kafka.DefaultDialer
+	transport = kafka.DefaultTransport
+)
+
+// initConfig loads the profile and uses it for every setting not given on
+// the command line.
+func initConfig() {
+	path, required := configPath, configPath != ""
+	if !required {
+		if v, ok := os.LookupEnv(config.EnvPrefix + "CONFIG"); ok {
+			path, required = v, true
+		} else {
+			path = config.DefaultPath()
+		}
+	}
+	name := profileName
+	if name == "" {
+		name = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+
+	f, err := config.Load(path, required)
+	cobra.CheckErr(err)
+	profile, err = f.Resolve(name)
+	cobra.CheckErr(err)
+	profile.ApplyEnv(os.LookupEnv)
+
+	if !rootCmd.PersistentFlags().Changed("brokers") {
+		brokers = profile.Brokers
+	}
+	if !rootCmd.Flags().Changed("format") && profile.Format != "" {
+		format = profile.Format
+	}
+	if !rootCmd.Flags().Changed("decoder") && profile.Decoder != "" {
+		decoderName = profile.Decoder
+	}
+	if !rootCmd.Flags().Changed("schema-registry") && profile.SchemaRegistry != "" {
+		decoderOptions.SchemaRegistryURL = profile.SchemaRegistry
+	}
+
+	dialer, err = profile.Dialer()
+	cobra.CheckErr(err)
+	transport, err = profile.Transport()
+	cobra.CheckErr(err)
+}
+
+func requireBrokers() error {
+	if len(brokers) == 0 {
+		return errors.New(`no brokers, pass --brokers or configure a profile, see --config and --profile`)
+	}
+	return nil
+}
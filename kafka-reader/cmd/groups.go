@@ -0,0 +1,439 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resetToEarliest bool
+	resetToLatest   bool
+	resetToOffset   int64
+	resetToDatetime string
+	resetShiftBy    int64
+	resetExecute    bool
+
+	groupsCmd = &cobra.Command{
+		Use:   "groups",
+		Short: "list and describe consumer groups and reset their offsets",
+	}
+
+	groupsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list consumer groups",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGroupsList(cmd.Context())
+		},
+	}
+
+	groupsDescribeCmd = &cobra.Command{
+		Use:   "describe GROUP",
+		Short: "describe members, committed offsets and lag of a consumer group",
+		Long: `Describe members, committed offsets and lag of a consumer group.
+
+Offsets are shown for --topic if given, otherwise for the topics assigned to
+the members of the group, or for every topic when the group has no members.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGroupsDescribe(cmd.Context(), args[0])
+		},
+	}
+
+	groupsResetOffsetsCmd = &cobra.Command{
+		Use:   "reset-offsets GROUP",
+		Short: "reset the committed offsets of an inactive consumer group on --topic",
+		Long: `Reset the committed offsets of a consumer group on --topic.
+
+Exactly one of --to-earliest, --to-latest, --to-offset, --to-datetime or
+--shift-by selects the new offsets. The plan is only printed unless
+--execute is given, and the group must have no active members.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireTopic(); err != nil {
+				return err
+			}
+			return runGroupsResetOffsets(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	addOutputFlag(groupsCmd)
+
+	groupsResetOffsetsCmd.Flags().BoolVar(&resetToEarliest, "to-earliest", false, "reset to the first offset of each partition")
+	groupsResetOffsetsCmd.Flags().BoolVar(&resetToLatest, "to-latest", false, "reset to the last offset of each partition")
+	groupsResetOffsetsCmd.Flags().Int64Var(&resetToOffset, "to-offset", -1, "reset every partition to this offset")
+	groupsResetOffsetsCmd.Flags().StringVar(&resetToDatetime, "to-datetime", "", "reset to the first offset at or after this RFC3339 time")
+	groupsResetOffsetsCmd.Flags().Int64Var(&resetShiftBy, "shift-by", 0, "move the committed offsets by this amount, may be negative")
+	groupsResetOffsetsCmd.Flags().BoolVar(&resetExecute, "execute", false, "commit the new offsets instead of only printing them")
+
+	groupsCmd.AddCommand(groupsListCmd, groupsDescribeCmd, groupsResetOffsetsCmd)
+	rootCmd.AddCommand(groupsCmd)
+}
+
+type groupSummary struct {
+	Group       string `json:"group"`
+	Coordinator int    `json:"coordinator"`
+	State       string `json:"state"`
+	Members     int    `json:"members"`
+}
+
+func runGroupsList(ctx context.Context) error {
+	client := newClient()
+	resp, err := client.ListGroups(ctx, &kafka.ListGroupsRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("failed to list groups: %w", resp.Error)
+	}
+	groups := make([]groupSummary, 0, len(resp.Groups))
+	ids := make([]string, 0, len(resp.Groups))
+	for _, g := range resp.Groups {
+		groups = append(groups, groupSummary{Group: g.GroupID, Coordinator: g.Coordinator})
+		ids = append(ids, g.GroupID)
+	}
+	if len(ids) > 0 {
+		desc, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: ids})
+		if err != nil {
+			return fmt.Errorf("failed to describe groups: %w", err)
+		}
+		described := make(map[string]kafka.DescribeGroupsResponseGroup, len(desc.Groups))
+		for _, g := range desc.Groups {
+			described[g.GroupID] = g
+		}
+		for i := range groups {
+			g := described[groups[i].Group]
+			groups[i].State = g.GroupState
+			groups[i].Members = len(g.Members)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+
+	rows := make([][]string, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, []string{g.Group, strconv.Itoa(g.Coordinator), g.State, strconv.Itoa(g.Members)})
+	}
+	return render(groups, []string{"GROUP", "COORDINATOR", "STATE", "MEMBERS"}, rows)
+}
+
+type groupMember struct {
+	MemberID   string           `json:"member_id"`
+	ClientID   string           `json:"client_id"`
+	ClientHost string           `json:"client_host"`
+	Partitions map[string][]int `json:"partitions"`
+	owns       map[string]map[int]bool
+}
+
+type partitionLag struct {
+	Topic           string `json:"topic"`
+	Partition       int    `json:"partition"`
+	CommittedOffset int64  `json:"committed_offset"`
+	LastOffset      int64  `json:"last_offset"`
+	Lag             int64  `json:"lag"`
+	MemberID        string `json:"member_id,omitempty"`
+}
+
+type groupDescription struct {
+	Group      string         `json:"group"`
+	State      string         `json:"state"`
+	Members    []groupMember  `json:"members"`
+	Partitions []partitionLag `json:"partitions"`
+	TotalLag   int64          `json:"total_lag"`
+}
+
+func describeGroup(ctx context.Context, client *kafka.Client, group string) (kafka.DescribeGroupsResponseGroup, error) {
+	resp, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		return kafka.DescribeGroupsResponseGroup{}, fmt.Errorf("failed to describe group %s: %w", group, err)
+	}
+	if len(resp.Groups) == 0 {
+		return kafka.DescribeGroupsResponseGroup{}, fmt.Errorf("group %s not found", group)
+	}
+	g := resp.Groups[0]
+	if g.Error != nil {
+		return g, fmt.Errorf("failed to describe group %s: %w", group, g.Error)
+	}
+	return g, nil
+}
+
+// groupLag returns the committed offset, last offset and lag of every
+// partition of topics that group has committed to.
+func groupLag(ctx context.Context, client *kafka.Client, group string, topics map[string][]int) ([]partitionLag, error) {
+	committed, err := committedOffsets(ctx, client, group, topics)
+	if err != nil {
+		return nil, err
+	}
+	lags := make([]partitionLag, 0)
+	for t, offsets := range committed {
+		partitions := make([]int, 0, len(offsets))
+		for p := range offsets {
+			partitions = append(partitions, p)
+		}
+		last, err := listOffsets(ctx, client, t, partitions, kafka.LastOffset)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			l := partitionLag{Topic: t, Partition: p, CommittedOffset: offsets[p], LastOffset: last[p]}
+			if l.LastOffset > l.CommittedOffset {
+				l.Lag = l.LastOffset - l.CommittedOffset
+			}
+			lags = append(lags, l)
+		}
+	}
+	sort.Slice(lags, func(i, j int) bool {
+		if lags[i].Topic != lags[j].Topic {
+			return lags[i].Topic < lags[j].Topic
+		}
+		return lags[i].Partition < lags[j].Partition
+	})
+	return lags, nil
+}
+
+func runGroupsDescribe(ctx context.Context, group string) error {
+	client := newClient()
+	g, err := describeGroup(ctx, client, group)
+	if err != nil {
+		return err
+	}
+
+	desc := groupDescription{Group: g.GroupID, State: g.GroupState, Members: make([]groupMember, 0, len(g.Members))}
+	assigned := make(map[string]bool)
+	for _, m := range g.Members {
+		member := groupMember{
+			MemberID:   m.MemberID,
+			ClientID:   m.ClientID,
+			ClientHost: m.ClientHost,
+			Partitions: make(map[string][]int),
+			owns:       make(map[string]map[int]bool),
+		}
+		for _, t := range m.MemberAssignments.Topics {
+			member.Partitions[t.Topic] = t.Partitions
+			member.owns[t.Topic] = make(map[int]bool)
+			for _, p := range t.Partitions {
+				member.owns[t.Topic][p] = true
+			}
+			assigned[t.Topic] = true
+		}
+		desc.Members = append(desc.Members, member)
+	}
+
+	var topics []string
+	switch {
+	case topic != "":
+		topics = []string{topic}
+	default:
+		for t := range assigned {
+			topics = append(topics, t)
+		}
+	}
+	partitions, err := topicPartitions(ctx, client, topics...)
+	if err != nil {
+		return err
+	}
+	if desc.Partitions, err = groupLag(ctx, client, group, partitions); err != nil {
+		return err
+	}
+	for i, l := range desc.Partitions {
+		desc.TotalLag += l.Lag
+		for _, m := range desc.Members {
+			if m.owns[l.Topic][l.Partition] {
+				desc.Partitions[i].MemberID = m.MemberID
+			}
+		}
+	}
+
+	if output == "json" {
+		return render(desc, nil, nil)
+	}
+	fmt.Printf("group: %s, state: %s, members: %d, total lag: %d\n\n", desc.Group, desc.State, len(desc.Members), desc.TotalLag)
+	rows := make([][]string, 0, len(desc.Partitions))
+	for _, l := range desc.Partitions {
+		rows = append(rows, []string{
+			l.Topic,
+			strconv.Itoa(l.Partition),
+			strconv.FormatInt(l.CommittedOffset, 10),
+			strconv.FormatInt(l.LastOffset, 10),
+			strconv.FormatInt(l.Lag, 10),
+			l.MemberID,
+		})
+	}
+	return render(desc, []string{"TOPIC", "PARTITION", "COMMITTED", "LAST-OFFSET", "LAG", "MEMBER"}, rows)
+}
+
+type offsetReset struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Current   int64  `json:"current"`
+	New       int64  `json:"new"`
+}
+
+// checkResetFlags checks that exactly one of the reset flags is given.
+func checkResetFlags() error {
+	modes := 0
+	for _, set := range []bool{resetToEarliest, resetToLatest, resetToOffset >= 0, resetToDatetime != "", resetShiftBy != 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return errors.New("exactly one of --to-earliest, --to-latest, --to-offset, --to-datetime or --shift-by is required")
+	}
+	return nil
+}
+
+// targetOffsets computes the new offset of each partition according to the
+// reset flags, clamped to the offsets available in the partition.
+func targetOffsets(ctx context.Context, client *kafka.Client, group string, partitions []int) (map[int]int64, map[int]int64, error) {
+	if err := checkResetFlags(); err != nil {
+		return nil, nil, err
+	}
+
+	committed, err := committedOffsets(ctx, client, group, map[string][]int{topic: partitions})
+	if err != nil {
+		return nil, nil, err
+	}
+	current := committed[topic]
+	if current == nil {
+		current = map[int]int64{}
+	}
+	first, err := listOffsets(ctx, client, topic, partitions, kafka.FirstOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := listOffsets(ctx, client, topic, partitions, kafka.LastOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var at map[int]int64
+	if resetToDatetime != "" {
+		t, err := time.Parse(time.RFC3339, resetToDatetime)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --to-datetime: %w", err)
+		}
+		if at, err = listOffsets(ctx, client, topic, partitions, t.UnixNano()/int64(time.Millisecond)); err != nil {
+			return nil, nil, err
+		}
+	}
+	target, err := resetTargets(group, partitions, current, first, last, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, target, nil
+}
+
+// resetTargets selects the new offset of each partition from the current,
+// first and last offsets, and from at, those at --to-datetime, according to
+// the reset flags. Offsets out of [first, last] are clamped to it.
+func resetTargets(group string, partitions []int, current, first, last, at map[int]int64) (map[int]int64, error) {
+	target := make(map[int]int64, len(partitions))
+	for _, p := range partitions {
+		switch {
+		case resetToEarliest:
+			target[p] = first[p]
+		case resetToLatest:
+			target[p] = last[p]
+		case resetToDatetime != "":
+			target[p] = at[p]
+		case resetToOffset >= 0:
+			target[p] = resetToOffset
+		default:
+			from, ok := current[p]
+			if !ok {
+				return nil, fmt.Errorf("group %s has no committed offset on %s/%d to shift", group, topic, p)
+			}
+			target[p] = from + resetShiftBy
+		}
+		if target[p] < first[p] {
+			target[p] = first[p]
+		}
+		if target[p] > last[p] {
+			target[p] = last[p]
+		}
+	}
+	return target, nil
+}
+
+func runGroupsResetOffsets(ctx context.Context, group string) error {
+	client := newClient()
+	g, err := describeGroup(ctx, client, group)
+	if err != nil {
+		return err
+	}
+	if len(g.Members) > 0 {
+		return fmt.Errorf("group %s has %d active members, stop them before resetting offsets", group, len(g.Members))
+	}
+
+	all, err := topicPartitions(ctx, client, topic)
+	if err != nil {
+		return err
+	}
+	partitions := all[topic]
+	current, target, err := targetOffsets(ctx, client, group, partitions)
+	if err != nil {
+		return err
+	}
+
+	resets := make([]offsetReset, 0, len(partitions))
+	rows := make([][]string, 0, len(partitions))
+	for _, p := range partitions {
+		r := offsetReset{Topic: topic, Partition: p, Current: -1, New: target[p]}
+		if offset, ok := current[p]; ok {
+			r.Current = offset
+		}
+		resets = append(resets, r)
+		rows = append(rows, []string{r.Topic, strconv.Itoa(r.Partition), strconv.FormatInt(r.Current, 10), strconv.FormatInt(r.New, 10)})
+	}
+	if err := render(resets, []string{"TOPIC", "PARTITION", "CURRENT", "NEW"}, rows); err != nil {
+		return err
+	}
+	if !resetExecute {
+		if output != "json" {
+			fmt.Println("\ndry run, pass --execute to commit the new offsets")
+		}
+		return nil
+	}
+	return commitGroupOffsets(ctx, group, map[string]map[int]int64{topic: target})
+}
+
+// commitGroupOffsets joins group as its only member and commits offsets.
+func commitGroupOffsets(ctx context.Context, group string, offsets map[string]map[int]int64) error {
+	topics := make([]string, 0, len(offsets))
+	for t := range offsets {
+		topics = append(topics, t)
+	}
+	cg, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:      group,
+		Brokers: brokers,
+		Topics:  topics,
+		Dialer:  dialer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to join group %s: %w", group, err)
+	}
+	defer cg.Close()
+
+	gen, err := cg.Next(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to join group %s: %w", group, err)
+	}
+	for t, partitions := range offsets {
+		if len(gen.Assignments[t]) != len(partitions) {
+			return fmt.Errorf("group %s was joined by another member, try again", group)
+		}
+	}
+	if err := gen.CommitOffsets(offsets); err != nil {
+		return fmt.Errorf("failed to commit offsets of group %s: %w", group, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+// setResetFlags sets the reset flags for a test and restores them after it.
+func setResetFlags(t *testing.T, earliest, latest bool, offset int64, datetime string, shift int64) {
+	t.Helper()
+	e, l, o, d, s := resetToEarliest, resetToLatest, resetToOffset, resetToDatetime, resetShiftBy
+	t.Cleanup(func() {
+		resetToEarliest, resetToLatest, resetToOffset, resetToDatetime, resetShiftBy = e, l, o, d, s
+	})
+	resetToEarliest, resetToLatest, resetToOffset, resetToDatetime, resetShiftBy = earliest, latest, offset, datetime, shift
+}
+
+func TestCheckResetFlags(t *testing.T) {
+	tests := []struct {
+		earliest, latest bool
+		offset           int64
+		datetime         string
+		shift            int64
+		ok               bool
+	}{
+		{true, false, -1, "", 0, true},
+		{false, true, -1, "", 0, true},
+		{false, false, 0, "", 0, true},
+		{false, false, -1, "2021-01-01T00:00:00Z", 0, true},
+		{false, false, -1, "", -5, true},
+		{false, false, -1, "", 0, false},
+		{true, true, -1, "", 0, false},
+		{false, false, 10, "", 5, false},
+	}
+	for _, test := range tests {
+		setResetFlags(t, test.earliest, test.latest, test.offset, test.datetime, test.shift)
+		if err := checkResetFlags(); (err == nil) != test.ok {
+			t.Errorf("expect ok %v for %+v but return %v", test.ok, test, err)
+		}
+	}
+}
+
+func TestResetTargets(t *testing.T) {
+	partitions := []int{0, 1, 2}
+	first := map[int]int64{0: 10, 1: 0, 2: 5}
+	last := map[int]int64{0: 100, 1: 50, 2: 5}
+	current := map[int]int64{0: 40, 1: 48, 2: 5}
+	at := map[int]int64{0: 30, 1: 50, 2: 5}
+
+	tests := []struct {
+		name     string
+		earliest bool
+		latest   bool
+		offset   int64
+		datetime string
+		shift    int64
+		expect   map[int]int64
+	}{
+		{"earliest", true, false, -1, "", 0, map[int]int64{0: 10, 1: 0, 2: 5}},
+		{"latest", false, true, -1, "", 0, map[int]int64{0: 100, 1: 50, 2: 5}},
+		{"datetime", false, false, -1, "2021-01-01T00:00:00Z", 0, map[int]int64{0: 30, 1: 50, 2: 5}},
+		{"offset within range", false, false, 20, "", 0, map[int]int64{0: 20, 1: 20, 2: 5}},
+		{"offset below first", false, false, 0, "", 0, map[int]int64{0: 10, 1: 0, 2: 5}},
+		{"offset past last", false, false, 70, "", 0, map[int]int64{0: 70, 1: 50, 2: 5}},
+		{"shift forward", false, false, -1, "", 5, map[int]int64{0: 45, 1: 50, 2: 5}},
+		{"shift backward", false, false, -1, "", -35, map[int]int64{0: 10, 1: 13, 2: 5}},
+	}
+	for _, test := range tests {
+		setResetFlags(t, test.earliest, test.latest, test.offset, test.datetime, test.shift)
+		target, err := resetTargets("g", partitions, current, first, last, at)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(target, test.expect) {
+			t.Errorf("%s: expect %v but return %v", test.name, test.expect, target)
+		}
+	}
+
+	// shifting needs a committed offset on every partition
+	setResetFlags(t, false, false, -1, "", 1)
+	if _, err := resetTargets("g", partitions, map[int]int64{0: 40}, first, last, at); err == nil {
+		t.Errorf("expect an error shifting partitions without a committed offset")
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// output is the --output flag shared by the admin commands.
+var output string
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "output format: table or json")
+}
+
+// render prints v as indented JSON with --output json, and rows as an
+// aligned table under header otherwise.
+func render(v interface{}, header []string, rows [][]string) error {
+	return renderTo(os.Stdout, output, v, header, rows)
+}
+
+// renderTo is render writing to w in the given output format.
+func renderTo(w io.Writer, format string, v interface{}, header []string, rows [][]string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output %q", format)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
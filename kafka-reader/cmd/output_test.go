@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	type row struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	v := []row{{"a", 1}, {"long-name", 10}}
+	header := []string{"NAME", "COUNT"}
+	rows := [][]string{{"a", "1"}, {"long-name", "10"}}
+	tests := []struct {
+		format string
+		expect string
+	}{
+		{"table", "NAME       COUNT\na          1\nlong-name  10\n"},
+		{"json", "[\n  {\n    \"name\": \"a\",\n    \"count\": 1\n  },\n  {\n    \"name\": \"long-name\",\n    \"count\": 10\n  }\n]\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := renderTo(&buf, test.format, v, header, rows); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expect {
+			t.Errorf("expect %q but return %q", test.expect, buf.String())
+		}
+	}
+
+	if err := renderTo(&bytes.Buffer{}, "yaml", v, header, rows); err == nil {
+		t.Errorf("expect an error for an unknown output")
+	}
+}
+
+func TestJoinInts(t *testing.T) {
+	tests := []struct {
+		v      []int
+		expect string
+	}{
+		{nil, ""},
+		{[]int{1}, "1"},
+		{[]int{1, 2, 3}, "1,2,3"},
+	}
+	for _, test := range tests {
+		if s := joinInts(test.v); s != test.expect {
+			t.Errorf("expect %q but return %q", test.expect, s)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	produceFormat       string
+	produceKeySeparator string
+	produceCompression  string
+	produceAcks         string
+	produceBatchSize    int
+	produceBatchTimeout time.Duration
+
+	produceCmd = &cobra.Command{
+		Use:   "produce",
+		Short: "write messages read from stdin to the topic",
+		Long: `Write messages read from stdin to the topic, one message per line.
+
+With --format line each line is the message value, optionally prefixed by a
+key and --key-separator. With --format json each line is an envelope:
+
+  {"key": "KRW-BTC", "value": {...}, "headers": [{"key": "source", "value": "upbit"}], "partition": 0}
+
+where value is either a JSON string, used as is, or any other JSON value,
+written in its JSON encoding. With "value_encoding": "json" value is written
+in its JSON encoding even when it is a string, and with "value_encoding":
+"base64" value is a base64 string of the bytes written. Envelopes printed by
+--format json set value_encoding so that they are produced back unchanged.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireTopic(); err != nil {
+				return err
+			}
+			return runProduce(cmd.Context(), topic, brokers)
+		},
+	}
+)
+
+func init() {
+	produceCmd.Flags().StringVarP(&produceFormat, "format", "f", "line", "input format: line or json")
+	produceCmd.Flags().StringVar(&produceKeySeparator, "key-separator", "", "in line format, split each line into key and value at the first occurrence of this separator")
+	produceCmd.Flags().StringVar(&produceCompression, "compression", "none", "compression codec: none, gzip, snappy, lz4 or zstd")
+	produceCmd.Flags().StringVar(&produceAcks, "acks", "all", "required acks: none, one or all")
+	produceCmd.Flags().IntVar(&produceBatchSize, "batch-size", 100, "maximum number of messages per batch")
+	produceCmd.Flags().DurationVar(&produceBatchTimeout, "batch-timeout", 100*time.Millisecond, "maximum time to wait before sending an incomplete batch")
+
+	rootCmd.AddCommand(produceCmd)
+}
+
+// Encodings of the value of an envelope, besides the default of a JSON
+// string used as is or another JSON value written in its JSON encoding.
+const (
+	// jsonEncoding writes the value in its JSON encoding, strings included.
+	jsonEncoding = "json"
+	// base64Encoding writes the bytes of a base64 string.
+	base64Encoding = "base64"
+)
+
+// envelope is the JSON representation of a message used by --format json,
+// both when printing and when producing.
+type envelope struct {
+	Topic         string          `json:"topic,omitempty"`
+	Partition     *int            `json:"partition,omitempty"`
+	Offset        *int64          `json:"offset,omitempty"`
+	Time          *time.Time      `json:"time,omitempty"`
+	Key           *string         `json:"key,omitempty"`
+	Value         json.RawMessage `json:"value"`
+	ValueEncoding string          `json:"value_encoding,omitempty"`
+	Headers       headers         `json:"headers,omitempty"`
+}
+
+type header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// headers keep the order and the duplicates of the headers of a message.
+type headers []header
+
+// UnmarshalJSON also reads headers written as an object, by earlier
+// versions, in key order.
+func (h *headers) UnmarshalJSON(b []byte) error {
+	if b := bytes.TrimSpace(b); len(b) == 0 || b[0] != '{' {
+		return json.Unmarshal(b, (*[]header)(h))
+	}
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	*h = make(headers, 0, len(keys))
+	for _, k := range keys {
+		*h = append(*h, header{Key: k, Value: m[k]})
+	}
+	return nil
+}
+
+// newEnvelope converts a consumed message. Values that are compact JSON are
+// embedded as is, other UTF-8 text becomes a JSON string and anything else
+// a base64 string.
+func newEnvelope(m kafka.Message) envelope {
+	e := envelope{
+		Topic:     m.Topic,
+		Partition: &m.Partition,
+		Offset:    &m.Offset,
+	}
+	e.Value, e.ValueEncoding = jsonValue(m.Value)
+	if !m.Time.IsZero() {
+		e.Time = &m.Time
+	}
+	if m.Key != nil {
+		key := string(m.Key)
+		e.Key = &key
+	}
+	for _, h := range m.Headers {
+		e.Headers = append(e.Headers, header{Key: h.Key, Value: string(h.Value)})
+	}
+	return e
+}
+
+// jsonValue returns v as the value of an envelope and its encoding.
+func jsonValue(v []byte) (json.RawMessage, string) {
+	switch {
+	case v == nil:
+		return json.RawMessage("null"), ""
+	case json.Valid(v) && embeds(v):
+		return v, jsonEncoding
+	case utf8.Valid(v):
+		s, _ := json.Marshal(string(v))
+		return s, ""
+	}
+	s, _ := json.Marshal(base64.StdEncoding.EncodeToString(v))
+	return s, base64Encoding
+}
+
+// embeds tells whether valid JSON v is printed as is, which encoding/json
+// does not do for JSON with spaces or HTML characters.
+func embeds(v []byte) bool {
+	b, err := json.Marshal(json.RawMessage(v))
+	return err == nil && bytes.Equal(b, v)
+}
+
+func (e envelope) message() (kafka.Message, error) {
+	m := kafka.Message{Partition: -1}
+	if e.Partition != nil {
+		if *e.Partition < 0 {
+			return m, fmt.Errorf("invalid partition %d", *e.Partition)
+		}
+		m.Partition = *e.Partition
+	}
+	if e.Key != nil {
+		m.Key = []byte(*e.Key)
+	}
+	v := bytes.TrimSpace(e.Value)
+	switch e.ValueEncoding {
+	case "":
+		if len(v) > 0 && v[0] == '"' {
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				return m, err
+			}
+			m.Value = []byte(s)
+		} else if len(v) > 0 && !bytes.Equal(v, []byte("null")) {
+			m.Value = append([]byte(nil), v...)
+		}
+	case jsonEncoding:
+		m.Value = append([]byte{}, v...)
+	case base64Encoding:
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return m, fmt.Errorf("invalid base64 value: %w", err)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return m, fmt.Errorf("invalid base64 value: %w", err)
+		}
+		m.Value = b
+	default:
+		return m, fmt.Errorf("unknown value encoding %q", e.ValueEncoding)
+	}
+	for _, h := range e.Headers {
+		m.Headers = append(m.Headers, kafka.Header{Key: h.Key, Value: []byte(h.Value)})
+	}
+	return m, nil
+}
+
+func parseLine(line string) (kafka.Message, error) {
+	switch produceFormat {
+	case "line":
+		m := kafka.Message{Partition: -1}
+		if produceKeySeparator != "" {
+			if i := strings.Index(line, produceKeySeparator); i >= 0 {
+				m.Key = []byte(line[:i])
+				line = line[i+len(produceKeySeparator):]
+			}
+		}
+		m.Value = []byte(line)
+		return m, nil
+	case "json":
+		var e envelope
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return kafka.Message{}, err
+		}
+		return e.message()
+	}
+	return kafka.Message{}, fmt.Errorf("unknown format %q", produceFormat)
+}
+
+func parseCompression(name string) (kafka.Compression, error) {
+	switch name {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	}
+	return 0, fmt.Errorf("unknown compression codec %q", name)
+}
+
+func parseAcks(name string) (kafka.RequiredAcks, error) {
+	switch name {
+	case "none", "0":
+		return kafka.RequireNone, nil
+	case "one", "1":
+		return kafka.RequireOne, nil
+	case "all", "-1":
+		return kafka.RequireAll, nil
+	}
+	return 0, fmt.Errorf("unknown acks %q", name)
+}
+
+// partitionBalancer sends messages to their requested partition, and falls
+// back to hashing the key for messages with a negative partition.
+type partitionBalancer struct {
+	fallback kafka.Hash
+}
+
+func (b *partitionBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	if msg.Partition >= 0 {
+		return msg.Partition
+	}
+	return b.fallback.Balance(msg, partitions...)
+}
+
+func runProduce(ctx context.Context, topic string, brokers []string) error {
+	if produceFormat != "line" && produceFormat != "json" {
+		return fmt.Errorf("unknown format %q", produceFormat)
+	}
+	compression, err := parseCompression(produceCompression)
+	if err != nil {
+		return err
+	}
+	acks, err := parseAcks(produceAcks)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mutex     sync.Mutex
+		delivered int
+		failed    int
+	)
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Transport:    transport,
+		Balancer:     &partitionBalancer{},
+		BatchSize:    produceBatchSize,
+		BatchTimeout: produceBatchTimeout,
+		RequiredAcks: acks,
+		Compression:  compression,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				failed += len(messages)
+				log.Printf("failed to deliver %d messages to partition %d: %v\n", len(messages), messages[0].Partition, err)
+				return
+			}
+			delivered += len(messages)
+		},
+	}
+
+	lines := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 10e6) // 10MB
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				scanErr <- nil
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	lineNo := 0
+	var readErr error
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("interrupted, flushing\n")
+			break loop
+		case line, ok := <-lines:
+			if !ok {
+				readErr = <-scanErr
+				break loop
+			}
+			lineNo++
+			if line == "" {
+				continue
+			}
+			m, err := parseLine(line)
+			if err != nil {
+				readErr = fmt.Errorf("line %d: %w", lineNo, err)
+				break loop
+			}
+			if err := w.WriteMessages(context.Background(), m); err != nil {
+				readErr = fmt.Errorf("line %d: %w", lineNo, err)
+				break loop
+			}
+		}
+	}
+
+	if err := w.Close(); err != nil && readErr == nil {
+		readErr = fmt.Errorf("failed to close writer: %w", err)
+	}
+	log.Printf("delivered: %d, failed: %d\n", delivered, failed)
+	if readErr != nil {
+		return readErr
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d messages were not delivered", failed)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// setProduceFlags sets the produce flags for a test and restores them after
+// it.
+func setProduceFlags(t *testing.T, format, keySeparator string) {
+	t.Helper()
+	f, s := produceFormat, produceKeySeparator
+	t.Cleanup(func() {
+		produceFormat, produceKeySeparator = f, s
+	})
+	produceFormat, produceKeySeparator = format, keySeparator
+}
+
+func TestParseLine(t *testing.T) {
+	setProduceFlags(t, "line", "\t")
+	m, err := parseLine("KRW-BTC\t{\"trade_price\":1}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Key) != "KRW-BTC" || string(m.Value) != `{"trade_price":1}` || m.Partition != -1 {
+		t.Errorf("unexpected message %+v", m)
+	}
+
+	setProduceFlags(t, "json", "")
+	m, err = parseLine(`{"key":"KRW-BTC","value":{"trade_price":1},"headers":{"source":"upbit"},"partition":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Key) != "KRW-BTC" || string(m.Value) != `{"trade_price":1}` || m.Partition != 2 {
+		t.Errorf("unexpected message %+v", m)
+	}
+	if len(m.Headers) != 1 || m.Headers[0].Key != "source" || string(m.Headers[0].Value) != "upbit" {
+		t.Errorf("unexpected headers %+v", m.Headers)
+	}
+
+	m, err = parseLine(`{"value":"plain text"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Key != nil || string(m.Value) != "plain text" || m.Partition != -1 {
+		t.Errorf("unexpected message %+v", m)
+	}
+
+	if _, err = parseLine(`{"value":"x","partition":-3}`); err == nil {
+		t.Errorf("expect error for negative partition")
+	}
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	setProduceFlags(t, "json", "")
+	headers := []kafka.Header{{Key: "b", Value: []byte("1")}, {Key: "a", Value: []byte("2")}, {Key: "b", Value: []byte("3")}}
+	for _, value := range []string{`{"trade_price":1}`, "plain text", `"abc"`, `  "abc"  `, `{"a": "<b>"}`, "12", "null", "", "\xff\x00"} {
+		m := kafka.Message{Topic: "t", Partition: 1, Offset: 10, Key: []byte("k"), Value: []byte(value), Headers: headers}
+		b, err := json.Marshal(newEnvelope(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m2, err := parseLine(string(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(m2.Key) != "k" || string(m2.Value) != value || m2.Partition != 1 {
+			t.Errorf("unexpected message %+v from %s", m2, b)
+		}
+		if !reflect.DeepEqual(m2.Headers, headers) {
+			t.Errorf("expect headers %v but return %v", headers, m2.Headers)
+		}
+	}
+
+	m, err := parseLine(`{"value":"AP8=","value_encoding":"base64","headers":[{"key":"x","value":"1"},{"key":"x","value":"2"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "\x00\xff" || len(m.Headers) != 2 || string(m.Headers[1].Value) != "2" {
+		t.Errorf("unexpected message %+v", m)
+	}
+	if _, err := parseLine(`{"value":"x","value_encoding":"hex"}`); err == nil {
+		t.Errorf("expect error for an unknown value encoding")
+	}
+}
@@ -2,10 +2,19 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"ipoemi/kafka-reader/decoder"
+	"ipoemi/kafka-reader/filter"
+
 	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
 	"github.com/spf13/cobra"
@@ -15,52 +24,272 @@ var (
 	topic   string
 	brokers []string
 
+	maxMessages int64
+	idleTimeout time.Duration
+	untilOffset int64
+	format      string
+
+	consumerGroup string
+	fromBeginning bool
+	commitMode    string
+	commitEvery   int
+
+	filterKey      string
+	filterKeyRegex string
+	filterGrep     string
+	filterHeaders  []string
+	filterExpr     string
+
+	decoderName    string
+	decoderOptions decoder.Options
+
 	rootCmd = &cobra.Command{
-		Use:   "kafka-reader",
-		Short: "kafka-reader",
-		Run: func(cmd *cobra.Command, args []string) {
-			runStreaming(topic, brokers)
+		Use:          "kafka-reader",
+		Short:        "kafka-reader",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return requireBrokers()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireTopic(); err != nil {
+				return err
+			}
+			if format != "value" && format != "json" {
+				return fmt.Errorf("unknown format %q", format)
+			}
+			keep, err := buildFilter()
+			if err != nil {
+				return err
+			}
+			dec, err := decoder.New(decoderName, decoderOptions)
+			if err != nil {
+				return err
+			}
+			return runStreaming(cmd.Context(), topic, brokers, keep, dec)
 		},
 	}
 )
 
-// Execute executes the root command.
+// Execute executes the root command. Its context is cancelled on SIGINT or
+// SIGTERM so that commands can stop gracefully.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
-	cobra.OnInitialize()
+	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVarP(&topic, "topic", "t", "", "topic")
-	rootCmd.PersistentFlags().StringSliceVarP(&brokers, "brokers", "b", []string{}, "brokers")
-	rootCmd.MarkPersistentFlagRequired("topic")
-	rootCmd.MarkPersistentFlagRequired("brokers")
+	rootCmd.PersistentFlags().StringSliceVarP(&brokers, "brokers", "b", []string{}, "brokers, defaults to the brokers of the profile")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file (default is $HOME/.kafka-reader.yaml, or $KAFKA_READER_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "cluster profile of the config file (default is the profile named in the file, or $KAFKA_READER_PROFILE)")
+
+	rootCmd.Flags().Int64VarP(&maxMessages, "max-messages", "n", 0, "exit after printing this many messages (0 means unlimited)")
+	rootCmd.Flags().DurationVar(&idleTimeout, "idle-timeout", 0, "exit when no message arrives for this long, e.g. 30s (0 means wait forever)")
+	rootCmd.Flags().Int64Var(&untilOffset, "until-offset", -1, "exit once every partition has reached this offset (-1 means no limit)")
+
+	rootCmd.Flags().StringVarP(&consumerGroup, "group", "g", "", "consumer group to join, resuming from its committed offsets (default is a new random group, whose offsets are not committed)")
+	rootCmd.Flags().BoolVar(&fromBeginning, "from-beginning", false, "start from the first offset when the group has no committed offset")
+	rootCmd.Flags().StringVar(&commitMode, "commit-mode", atLeastOnce, "commit offsets after handling messages ("+atLeastOnce+"), before ("+atMostOnce+") or never ("+noCommit+"), never without --group")
+	rootCmd.Flags().IntVar(&commitEvery, "commit-every", 1, "with "+atLeastOnce+", commit once this many messages were handled")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "value", "output format: value prints the raw value, json prints an envelope readable by produce --format json")
+
+	rootCmd.Flags().StringVar(&filterKey, "key", "", "only print messages with this key")
+	rootCmd.Flags().StringVar(&filterKeyRegex, "key-regex", "", "only print messages whose key matches this regular expression")
+	rootCmd.Flags().StringVar(&filterGrep, "grep", "", "only print messages whose value matches this regular expression")
+	rootCmd.Flags().StringArrayVar(&filterHeaders, "header", []string{}, "only print messages with this header, as k=v or k (repeatable)")
+	rootCmd.Flags().StringVar(&filterExpr, "filter", "", `only print JSON messages matching this jq-like expression, e.g. '.market == "KRW-BTC"'`)
+
+	rootCmd.Flags().StringVar(&decoderName, "decoder", "none", "decode values to JSON before filtering and printing: "+strings.Join(decoder.Names, ", "))
+	rootCmd.Flags().StringVar(&decoderOptions.AvroSchemaFile, "avro-schema", "", "avro schema file used for every value, otherwise values are read in the Confluent wire format")
+	rootCmd.Flags().StringVar(&decoderOptions.SchemaRegistryURL, "schema-registry", "", "schema registry url resolving Confluent wire format schema ids")
+	rootCmd.Flags().StringVar(&decoderOptions.SchemaDir, "schema-dir", "", "directory of <id>.avsc files resolving Confluent wire format schema ids")
+	rootCmd.Flags().StringVar(&decoderOptions.ProtoDescriptorSet, "proto-descriptor", "", "protobuf FileDescriptorSet file, see protoc --descriptor_set_out")
+	rootCmd.Flags().StringVar(&decoderOptions.ProtoMessage, "proto-message", "", "full name of the protobuf message type of the values")
+}
+
+// buildFilter combines the filter flags into a single filter.
+func buildFilter() (filter.Filter, error) {
+	filters := make([]filter.Filter, 0)
+	if filterKey != "" {
+		filters = append(filters, filter.Key(filterKey))
+	}
+	if filterKeyRegex != "" {
+		f, err := filter.KeyRegex(filterKeyRegex)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if filterGrep != "" {
+		f, err := filter.Grep(filterGrep)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	for _, h := range filterHeaders {
+		f, err := filter.Header(h)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if filterExpr != "" {
+		f, err := filter.Expr(filterExpr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filter.All(filters...), nil
+}
+
+// requireTopic checks --topic for the commands that need it. The admin
+// commands do not, so the flag cannot be marked as required.
+func requireTopic() error {
+	if topic == "" {
+		return errors.New(`required flag(s) "topic" not set`)
+	}
+	return nil
+}
+
+// errIdle is returned by fetch when no message arrived within idle.
+var errIdle = errors.New("idle timeout")
+
+func fetch(ctx context.Context, r *kafka.Reader, idle time.Duration) (kafka.Message, error) {
+	if idle <= 0 {
+		return r.FetchMessage(ctx)
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, idle)
+	defer cancel()
+	m, err := r.FetchMessage(fetchCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return m, errIdle
+	}
+	return m, err
+}
+
+func printMessage(m kafka.Message) error {
+	switch format {
+	case "json":
+		b, err := json.Marshal(newEnvelope(m))
+		if err != nil {
+			return fmt.Errorf("failed to format message at %d/%d: %w", m.Partition, m.Offset, err)
+		}
+		fmt.Printf("%s\n", b)
+	default:
+		//fmt.Printf("message at topic/partition/offset %v/%v/%v: %s = %s\n", m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
+		fmt.Printf("%s\n", string(m.Value))
+	}
+	return nil
 }
 
-func runStreaming(topic string, brokers []string) {
+func runStreaming(ctx context.Context, topic string, brokers []string, keep filter.Filter, dec decoder.Decoder) (err error) {
+	groupId, mode, startOffset := consumerGroup, commitMode, kafka.LastOffset
+	if groupId == "" {
+		// offsets of a group of a single run are not committed, so that
+		// the group is removed once the reader leaves it
+		groupId, mode = uuid.New().String(), noCommit
+	}
+	if fromBeginning {
+		startOffset = kafka.FirstOffset
+	}
+	var until *untilOffsets
+	if untilOffset >= 0 {
+		if until, err = lookupUntilOffsets(ctx, topic, consumerGroup, startOffset); err != nil {
+			return err
+		}
+		if until.done() {
+			return nil
+		}
+	}
+
 	log.Printf("Streaming Start...\n")
-	groupId := uuid.New().String()
 	r := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:     brokers,
 		GroupID:     groupId,
-		StartOffset: kafka.LastOffset,
+		StartOffset: startOffset,
 		Topic:       topic,
 		MinBytes:    10e3, // 10KB
 		MaxBytes:    10e6, // 10MB
 		MaxWait:     100 * time.Millisecond,
+		Dialer:      dialer,
 	})
-	ctx := context.Background()
-	for {
-		m, err := r.FetchMessage(ctx)
-		if err != nil {
-			break
-		}
-		//fmt.Printf("message at topic/partition/offset %v/%v/%v: %s = %s\n", m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
-		fmt.Printf("%s\n", string(m.Value))
+
+	c, err := newCommitter(r, mode, commitEvery)
+	if err != nil {
+		r.Close()
+		return err
 	}
+	defer func() {
+		if cerr := c.flush(context.Background()); cerr != nil && err == nil {
+			err = cerr
+		}
+		if cerr := r.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close reader: %w", cerr)
+		}
+	}()
+
+	var count, skipped int64
+	defer func() {
+		log.Printf("matched: %d, skipped: %d\n", count, skipped)
+	}()
+
+	for maxMessages <= 0 || count < maxMessages {
+		m, ferr := fetch(ctx, r, idleTimeout)
+		switch {
+		case ferr == nil:
+		case errors.Is(ferr, errIdle):
+			log.Printf("no message for %v, exiting\n", idleTimeout)
+			return nil
+		case ctx.Err() != nil:
+			log.Printf("interrupted, exiting\n")
+			return nil
+		default:
+			return fmt.Errorf("failed to fetch message: %w", ferr)
+		}
+		if until != nil && !until.accept(m) {
+			if until.done() {
+				break
+			}
+			continue
+		}
+
+		// commits use their own context so that an interrupt does not
+		// abort a commit half way
+		if err := c.before(context.Background(), m); err != nil {
+			return err
+		}
 
-	if err := r.Close(); err != nil {
-		log.Fatal("failed to close reader:", err)
+		decoded := true
+		if dec != nil {
+			value, derr := dec.Decode(m.Value)
+			if derr != nil {
+				log.Printf("failed to decode message at %d/%d: %v\n", m.Partition, m.Offset, derr)
+				decoded = false
+			}
+			m.Value = value
+		}
+
+		if decoded && keep(m) {
+			if err := printMessage(m); err != nil {
+				return err
+			}
+			count++
+		} else {
+			skipped++
+		}
+
+		if err := c.after(context.Background(), m); err != nil {
+			return err
+		}
+
+		if until != nil && until.done() {
+			break
+		}
 	}
+	return nil
 }
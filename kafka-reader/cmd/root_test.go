@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func commandPaths(c *cobra.Command, path []string) [][]string {
+	paths := [][]string{path}
+	for _, sub := range c.Commands() {
+		paths = append(paths, commandPaths(sub, append(append([]string{}, path...), sub.Name()))...)
+	}
+	return paths
+}
+
+// TestHelp runs --help on every command, which panics when a flag
+// shorthand of a command clashes with a persistent one.
+func TestHelp(t *testing.T) {
+	rootCmd.SetOut(ioutil.Discard)
+	rootCmd.SetErr(ioutil.Discard)
+	defer rootCmd.SetArgs(nil)
+	for _, path := range commandPaths(rootCmd, nil) {
+		rootCmd.SetArgs(append(path, "--help"))
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("expect help of %v but panic %v", path, r)
+				}
+			}()
+			if err := rootCmd.Execute(); err != nil {
+				t.Errorf("expect help of %v but return %v", path, err)
+			}
+		}()
+	}
+}
+
+// TestStatsFlags checks that the stats flags do not share the variables of
+// the consume flags of the same name.
+func TestStatsFlags(t *testing.T) {
+	for name, value := range map[string]string{"max-messages": "5", "idle-timeout": "5s"} {
+		root, stats := rootCmd.Flags().Lookup(name), statsCmd.Flags().Lookup(name)
+		if err := stats.Value.Set(value); err != nil {
+			t.Fatal(err)
+		}
+		if root.Value.String() != root.DefValue {
+			t.Errorf("expect %s of the root command unchanged but return %v", name, root.Value)
+		}
+		stats.Value.Set(stats.DefValue)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ipoemi/kafka-reader/sink"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sinkOptions sink.Options
+	sinkStart   string
+
+	sinkCmd = &cobra.Command{
+		Use:   "sink",
+		Short: "archive the topic to rotating local JSONL files",
+		Long: `Archive the topic to rotating local JSONL files, one line per message in
+the envelope format of --format json, so that files can be replayed with
+produce --format json.
+
+Files are laid out as
+  <dir>/<topic>/partition=<p>/date=<yyyy-mm-dd>/<topic>-<p>-<first>-<last>.jsonl[.gz]
+and every partition checkpoints its next offset once a file is complete, so
+restarting continues without gaps or duplicates.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireTopic(); err != nil {
+				return err
+			}
+			if sinkOptions.Dir == "" {
+				return errors.New(`required flag(s) "dir" not set`)
+			}
+			if sinkStart != "earliest" && sinkStart != "latest" {
+				return fmt.Errorf("unknown start %q", sinkStart)
+			}
+			return runSink(cmd.Context(), topic, brokers)
+		},
+	}
+)
+
+func init() {
+	sinkCmd.Flags().StringVarP(&sinkOptions.Dir, "dir", "d", "", "directory to write the files to")
+	sinkCmd.Flags().BoolVar(&sinkOptions.Gzip, "gzip", false, "gzip the files")
+	sinkCmd.Flags().Int64Var(&sinkOptions.MaxBytes, "max-file-size", 128<<20, "roll files once this many bytes were written, before compression (0 means no limit)")
+	sinkCmd.Flags().DurationVar(&sinkOptions.MaxAge, "roll-interval", time.Hour, "roll files this long after they were opened (0 means no limit)")
+	sinkCmd.Flags().StringVar(&sinkStart, "start", "earliest", "where partitions without a checkpoint start: earliest or latest")
+
+	rootCmd.AddCommand(sinkCmd)
+}
+
+func encodeEnvelope(m kafka.Message) ([]byte, error) {
+	return json.Marshal(newEnvelope(m))
+}
+
+// lookupPartitions returns the partition ids of topic, each written to its
+// own files.
+func lookupPartitions(ctx context.Context, brokers []string, topic string) ([]int, error) {
+	var err error
+	for _, broker := range brokers {
+		var partitions []kafka.Partition
+		partitions, err = dialer.LookupPartitions(ctx, "tcp", broker, topic)
+		if err != nil {
+			continue
+		}
+		ids := make([]int, 0, len(partitions))
+		for _, p := range partitions {
+			ids = append(ids, p.ID)
+		}
+		return ids, nil
+	}
+	return nil, fmt.Errorf("lookup partitions of %s: %w", topic, err)
+}
+
+func runSink(ctx context.Context, topic string, brokers []string) error {
+	partitions, err := lookupPartitions(ctx, brokers, topic)
+	if err != nil {
+		return err
+	}
+	opts := sinkOptions
+	opts.Encode = encodeEnvelope
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	for _, p := range partitions {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			if err := sinkPartition(ctx, opts, topic, p); err != nil {
+				mutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mutex.Unlock()
+				cancel()
+			}
+		}(p)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// sinkPartition archives one partition until ctx is done, then completes the
+// current file.
+func sinkPartition(ctx context.Context, opts sink.Options, topic string, partition int) (err error) {
+	w, err := sink.OpenPartition(opts, topic, partition)
+	if err != nil {
+		return err
+	}
+	offset := w.NextOffset()
+	switch {
+	case offset >= 0:
+		log.Printf("partition %d: resuming from offset %d\n", partition, offset)
+	case sinkStart == "latest":
+		offset = kafka.LastOffset
+	default:
+		offset = kafka.FirstOffset
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+		MinBytes:  10e3, // 10KB
+		MaxBytes:  10e6, // 10MB
+		MaxWait:   100 * time.Millisecond,
+		Dialer:    dialer,
+	})
+	defer func() {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if cerr := r.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close reader: %w", cerr)
+		}
+	}()
+	if err := r.SetOffset(offset); err != nil {
+		return fmt.Errorf("partition %d: %w", partition, err)
+	}
+
+	for {
+		// wake up every second so that files roll on time on quiet partitions
+		fetchCtx, cancel := context.WithTimeout(ctx, time.Second)
+		m, err := r.FetchMessage(fetchCtx)
+		cancel()
+		switch {
+		case err == nil:
+			if err := w.Write(m); err != nil {
+				return err
+			}
+		case ctx.Err() != nil:
+			return nil
+		case !errors.Is(err, context.DeadlineExceeded):
+			return fmt.Errorf("partition %d: failed to fetch message: %w", partition, err)
+		}
+		if err := w.RollIfDue(time.Now()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ipoemi/kafka-reader/stats"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsInterval      time.Duration
+	statsFromBeginning bool
+	statsMaxMessages   int64
+	statsIdleTimeout   time.Duration
+
+	statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "consume the topic without printing and report its statistics",
+		Long: `Consume the topic without printing messages and report its statistics.
+
+Every --interval a line with the messages/sec and bytes/sec of the interval,
+the lag of every partition and the estimated number of distinct keys is
+logged. On exit, by interrupt, --max-messages or --idle-timeout, a summary
+with the totals, per partition lag and a histogram of value sizes is printed
+in --output format.
+
+Nothing is committed, consuming does not affect any group.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireTopic(); err != nil {
+				return err
+			}
+			if statsInterval <= 0 {
+				return fmt.Errorf("invalid interval %v", statsInterval)
+			}
+			return runStats(cmd.Context(), topic, brokers)
+		},
+	}
+)
+
+func init() {
+	addOutputFlag(statsCmd)
+	statsCmd.Flags().DurationVar(&statsInterval, "interval", 5*time.Second, "interval between reports")
+	statsCmd.Flags().BoolVar(&statsFromBeginning, "from-beginning", false, "start from the earliest offsets instead of the latest")
+	statsCmd.Flags().Int64VarP(&statsMaxMessages, "max-messages", "n", 0, "stop after consuming this many messages (0 means no limit)")
+	statsCmd.Flags().DurationVar(&statsIdleTimeout, "idle-timeout", 0, "stop when no message arrives for this long (0 means wait forever)")
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+// refreshLastOffsets updates the last offsets, and so the lag, of every
+// partition. Failures are logged, lag being reported from the previous
+// offsets.
+func refreshLastOffsets(ctx context.Context, client *kafka.Client, c *stats.Collector, topic string, partitions []int) {
+	last, err := listOffsets(ctx, client, topic, partitions, kafka.LastOffset)
+	if err != nil {
+		log.Printf("failed to refresh lag: %v\n", err)
+		return
+	}
+	c.SetLastOffsets(last)
+}
+
+func formatLag(partitions []stats.PartitionSummary) string {
+	s := make([]string, 0, len(partitions))
+	for _, p := range partitions {
+		lag := "?"
+		if p.Lag >= 0 {
+			lag = strconv.FormatInt(p.Lag, 10)
+		}
+		s = append(s, fmt.Sprintf("%d=%s", p.Partition, lag))
+	}
+	return strings.Join(s, " ")
+}
+
+func runStats(ctx context.Context, topic string, brokers []string) (err error) {
+	client := newClient()
+	metadata, err := topicPartitions(ctx, client, topic)
+	if err != nil {
+		return err
+	}
+	partitions := metadata[topic]
+
+	startOffset := kafka.LastOffset
+	if statsFromBeginning {
+		startOffset = kafka.FirstOffset
+	}
+	positions, err := listOffsets(ctx, client, topic, partitions, startOffset)
+	if err != nil {
+		return err
+	}
+	c := stats.NewCollector(time.Now())
+	c.SetPositions(positions)
+	refreshLastOffsets(ctx, client, c, topic, partitions)
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     uuid.New().String(),
+		StartOffset: startOffset,
+		Topic:       topic,
+		MinBytes:    10e3, // 10KB
+		MaxBytes:    10e6, // 10MB
+		MaxWait:     100 * time.Millisecond,
+		Dialer:      dialer,
+	})
+	defer func() {
+		if cerr := r.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close reader: %w", cerr)
+		}
+	}()
+
+	done := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+		prev := c.Summary(time.Now())
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				refreshLastOffsets(ctx, client, c, topic, partitions)
+				cur := c.Summary(now)
+				messagesPerSec, bytesPerSec := stats.Rates(prev, cur)
+				log.Printf("%.1f msgs/s, %.1f bytes/s, total: %d, keys: ~%d, lag: %d [%s]\n",
+					messagesPerSec, bytesPerSec, cur.Messages, cur.DistinctKeys, cur.TotalLag, formatLag(cur.Partitions))
+				prev = cur
+			}
+		}
+	}()
+
+	var count int64
+	for statsMaxMessages <= 0 || count < statsMaxMessages {
+		m, ferr := fetch(ctx, r, statsIdleTimeout)
+		if ferr != nil {
+			if errors.Is(ferr, errIdle) {
+				log.Printf("no message for %v, exiting\n", statsIdleTimeout)
+				break
+			}
+			if ctx.Err() != nil {
+				log.Printf("interrupted, exiting\n")
+				break
+			}
+			close(done)
+			<-reported
+			return fmt.Errorf("failed to fetch message: %w", ferr)
+		}
+		c.Add(m)
+		count++
+	}
+	close(done)
+	<-reported
+
+	// the final lag is fetched with its own context, ctx may be interrupted
+	refreshLastOffsets(context.Background(), client, c, topic, partitions)
+	return renderStats(c.Summary(time.Now()))
+}
+
+func renderStats(s stats.Summary) error {
+	if output == "json" {
+		return render(s, nil, nil)
+	}
+	overview := [][]string{
+		{"elapsed", s.Elapsed.Round(time.Millisecond).String()},
+		{"messages", strconv.FormatInt(s.Messages, 10)},
+		{"bytes", strconv.FormatInt(s.Bytes, 10)},
+		{"messages/sec", strconv.FormatFloat(s.MessagesPerSec, 'f', 1, 64)},
+		{"bytes/sec", strconv.FormatFloat(s.BytesPerSec, 'f', 1, 64)},
+		{"distinct keys (estimate)", strconv.FormatUint(s.DistinctKeys, 10)},
+		{"null keys", strconv.FormatInt(s.NullKeys, 10)},
+		{"total lag", strconv.FormatInt(s.TotalLag, 10)},
+		{"value size min/mean/max", fmt.Sprintf("%d/%.1f/%d", s.ValueSizes.Min, s.ValueSizes.Mean, s.ValueSizes.Max)},
+		{"value size p50/p95/p99", fmt.Sprintf("%d/%d/%d", s.ValueSizes.P50, s.ValueSizes.P95, s.ValueSizes.P99)},
+	}
+	if err := render(s, []string{"STAT", "VALUE"}, overview); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(s.Partitions))
+	for _, p := range s.Partitions {
+		rows = append(rows, []string{
+			strconv.Itoa(p.Partition),
+			strconv.FormatInt(p.Messages, 10),
+			strconv.FormatInt(p.Bytes, 10),
+			strconv.FormatInt(p.NextOffset, 10),
+			strconv.FormatInt(p.LastOffset, 10),
+			strconv.FormatInt(p.Lag, 10),
+		})
+	}
+	fmt.Println()
+	if err := render(s, []string{"PARTITION", "MESSAGES", "BYTES", "NEXT-OFFSET", "LAST-OFFSET", "LAG"}, rows); err != nil {
+		return err
+	}
+
+	rows = make([][]string, 0, len(s.ValueSizes.Buckets))
+	for _, b := range s.ValueSizes.Buckets {
+		percent := 0.0
+		if s.Messages > 0 {
+			percent = float64(b.Count) * 100 / float64(s.Messages)
+		}
+		rows = append(rows, []string{
+			"<= " + strconv.FormatInt(b.Le, 10),
+			strconv.FormatInt(b.Count, 10),
+			strconv.FormatFloat(percent, 'f', 1, 64) + "%",
+			strings.Repeat("#", int(percent/2+0.5)),
+		})
+	}
+	fmt.Println()
+	return render(s, []string{"VALUE-SIZE", "COUNT", "PERCENT", ""}, rows)
+}
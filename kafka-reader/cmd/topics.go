@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	topicsInternal          bool
+	topicsPartitions        int
+	topicsReplicationFactor int
+	topicsConfigs           []string
+
+	topicsCmd = &cobra.Command{
+		Use:   "topics",
+		Short: "list, describe, create and delete topics",
+	}
+
+	topicsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list topics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTopicsList(cmd.Context())
+		},
+	}
+
+	topicsDescribeCmd = &cobra.Command{
+		Use:   "describe TOPIC...",
+		Short: "describe partitions, replicas and offsets of topics",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTopicsDescribe(cmd.Context(), args)
+		},
+	}
+
+	topicsCreateCmd = &cobra.Command{
+		Use:   "create TOPIC...",
+		Short: "create topics",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTopicsCreate(cmd.Context(), args)
+		},
+	}
+
+	topicsDeleteCmd = &cobra.Command{
+		Use:   "delete TOPIC...",
+		Short: "delete topics",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTopicsDelete(cmd.Context(), args)
+		},
+	}
+)
+
+func init() {
+	addOutputFlag(topicsCmd)
+
+	topicsListCmd.Flags().BoolVar(&topicsInternal, "internal", false, "include internal topics")
+
+	topicsCreateCmd.Flags().IntVarP(&topicsPartitions, "partitions", "p", 1, "number of partitions")
+	topicsCreateCmd.Flags().IntVarP(&topicsReplicationFactor, "replication-factor", "r", 1, "replication factor")
+	topicsCreateCmd.Flags().StringArrayVarP(&topicsConfigs, "config", "c", []string{}, "topic config as k=v, e.g. retention.ms=86400000 (repeatable)")
+
+	topicsCmd.AddCommand(topicsListCmd, topicsDescribeCmd, topicsCreateCmd, topicsDeleteCmd)
+	rootCmd.AddCommand(topicsCmd)
+}
+
+type topicSummary struct {
+	Name              string `json:"name"`
+	Partitions        int    `json:"partitions"`
+	ReplicationFactor int    `json:"replication_factor"`
+	Internal          bool   `json:"internal"`
+}
+
+func runTopicsList(ctx context.Context) error {
+	meta, err := newClient().Metadata(ctx, &kafka.MetadataRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	topics := make([]topicSummary, 0, len(meta.Topics))
+	for _, t := range meta.Topics {
+		if t.Internal && !topicsInternal {
+			continue
+		}
+		s := topicSummary{Name: t.Name, Partitions: len(t.Partitions), Internal: t.Internal}
+		if len(t.Partitions) > 0 {
+			s.ReplicationFactor = len(t.Partitions[0].Replicas)
+		}
+		topics = append(topics, s)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+
+	rows := make([][]string, 0, len(topics))
+	for _, t := range topics {
+		rows = append(rows, []string{t.Name, strconv.Itoa(t.Partitions), strconv.Itoa(t.ReplicationFactor), strconv.FormatBool(t.Internal)})
+	}
+	return render(topics, []string{"TOPIC", "PARTITIONS", "REPLICATION", "INTERNAL"}, rows)
+}
+
+type partitionDescription struct {
+	Topic       string `json:"topic"`
+	Partition   int    `json:"partition"`
+	Leader      int    `json:"leader"`
+	Replicas    []int  `json:"replicas"`
+	Isr         []int  `json:"isr"`
+	FirstOffset int64  `json:"first_offset"`
+	LastOffset  int64  `json:"last_offset"`
+}
+
+func brokerIds(brokers []kafka.Broker) []int {
+	ids := make([]int, 0, len(brokers))
+	for _, b := range brokers {
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+func joinInts(v []int) string {
+	s := make([]string, 0, len(v))
+	for _, i := range v {
+		s = append(s, strconv.Itoa(i))
+	}
+	return strings.Join(s, ",")
+}
+
+func runTopicsDescribe(ctx context.Context, topics []string) error {
+	client := newClient()
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	partitions := make([]partitionDescription, 0)
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return fmt.Errorf("topic %s: %w", t.Name, t.Error)
+		}
+		ids := make([]int, 0, len(t.Partitions))
+		for _, p := range t.Partitions {
+			ids = append(ids, p.ID)
+		}
+		first, err := listOffsets(ctx, client, t.Name, ids, kafka.FirstOffset)
+		if err != nil {
+			return err
+		}
+		last, err := listOffsets(ctx, client, t.Name, ids, kafka.LastOffset)
+		if err != nil {
+			return err
+		}
+		for _, p := range t.Partitions {
+			partitions = append(partitions, partitionDescription{
+				Topic:       t.Name,
+				Partition:   p.ID,
+				Leader:      p.Leader.ID,
+				Replicas:    brokerIds(p.Replicas),
+				Isr:         brokerIds(p.Isr),
+				FirstOffset: first[p.ID],
+				LastOffset:  last[p.ID],
+			})
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+
+	rows := make([][]string, 0, len(partitions))
+	for _, p := range partitions {
+		rows = append(rows, []string{
+			p.Topic,
+			strconv.Itoa(p.Partition),
+			strconv.Itoa(p.Leader),
+			joinInts(p.Replicas),
+			joinInts(p.Isr),
+			strconv.FormatInt(p.FirstOffset, 10),
+			strconv.FormatInt(p.LastOffset, 10),
+		})
+	}
+	return render(partitions, []string{"TOPIC", "PARTITION", "LEADER", "REPLICAS", "ISR", "FIRST-OFFSET", "LAST-OFFSET"}, rows)
+}
+
+type topicResult struct {
+	Topic string `json:"topic"`
+	Error string `json:"error,omitempty"`
+}
+
+// renderTopicResults prints the per topic outcome of a create or delete and
+// returns an error when any topic failed.
+func renderTopicResults(topics []string, errs map[string]error, action string) error {
+	results := make([]topicResult, 0, len(topics))
+	rows := make([][]string, 0, len(topics))
+	failed := 0
+	for _, t := range topics {
+		err := errs[t]
+		status := action
+		if err != nil {
+			status = "failed: " + err.Error()
+			failed++
+		}
+		results = append(results, topicResult{Topic: t, Error: errorString(err)})
+		rows = append(rows, []string{t, status})
+	}
+	if err := render(results, []string{"TOPIC", "STATUS"}, rows); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d topics failed", failed, len(topics))
+	}
+	return nil
+}
+
+func runTopicsCreate(ctx context.Context, topics []string) error {
+	entries := make([]kafka.ConfigEntry, 0, len(topicsConfigs))
+	for _, c := range topicsConfigs {
+		i := strings.IndexByte(c, '=')
+		if i <= 0 {
+			return fmt.Errorf("invalid config %q, expected k=v", c)
+		}
+		entries = append(entries, kafka.ConfigEntry{ConfigName: c[:i], ConfigValue: c[i+1:]})
+	}
+	configs := make([]kafka.TopicConfig, 0, len(topics))
+	for _, t := range topics {
+		configs = append(configs, kafka.TopicConfig{
+			Topic:             t,
+			NumPartitions:     topicsPartitions,
+			ReplicationFactor: topicsReplicationFactor,
+			ConfigEntries:     entries,
+		})
+	}
+	resp, err := newClient().CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: configs})
+	if err != nil {
+		return fmt.Errorf("failed to create topics: %w", err)
+	}
+	return renderTopicResults(topics, resp.Errors, "created")
+}
+
+func runTopicsDelete(ctx context.Context, topics []string) error {
+	resp, err := newClient().DeleteTopics(ctx, &kafka.DeleteTopicsRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("failed to delete topics: %w", err)
+	}
+	return renderTopicResults(topics, resp.Errors, "deleted")
+}
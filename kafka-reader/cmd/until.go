@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// untilOffsets tracks the partitions still to be read up to --until-offset.
+type untilOffsets struct {
+	offset  int64
+	pending map[int]bool
+}
+
+// newUntilOffsets returns the tracker of the partitions that reach offset,
+// given the offsets they start reading from and their last offsets. A
+// partition whose last offset is not past offset has no message at offset
+// yet, and one that starts past offset has no message up to it to read, so
+// neither is waited for.
+func newUntilOffsets(offset int64, start, last map[int]int64) *untilOffsets {
+	u := &untilOffsets{offset: offset, pending: make(map[int]bool)}
+	for p, end := range last {
+		if offset < end && start[p] <= offset {
+			u.pending[p] = true
+		}
+	}
+	return u
+}
+
+// accept returns whether a message is to be handled, that is whether its
+// partition is still pending and it is not past offset.
+func (u *untilOffsets) accept(m kafka.Message) bool {
+	if !u.pending[m.Partition] {
+		return false
+	}
+	if m.Offset >= u.offset {
+		delete(u.pending, m.Partition)
+	}
+	return m.Offset <= u.offset
+}
+
+// done returns whether every partition has reached offset.
+func (u *untilOffsets) done() bool {
+	return len(u.pending) == 0
+}
+
+// partitions returns the sorted pending partitions.
+func (u *untilOffsets) partitions() []int {
+	ids := make([]int, 0, len(u.pending))
+	for p := range u.pending {
+		ids = append(ids, p)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// lookupUntilOffsets returns the tracker of --until-offset for topic,
+// resolving where group starts from as the reader does: its committed
+// offsets, else the first or last offsets of startOffset.
+func lookupUntilOffsets(ctx context.Context, topic, group string, startOffset int64) (*untilOffsets, error) {
+	client := newClient()
+	meta, err := topicPartitions(ctx, client, topic)
+	if err != nil {
+		return nil, err
+	}
+	partitions := meta[topic]
+	last, err := listOffsets(ctx, client, topic, partitions, kafka.LastOffset)
+	if err != nil {
+		return nil, err
+	}
+	start, err := listOffsets(ctx, client, topic, partitions, startOffset)
+	if err != nil {
+		return nil, err
+	}
+	if group != "" {
+		committed, err := committedOffsets(ctx, client, group, meta)
+		if err != nil {
+			return nil, err
+		}
+		for p, offset := range committed[topic] {
+			start[p] = offset
+		}
+	}
+	u := newUntilOffsets(untilOffset, start, last)
+	if n := len(partitions) - len(u.pending); n > 0 {
+		log.Printf("%d of %d partitions have no message to read up to offset %d\n", n, len(partitions), untilOffset)
+	}
+	if !u.done() {
+		log.Printf("reading partitions %s up to offset %d\n", joinInts(u.partitions()), untilOffset)
+	}
+	return u, nil
+}
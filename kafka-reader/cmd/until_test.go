@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestUntilOffsetsPending(t *testing.T) {
+	// partition 0 reaches 10, 1 is empty, 2 has no offset 10 yet and 3
+	// starts past it
+	start := map[int]int64{0: 0, 1: 0, 2: 4, 3: 12}
+	last := map[int]int64{0: 20, 1: 0, 2: 10, 3: 30}
+	u := newUntilOffsets(10, start, last)
+	if expect := []int{0}; !reflect.DeepEqual(u.partitions(), expect) {
+		t.Errorf("expect %v but return %v", expect, u.partitions())
+	}
+
+	u = newUntilOffsets(10, map[int]int64{0: 11}, map[int]int64{0: 20})
+	if !u.done() {
+		t.Errorf("expect done without partitions to read but pending %v", u.partitions())
+	}
+}
+
+func TestUntilOffsetsAccept(t *testing.T) {
+	u := newUntilOffsets(10, map[int]int64{0: 0, 1: 0, 2: 0}, map[int]int64{0: 20, 1: 20, 2: 20})
+	tests := []struct {
+		partition int
+		offset    int64
+		accept    bool
+		pending   []int
+	}{
+		{0, 9, true, []int{0, 1, 2}},
+		{0, 10, true, []int{1, 2}},
+		{0, 11, false, []int{1, 2}},
+		// a partition may skip offset 10 after a compaction
+		{1, 12, false, []int{2}},
+		{2, 10, true, []int{}},
+	}
+	for _, test := range tests {
+		accept := u.accept(kafka.Message{Partition: test.partition, Offset: test.offset})
+		if accept != test.accept || !reflect.DeepEqual(u.partitions(), test.pending) {
+			t.Errorf("expect %v and %v pending at %d/%d but return %v and %v", test.accept, test.pending, test.partition, test.offset, accept, u.partitions())
+		}
+	}
+	if !u.done() {
+		t.Errorf("expect done but pending %v", u.partitions())
+	}
+}
@@ -0,0 +1,222 @@
+// Package config loads kafka-reader cluster profiles from a YAML file, e.g.
+//
+//	profile: local
+//	profiles:
+//	  local:
+//	    brokers: [localhost:9092]
+//	  prod:
+//	    brokers: [kafka1:9093, kafka2:9093]
+//	    format: json
+//	    sasl:
+//	      mechanism: scram-sha-512
+//	      username: reader
+//	      password: secret
+//	    tls:
+//	      ca: /etc/kafka/ca.pem
+//	      cert: /etc/kafka/client.pem
+//	      key: /etc/kafka/client.key
+//
+// where profile names the profile used when none is given.
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix prefixes the environment variables overriding profile settings,
+// e.g. KAFKA_READER_BROKERS or KAFKA_READER_SASL_PASSWORD.
+const EnvPrefix = "KAFKA_READER_"
+
+type SASL struct {
+	// Mechanism is plain, scram-sha-256 or scram-sha-512.
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+type TLS struct {
+	Enabled bool `yaml:"enabled"`
+	// CA is a PEM file of the certificate authorities trusted for brokers,
+	// the system pool is used when empty.
+	CA string `yaml:"ca"`
+	// Cert and Key are PEM files of the client certificate.
+	Cert               string `yaml:"cert"`
+	Key                string `yaml:"key"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// Profile holds the settings of one cluster.
+type Profile struct {
+	Brokers        []string `yaml:"brokers"`
+	SASL           SASL     `yaml:"sasl"`
+	TLS            TLS      `yaml:"tls"`
+	Format         string   `yaml:"format"`
+	Decoder        string   `yaml:"decoder"`
+	SchemaRegistry string   `yaml:"schema_registry"`
+}
+
+type File struct {
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// DefaultPath returns ~/.kafka-reader.yaml.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".kafka-reader.yaml"
+	}
+	return filepath.Join(home, ".kafka-reader.yaml")
+}
+
+// Load reads the config file at path. A missing file is an empty config
+// unless required is set.
+func Load(path string, required bool) (*File, error) {
+	b, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Resolve returns the profile called name, or the default profile of the
+// file when name is empty. No profile at all is an empty profile.
+func (f *File) Resolve(name string) (Profile, error) {
+	if name == "" {
+		name = f.Profile
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(f.Profiles))
+		for n := range f.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("unknown profile %q, known profiles: %s", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// ApplyEnv overrides the profile with the KAFKA_READER_* variables found by
+// lookup, usually os.LookupEnv.
+func (p *Profile) ApplyEnv(lookup func(string) (string, bool)) {
+	str := func(name string, dst *string) {
+		if v, ok := lookup(EnvPrefix + name); ok {
+			*dst = v
+		}
+	}
+	if v, ok := lookup(EnvPrefix + "BROKERS"); ok {
+		p.Brokers = strings.Split(v, ",")
+	}
+	str("SASL_MECHANISM", &p.SASL.Mechanism)
+	str("SASL_USERNAME", &p.SASL.Username)
+	str("SASL_PASSWORD", &p.SASL.Password)
+	str("TLS_CA", &p.TLS.CA)
+	str("TLS_CERT", &p.TLS.Cert)
+	str("TLS_KEY", &p.TLS.Key)
+	if v, ok := lookup(EnvPrefix + "TLS_ENABLED"); ok {
+		p.TLS.Enabled = v == "true" || v == "1"
+	}
+	str("FORMAT", &p.Format)
+	str("DECODER", &p.Decoder)
+	str("SCHEMA_REGISTRY", &p.SchemaRegistry)
+}
+
+// SASLMechanism returns the configured mechanism, or nil without SASL.
+func (p Profile) SASLMechanism() (sasl.Mechanism, error) {
+	switch strings.ToLower(p.SASL.Mechanism) {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: p.SASL.Username, Password: p.SASL.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, p.SASL.Username, p.SASL.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, p.SASL.Username, p.SASL.Password)
+	}
+	return nil, fmt.Errorf("unknown sasl mechanism %q", p.SASL.Mechanism)
+}
+
+// TLSConfig returns the configured TLS settings, or nil when TLS is not
+// enabled. Setting a CA or client certificate enables TLS.
+func (p Profile) TLSConfig() (*tls.Config, error) {
+	t := p.TLS
+	if !t.Enabled && t.CA == "" && t.Cert == "" {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CA != "" {
+		pem, err := ioutil.ReadFile(t.CA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", t.CA)
+		}
+		config.RootCAs = pool
+	}
+	if t.Cert != "" || t.Key != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Dialer returns a dialer for readers and consumer groups.
+func (p Profile) Dialer() (*kafka.Dialer, error) {
+	mechanism, err := p.SASLMechanism()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := p.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Dialer{
+		Timeout:       kafka.DefaultDialer.Timeout,
+		DualStack:     kafka.DefaultDialer.DualStack,
+		SASLMechanism: mechanism,
+		TLS:           tlsConfig,
+	}, nil
+}
+
+// Transport returns a transport for writers and admin clients.
+func (p Profile) Transport() (*kafka.Transport, error) {
+	mechanism, err := p.SASLMechanism()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := p.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Transport{SASL: mechanism, TLS: tlsConfig}, nil
+}
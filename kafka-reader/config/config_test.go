@@ -0,0 +1,76 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const sample = `
+profile: local
+profiles:
+  local:
+    brokers: [localhost:9092]
+  prod:
+    brokers: [kafka1:9093, kafka2:9093]
+    format: json
+    sasl:
+      mechanism: scram-sha-512
+      username: reader
+      password: secret
+`
+
+func TestLoadAndResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(sample), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := f.Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Brokers, []string{"localhost:9092"}) {
+		t.Errorf("unexpected default profile %+v", p)
+	}
+
+	p, err = f.Resolve("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Format != "json" || p.SASL.Username != "reader" {
+		t.Errorf("unexpected prod profile %+v", p)
+	}
+	if m, err := p.SASLMechanism(); err != nil || m.Name() != "SCRAM-SHA-512" {
+		t.Errorf("unexpected sasl mechanism %v, %v", m, err)
+	}
+
+	env := map[string]string{"KAFKA_READER_BROKERS": "a:1,b:2", "KAFKA_READER_SASL_PASSWORD": "other"}
+	p.ApplyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok })
+	if !reflect.DeepEqual(p.Brokers, []string{"a:1", "b:2"}) || p.SASL.Password != "other" || p.SASL.Username != "reader" {
+		t.Errorf("unexpected profile after env %+v", p)
+	}
+
+	if _, err := f.Resolve("dev"); err == nil {
+		t.Errorf("expect error for unknown profile")
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+	f, err := Load(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := f.Resolve(""); err != nil || p.Brokers != nil {
+		t.Errorf("unexpected profile %+v, %v", p, err)
+	}
+	if _, err := Load(path, true); err == nil {
+		t.Errorf("expect error for missing required config")
+	}
+}
@@ -0,0 +1,84 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// Avro decodes Avro binary values, either with a fixed schema or with the
+// schema referenced by the Confluent wire format header.
+type Avro struct {
+	codec  *goavro.Codec
+	source SchemaSource
+
+	mutex  sync.Mutex
+	codecs map[int]*goavro.Codec
+}
+
+func NewAvro(opts Options) (*Avro, error) {
+	if opts.AvroSchemaFile != "" {
+		schema, err := ioutil.ReadFile(opts.AvroSchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read avro schema: %w", err)
+		}
+		codec, err := goavro.NewCodec(string(schema))
+		if err != nil {
+			return nil, fmt.Errorf("invalid avro schema %s: %w", opts.AvroSchemaFile, err)
+		}
+		return &Avro{codec: codec}, nil
+	}
+	switch {
+	case opts.SchemaRegistryURL != "":
+		return NewAvroWithSource(NewRegistry(opts.SchemaRegistryURL)), nil
+	case opts.SchemaDir != "":
+		return NewAvroWithSource(SchemaDir(opts.SchemaDir)), nil
+	}
+	return nil, errors.New("avro decoder needs a schema file, a schema registry url or a schema directory")
+}
+
+// NewAvroWithSource returns a decoder for Confluent wire format values whose
+// schemas are resolved by source and cached by id.
+func NewAvroWithSource(source SchemaSource) *Avro {
+	return &Avro{source: source, codecs: make(map[int]*goavro.Codec)}
+}
+
+func (a *Avro) codecOf(id int) (*goavro.Codec, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if codec, ok := a.codecs[id]; ok {
+		return codec, nil
+	}
+	schema, err := a.source.Schema(id)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema %d: %w", id, err)
+	}
+	a.codecs[id] = codec
+	return codec, nil
+}
+
+func (a *Avro) Decode(value []byte) ([]byte, error) {
+	codec := a.codec
+	if codec == nil {
+		id, payload, err := splitWireFormat(value)
+		if err != nil {
+			return nil, err
+		}
+		if codec, err = a.codecOf(id); err != nil {
+			return nil, err
+		}
+		value = payload
+	}
+	native, _, err := codec.NativeFromBinary(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode avro: %w", err)
+	}
+	return codec.TextualFromNative(nil, native)
+}
@@ -0,0 +1,61 @@
+// Package decoder converts binary kafka message values to JSON for display.
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Decoder converts a message value to its JSON representation.
+type Decoder interface {
+	Decode(value []byte) ([]byte, error)
+}
+
+// Options configures the decoders created by New.
+type Options struct {
+	// AvroSchemaFile is an Avro schema used for every message. When empty,
+	// messages are expected in the Confluent wire format and their schema is
+	// resolved by id against SchemaRegistryURL or SchemaDir.
+	AvroSchemaFile    string
+	SchemaRegistryURL string
+	// SchemaDir holds schemas named by id, e.g. 42.avsc.
+	SchemaDir string
+
+	// ProtoDescriptorSet is a FileDescriptorSet file, as written by
+	// protoc --include_imports --descriptor_set_out, and ProtoMessage the full
+	// name of the message type of every value.
+	ProtoDescriptorSet string
+	ProtoMessage       string
+}
+
+// Names lists the decoders accepted by New.
+var Names = []string{"none", "avro", "protobuf", "msgpack"}
+
+// New returns the decoder called name, or nil for "none".
+func New(name string, opts Options) (Decoder, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "avro":
+		return NewAvro(opts)
+	case "protobuf":
+		return NewProtobuf(opts.ProtoDescriptorSet, opts.ProtoMessage)
+	case "msgpack":
+		return MessagePack{}, nil
+	}
+	return nil, fmt.Errorf("unknown decoder %q", name)
+}
+
+// ErrNotWireFormat is returned for values without the Confluent wire format header.
+var ErrNotWireFormat = errors.New("value is not in the Confluent wire format")
+
+// splitWireFormat splits a Confluent wire format value into its schema id
+// and payload: a zero magic byte, a big-endian 4 byte schema id, then the
+// encoded payload.
+func splitWireFormat(value []byte) (int, []byte, error) {
+	if len(value) < 5 || value[0] != 0 {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(value[1:5])), value[5:], nil
+}
@@ -0,0 +1,125 @@
+package decoder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const candleSchema = `{"type":"record","name":"Candle","fields":[{"name":"market","type":"string"},{"name":"trade_price","type":"double"}]}`
+
+func assertJSON(t *testing.T, actual []byte, expect string) {
+	t.Helper()
+	var a, e interface{}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		t.Fatalf("invalid json %s: %v", actual, err)
+	}
+	_ = json.Unmarshal([]byte(expect), &e)
+	if !reflect.DeepEqual(a, e) {
+		t.Errorf("expect %s but return %s", expect, actual)
+	}
+}
+
+func TestAvro(t *testing.T) {
+	codec, err := goavro.NewCodec(candleSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := codec.BinaryFromNative(nil, map[string]interface{}{"market": "KRW-BTC", "trade_price": 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	schemaFile := filepath.Join(dir, "42.avsc")
+	if err := ioutil.WriteFile(schemaFile, []byte(candleSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New("avro", Options{AvroSchemaFile: schemaFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.Decode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, result, `{"market":"KRW-BTC","trade_price":1.5}`)
+
+	d, err = New("avro", Options{SchemaDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = d.Decode(append([]byte{0, 0, 0, 0, 42}, payload...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, result, `{"market":"KRW-BTC","trade_price":1.5}`)
+
+	if _, err = d.Decode(payload); err != ErrNotWireFormat {
+		t.Errorf("expect %v but return %v", ErrNotWireFormat, err)
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto)},
+	}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptorFile := filepath.Join(t.TempDir(), "timestamp.pb")
+	if err := ioutil.WriteFile(descriptorFile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := proto.Marshal(&timestamppb.Timestamp{Seconds: 1612357354, Nanos: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New("protobuf", Options{ProtoDescriptorSet: descriptorFile, ProtoMessage: "google.protobuf.Timestamp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range [][]byte{payload, append([]byte{0, 0, 0, 0, 1, 0}, payload...)} {
+		result, err := d.Decode(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSON(t, result, `"2021-02-03T13:02:34Z"`)
+	}
+
+	if _, err := New("protobuf", Options{ProtoDescriptorSet: descriptorFile, ProtoMessage: "no.Such"}); err == nil {
+		t.Errorf("expect error for unknown message")
+	}
+}
+
+func TestMessagePack(t *testing.T) {
+	payload, err := msgpack.Marshal(map[string]interface{}{
+		"market": "KRW-BTC",
+		"prices": []interface{}{1, 2.5},
+		"by_id":  map[int]string{1: "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := New("msgpack", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.Decode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, result, `{"market":"KRW-BTC","prices":[1,2.5],"by_id":{"1":"a"}}`)
+}
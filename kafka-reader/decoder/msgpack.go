@@ -0,0 +1,48 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack decodes MessagePack values.
+type MessagePack struct{}
+
+func (MessagePack) Decode(value []byte) ([]byte, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(value))
+	dec.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode msgpack: %w", err)
+	}
+	return json.Marshal(jsonable(v))
+}
+
+// jsonable converts maps, which msgpack allows to have keys of any type,
+// into maps keyed by the formatted key.
+func jsonable(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = jsonable(v)
+		}
+		return m
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = jsonable(v)
+		}
+		return t
+	case []interface{}:
+		for i, v := range t {
+			t[i] = jsonable(v)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,81 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Protobuf decodes values of a single message type described by a
+// FileDescriptorSet.
+type Protobuf struct {
+	message protoreflect.MessageDescriptor
+}
+
+func NewProtobuf(descriptorSetFile string, messageName string) (*Protobuf, error) {
+	if descriptorSetFile == "" || messageName == "" {
+		return nil, errors.New("protobuf decoder needs a descriptor set file and a message name")
+	}
+	b, err := ioutil.ReadFile(descriptorSetFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", descriptorSetFile, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", descriptorSetFile, err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("message %s not found in %s: %w", messageName, descriptorSetFile, err)
+	}
+	message, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", messageName)
+	}
+	return &Protobuf{message: message}, nil
+}
+
+// Decode accepts plain protobuf values as well as the Confluent wire format,
+// which is recognised by its zero magic byte since no protobuf encoding
+// starts with a zero tag.
+func (p *Protobuf) Decode(value []byte) ([]byte, error) {
+	if _, payload, err := splitWireFormat(value); err == nil {
+		if value, err = skipMessageIndexes(payload); err != nil {
+			return nil, err
+		}
+	}
+	msg := dynamicpb.NewMessage(p.message)
+	if err := proto.Unmarshal(value, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode protobuf: %w", err)
+	}
+	return protojson.Marshal(msg)
+}
+
+// skipMessageIndexes drops the zigzag varint encoded message index path that
+// follows the schema id in the Confluent protobuf wire format.
+func skipMessageIndexes(payload []byte) ([]byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, errors.New("invalid message indexes")
+	}
+	payload = payload[n:]
+	for i := int64(0); i < count; i++ {
+		if _, n = binary.Varint(payload); n <= 0 {
+			return nil, errors.New("invalid message indexes")
+		}
+		payload = payload[n:]
+	}
+	return payload, nil
+}
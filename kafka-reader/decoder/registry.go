@@ -0,0 +1,58 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SchemaSource resolves schema ids found in the Confluent wire format.
+type SchemaSource interface {
+	Schema(id int) (string, error)
+}
+
+// Registry fetches schemas from a Confluent schema registry.
+type Registry struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewRegistry(url string) *Registry {
+	return &Registry{
+		URL:    strings.TrimRight(url, "/"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (r *Registry) Schema(id int) (string, error) {
+	resp, err := r.Client.Get(fmt.Sprintf("%s/schemas/ids/%d", r.URL, id))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch schema %d: %s", id, resp.Status)
+	}
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to read schema %d: %w", id, err)
+	}
+	return body.Schema, nil
+}
+
+// SchemaDir reads schemas from files named <id>.avsc in a directory.
+type SchemaDir string
+
+func (d SchemaDir) Schema(id int) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(string(d), fmt.Sprintf("%d.avsc", id)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema %d: %w", id, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,447 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a compiled jq-like predicate over a JSON document.
+//
+// The grammar is a small subset of jq:
+//
+//	expr    = or
+//	or      = and { "or" and }
+//	and     = unary { "and" unary }
+//	unary   = "not" unary | compare
+//	compare = operand [ ("==" | "!=" | "<" | "<=" | ">" | ">=") operand ]
+//	operand = path | string | number | "true" | "false" | "null" | "(" expr ")"
+//	path    = "." [ field ] { "." field | "[" index "]" | "[" string "]" }
+//
+// e.g. `.market == "KRW-BTC" and .trade_price > 50000000`.
+// Missing fields evaluate to null, and like jq only false and null are falsy.
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses src into an Expression.
+func Compile(src string) (*Expression, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+func (e *Expression) String() string { return e.src }
+
+// Match decodes data as JSON and evaluates the expression against it.
+func (e *Expression) Match(data []byte) (bool, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false, err
+	}
+	return e.Eval(doc), nil
+}
+
+// Eval evaluates the expression against an already decoded JSON document.
+func (e *Expression) Eval(doc interface{}) bool {
+	return truthy(e.root.eval(doc))
+}
+
+type node interface {
+	eval(doc interface{}) interface{}
+}
+
+type literalNode struct{ v interface{} }
+
+func (n literalNode) eval(interface{}) interface{} { return n.v }
+
+type pathNode struct{ steps []interface{} }
+
+func (n pathNode) eval(doc interface{}) interface{} {
+	cur := doc
+	for _, step := range n.steps {
+		switch s := step.(type) {
+		case string:
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			cur = obj[s]
+		case int:
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil
+			}
+			if s < 0 {
+				s += len(arr)
+			}
+			if s < 0 || s >= len(arr) {
+				return nil
+			}
+			cur = arr[s]
+		}
+	}
+	return cur
+}
+
+type notNode struct{ x node }
+
+func (n notNode) eval(doc interface{}) interface{} { return !truthy(n.x.eval(doc)) }
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n binaryNode) eval(doc interface{}) interface{} {
+	switch n.op {
+	case "and":
+		return truthy(n.l.eval(doc)) && truthy(n.r.eval(doc))
+	case "or":
+		return truthy(n.l.eval(doc)) || truthy(n.r.eval(doc))
+	}
+	l, r := n.l.eval(doc), n.r.eval(doc)
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(l, r)
+	case "!=":
+		return !reflect.DeepEqual(l, r)
+	}
+	c, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// compare orders two numbers or two strings. ok is false for any other pair.
+func compare(l, r interface{}) (c int, ok bool) {
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case lv < rv:
+			return -1, true
+		case lv > rv:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(lv, rv), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	}
+	return true
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokDot
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q at %d: %s", p.src, p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) next() error {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '.':
+		p.pos++
+		p.tok = token{kind: tokDot, text: ".", pos: start}
+	case c == '[':
+		p.pos++
+		p.tok = token{kind: tokLBracket, text: "[", pos: start}
+	case c == ']':
+		p.pos++
+		p.tok = token{kind: tokRBracket, text: "]", pos: start}
+	case c == '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, text: "(", pos: start}
+	case c == ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, text: ")", pos: start}
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			p.tok.pos = start
+			return p.errorf("unterminated string")
+		}
+		p.pos++
+		p.tok = token{kind: tokString, text: p.src[start:p.pos], pos: start}
+	case c == '=' || c == '!' || c == '<' || c == '>':
+		p.pos++
+		if p.pos < len(p.src) && p.src[p.pos] == '=' {
+			p.pos++
+		}
+		op := p.src[start:p.pos]
+		if op == "=" || op == "!" {
+			p.tok.pos = start
+			return p.errorf("unknown operator %q", op)
+		}
+		p.tok = token{kind: tokOp, text: op, pos: start}
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+	default:
+		p.tok.pos = start
+		return p.errorf("unexpected character %q", c)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokIdent && p.tok.text == "or" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: "or", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokIdent && p.tok.text == "and" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryNode{op: "and", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokIdent && p.tok.text == "not" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOp {
+		return l, nil
+	}
+	op := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	r, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, l: l, r: r}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokDot:
+		return p.parsePath()
+	case tokString:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid string %s", tok.text)
+		}
+		return literalNode{v: s}, p.next()
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %s", tok.text)
+		}
+		return literalNode{v: f}, p.next()
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literalNode{v: true}, p.next()
+		case "false":
+			return literalNode{v: false}, p.next()
+		case "null":
+			return literalNode{v: nil}, p.next()
+		}
+		return nil, p.errorf("unexpected %q", tok.text)
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected )")
+		}
+		return x, p.next()
+	case tokEOF:
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) parsePath() (node, error) {
+	var steps []interface{}
+	// the leading "." may be followed directly by a field name, e.g. ".market"
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokIdent && p.src[p.tok.pos-1] == '.' {
+		steps = append(steps, p.tok.text)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		switch p.tok.kind {
+		case tokDot:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokIdent {
+				return nil, p.errorf("expected field name after .")
+			}
+			steps = append(steps, p.tok.text)
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		case tokLBracket:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			switch p.tok.kind {
+			case tokNumber:
+				i, err := strconv.Atoi(p.tok.text)
+				if err != nil {
+					return nil, p.errorf("invalid index %s", p.tok.text)
+				}
+				steps = append(steps, i)
+			case tokString:
+				s, err := strconv.Unquote(p.tok.text)
+				if err != nil {
+					return nil, p.errorf("invalid string %s", p.tok.text)
+				}
+				steps = append(steps, s)
+			default:
+				return nil, p.errorf("expected index or string in []")
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokRBracket {
+				return nil, p.errorf("expected ]")
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		default:
+			return pathNode{steps: steps}, nil
+		}
+	}
+}
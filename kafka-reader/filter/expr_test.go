@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestExpressionMatch(t *testing.T) {
+	doc := []byte(`{"market":"KRW-BTC","trade_price":51000000,"tags":["a","b"],"meta":{"is-live":true,"note":null}}`)
+	cases := []struct {
+		expr   string
+		expect bool
+	}{
+		{`.market == "KRW-BTC"`, true},
+		{`.market != "KRW-BTC"`, false},
+		{`.trade_price > 50000000`, true},
+		{`.trade_price <= 50000000`, false},
+		{`.market == "KRW-BTC" and .trade_price < 1`, false},
+		{`.market == "KRW-ETH" or .trade_price >= 51000000`, true},
+		{`not (.market == "KRW-ETH")`, true},
+		{`.tags[1] == "b"`, true},
+		{`.tags[-1] == "b"`, true},
+		{`.meta["is-live"]`, true},
+		{`.meta.note`, false},
+		{`.missing == null`, true},
+		{`.missing.deeper`, false},
+		{`.market > 1`, false},
+	}
+	for _, c := range cases {
+		e, err := Compile(c.expr)
+		if err != nil {
+			t.Errorf("compile %s: %v", c.expr, err)
+			continue
+		}
+		result, err := e.Match(doc)
+		if err != nil {
+			t.Errorf("match %s: %v", c.expr, err)
+			continue
+		}
+		if result != c.expect {
+			t.Errorf("%s: expect %v but return %v", c.expr, c.expect, result)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for _, src := range []string{``, `.market ==`, `.market = "x"`, `(.a == 1`, `.a[`, `"open`, `.a == 1 1`} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("%q: expect error", src)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	m := kafka.Message{
+		Key:     []byte("KRW-BTC"),
+		Value:   []byte(`{"market":"KRW-BTC"}`),
+		Headers: []kafka.Header{{Key: "source", Value: []byte("upbit")}},
+	}
+	keyRegex, _ := KeyRegex(`^KRW-`)
+	grep, _ := Grep(`BTC`)
+	header, _ := Header("source=upbit")
+	present, _ := Header("source")
+	expr, _ := Expr(`.market == "KRW-BTC"`)
+	if !All(Key("KRW-BTC"), keyRegex, grep, header, present, expr)(m) {
+		t.Errorf("expect all filters to match")
+	}
+	other, _ := Header("source=bithumb")
+	if All(Key("KRW-BTC"), other)(m) {
+		t.Errorf("expect header filter to skip")
+	}
+	if expr(kafka.Message{Value: []byte("not json")}) {
+		t.Errorf("expect non json value to be skipped")
+	}
+}
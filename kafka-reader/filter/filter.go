@@ -0,0 +1,90 @@
+// Package filter selects kafka messages by key, value, headers or a jq-like
+// expression evaluated against JSON values.
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Filter reports whether a message should be kept.
+type Filter func(m kafka.Message) bool
+
+// All keeps a message only when every filter keeps it. No filters keeps everything.
+func All(filters ...Filter) Filter {
+	return func(m kafka.Message) bool {
+		for _, f := range filters {
+			if !f(m) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Key keeps messages whose key equals key.
+func Key(key string) Filter {
+	k := []byte(key)
+	return func(m kafka.Message) bool {
+		return bytes.Equal(m.Key, k)
+	}
+}
+
+// KeyRegex keeps messages whose key matches the regular expression expr.
+func KeyRegex(expr string) (Filter, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key regex %q: %w", expr, err)
+	}
+	return func(m kafka.Message) bool {
+		return re.Match(m.Key)
+	}, nil
+}
+
+// Grep keeps messages whose value matches the regular expression expr.
+func Grep(expr string) (Filter, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep pattern %q: %w", expr, err)
+	}
+	return func(m kafka.Message) bool {
+		return re.Match(m.Value)
+	}, nil
+}
+
+// Header keeps messages carrying a header matching kv, given as "key=value".
+// A bare "key" only requires the header to be present.
+func Header(kv string) (Filter, error) {
+	key, value, hasValue := kv, "", false
+	if i := strings.IndexByte(kv, '='); i >= 0 {
+		key, value, hasValue = kv[:i], kv[i+1:], true
+	}
+	if key == "" {
+		return nil, fmt.Errorf("invalid header filter %q: empty key", kv)
+	}
+	return func(m kafka.Message) bool {
+		for _, h := range m.Headers {
+			if h.Key == key && (!hasValue || string(h.Value) == value) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Expr keeps messages whose JSON value satisfies the expression src, see Compile.
+// Values that are not valid JSON never match.
+func Expr(src string) (Filter, error) {
+	e, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(m kafka.Message) bool {
+		ok, err := e.Match(m.Value)
+		return err == nil && ok
+	}, nil
+}
@@ -4,6 +4,10 @@ go 1.16
 
 require (
 	github.com/google/uuid v1.2.0
+	github.com/linkedin/goavro/v2 v2.10.0
 	github.com/segmentio/kafka-go v0.4.10
 	github.com/spf13/cobra v1.1.3
+	github.com/vmihailenco/msgpack/v5 v5.3.4
+	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -1,7 +1,13 @@
 package main
 
-import "ipoemi/kafka-reader/cmd"
+import (
+	"os"
+
+	"ipoemi/kafka-reader/cmd"
+)
 
 func main() {
-	cmd.Execute()
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,276 @@
+// Package sink archives the messages of a topic partition to rotating local
+// JSONL files, laid out as
+//
+//	<dir>/<topic>/partition=<p>/date=<yyyy-mm-dd>/<topic>-<p>-<first>-<last>.jsonl[.gz]
+//
+// where first and last are the offsets of the first and last message of the
+// file. A file is written under an .inprogress name and only renamed once it
+// is complete, after which the next offset of the partition is checkpointed.
+// On open, in progress files are discarded and the next offset is recovered
+// from the checkpoint and the completed files, so a restart continues without
+// gaps or duplicates.
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	inProgressSuffix = ".inprogress"
+	checkpointName   = "checkpoint"
+)
+
+// Options configures the files written by a PartitionWriter.
+type Options struct {
+	Dir string
+	// Gzip compresses the files.
+	Gzip bool
+	// MaxBytes rolls a file once this many bytes of JSON lines, before
+	// compression, were written to it. 0 means no limit.
+	MaxBytes int64
+	// MaxAge rolls a file this long after it was opened, 0 means no limit.
+	MaxAge time.Duration
+	// Encode returns the JSON line of a message, without the newline.
+	Encode func(m kafka.Message) ([]byte, error)
+}
+
+func (o Options) ext() string {
+	if o.Gzip {
+		return ".jsonl.gz"
+	}
+	return ".jsonl"
+}
+
+type checkpoint struct {
+	NextOffset int64 `json:"next_offset"`
+}
+
+// PartitionWriter writes the messages of one partition, in offset order.
+type PartitionWriter struct {
+	opts      Options
+	topic     string
+	partition int
+	dir       string
+	next      int64
+
+	// the file being written, nil between files
+	file     *os.File
+	size     int64
+	buffer   *bufio.Writer
+	gzip     *gzip.Writer
+	path     string
+	date     string
+	first    int64
+	last     int64
+	openedAt time.Time
+}
+
+// OpenPartition prepares writing topic/partition under opts.Dir and recovers
+// the next offset to consume, see NextOffset.
+func OpenPartition(opts Options, topic string, partition int) (*PartitionWriter, error) {
+	if opts.Encode == nil {
+		return nil, errors.New("sink: Encode is required")
+	}
+	w := &PartitionWriter{
+		opts:      opts,
+		topic:     topic,
+		partition: partition,
+		dir:       filepath.Join(opts.Dir, topic, fmt.Sprintf("partition=%d", partition)),
+		next:      -1,
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return nil, fmt.Errorf("sink: %w", err)
+	}
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// recover removes incomplete files and computes the next offset from the
+// checkpoint and the last offset of the completed files, whichever is ahead,
+// since a crash may happen between completing a file and checkpointing it.
+func (w *PartitionWriter) recover() error {
+	b, err := ioutil.ReadFile(filepath.Join(w.dir, checkpointName))
+	switch {
+	case err == nil:
+		var c checkpoint
+		if err := json.Unmarshal(b, &c); err != nil {
+			return fmt.Errorf("sink: invalid checkpoint of %s/%d: %w", w.topic, w.partition, err)
+		}
+		w.next = c.NextOffset
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("sink: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(w.dir, "date=*", "*"))
+	if err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f, inProgressSuffix) {
+			if err := os.Remove(f); err != nil {
+				return fmt.Errorf("sink: %w", err)
+			}
+			continue
+		}
+		if last, ok := lastOffsetOf(filepath.Base(f)); ok && last+1 > w.next {
+			w.next = last + 1
+		}
+	}
+	if err := w.saveCheckpoint(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// lastOffsetOf parses the last offset out of a completed file name.
+func lastOffsetOf(name string) (int64, bool) {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".jsonl")
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return 0, false
+	}
+	last, err := strconv.ParseInt(name[i+1:], 10, 64)
+	return last, err == nil
+}
+
+// NextOffset returns the offset to resume consuming from, or -1 when nothing
+// was archived yet.
+func (w *PartitionWriter) NextOffset() int64 {
+	return w.next
+}
+
+func (w *PartitionWriter) saveCheckpoint() error {
+	if w.next < 0 {
+		return nil
+	}
+	b, _ := json.Marshal(checkpoint{NextOffset: w.next})
+	tmp := filepath.Join(w.dir, checkpointName+inProgressSuffix)
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("sink: failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(w.dir, checkpointName)); err != nil {
+		return fmt.Errorf("sink: failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Write appends m to the current file, first rolling it when m belongs to
+// another date or the file is full. Messages at or before an offset already
+// archived are ignored.
+func (w *PartitionWriter) Write(m kafka.Message) error {
+	if w.next >= 0 && m.Offset < w.next || w.file != nil && m.Offset <= w.last {
+		return nil
+	}
+	date := m.Time.UTC().Format("2006-01-02")
+	if w.file != nil && (date != w.date || w.opts.MaxBytes > 0 && w.size >= w.opts.MaxBytes) {
+		if err := w.roll(); err != nil {
+			return err
+		}
+	}
+	if w.file == nil {
+		if err := w.open(date, m.Offset); err != nil {
+			return err
+		}
+	}
+
+	line, err := w.opts.Encode(m)
+	if err != nil {
+		return fmt.Errorf("sink: failed to encode message at %d/%d: %w", m.Partition, m.Offset, err)
+	}
+	var out io.Writer = w.buffer
+	if w.gzip != nil {
+		out = w.gzip
+	}
+	if _, err := out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	w.size += int64(len(line)) + 1
+	w.last = m.Offset
+	return nil
+}
+
+// RollIfDue completes the current file once it is older than MaxAge.
+func (w *PartitionWriter) RollIfDue(now time.Time) error {
+	if w.file == nil || w.opts.MaxAge <= 0 || now.Sub(w.openedAt) < w.opts.MaxAge {
+		return nil
+	}
+	return w.roll()
+}
+
+func (w *PartitionWriter) open(date string, first int64) error {
+	dir := filepath.Join(w.dir, "date="+date)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%d-%020d%s", w.topic, w.partition, first, w.opts.ext()))
+	f, err := os.Create(path + inProgressSuffix)
+	if err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	w.file, w.path, w.date, w.first, w.last, w.size, w.openedAt = f, path, date, first, first-1, 0, time.Now()
+	w.buffer = bufio.NewWriter(f)
+	if w.opts.Gzip {
+		w.gzip = gzip.NewWriter(w.buffer)
+	}
+	return nil
+}
+
+// roll completes the current file: flushes and syncs it, renames it to its
+// final name carrying its last offset, then checkpoints the next offset.
+func (w *PartitionWriter) roll() error {
+	if w.file == nil {
+		return nil
+	}
+	f := w.file
+	w.file = nil
+	if w.gzip != nil {
+		if err := w.gzip.Close(); err != nil {
+			f.Close()
+			return fmt.Errorf("sink: %w", err)
+		}
+		w.gzip = nil
+	}
+	if err := w.buffer.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("sink: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sink: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	if w.last < w.first {
+		// nothing was written
+		return os.Remove(w.path + inProgressSuffix)
+	}
+
+	ext := w.opts.ext()
+	final := fmt.Sprintf("%s-%020d%s", strings.TrimSuffix(w.path, ext), w.last, ext)
+	if err := os.Rename(w.path+inProgressSuffix, final); err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	w.next = w.last + 1
+	return w.saveCheckpoint()
+}
+
+// Close completes the current file.
+func (w *PartitionWriter) Close() error {
+	return w.roll()
+}
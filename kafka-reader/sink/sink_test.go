@@ -0,0 +1,121 @@
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func encode(m kafka.Message) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"offset": m.Offset, "value": string(m.Value)})
+}
+
+func message(offset int64, t time.Time) kafka.Message {
+	return kafka.Message{Topic: "candles", Partition: 0, Offset: offset, Value: []byte("v"), Time: t}
+}
+
+func readOffsets(t *testing.T, files []string) []int64 {
+	t.Helper()
+	offsets := make([]int64, 0)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			var line struct {
+				Offset int64 `json:"offset"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			offsets = append(offsets, line.Offset)
+		}
+		f.Close()
+	}
+	return offsets
+}
+
+func TestPartitionWriter(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Dir: dir, Gzip: true, MaxBytes: 1, Encode: encode}
+	day1 := time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+
+	w, err := OpenPartition(opts, "candles", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.NextOffset() != -1 {
+		t.Errorf("expect -1 but return %d", w.NextOffset())
+	}
+	for _, m := range []kafka.Message{message(10, day1), message(11, day1), message(12, day2)} {
+		if err := w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an incomplete file left behind by a crash
+	if err := w.gzip.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = OpenPartition(opts, "candles", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.NextOffset() != 12 {
+		t.Errorf("expect 12 but return %d", w.NextOffset())
+	}
+	for _, m := range []kafka.Message{message(11, day1), message(12, day2), message(13, day2)} {
+		if err := w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "candles", "partition=0", "date=*", "*"))
+	sort.Strings(files)
+	expectFiles := []string{
+		filepath.Join(dir, "candles", "partition=0", "date=2021-06-01", "candles-0-00000000000000000010-00000000000000000010.jsonl.gz"),
+		filepath.Join(dir, "candles", "partition=0", "date=2021-06-01", "candles-0-00000000000000000011-00000000000000000011.jsonl.gz"),
+		filepath.Join(dir, "candles", "partition=0", "date=2021-06-02", "candles-0-00000000000000000012-00000000000000000012.jsonl.gz"),
+		filepath.Join(dir, "candles", "partition=0", "date=2021-06-02", "candles-0-00000000000000000013-00000000000000000013.jsonl.gz"),
+	}
+	if len(files) != len(expectFiles) {
+		t.Fatalf("expect %v but return %v", expectFiles, files)
+	}
+	for i := range files {
+		if files[i] != expectFiles[i] {
+			t.Errorf("expect %s but return %s", expectFiles[i], files[i])
+		}
+	}
+	offsets := readOffsets(t, files)
+	for i, o := range offsets {
+		if o != int64(10+i) {
+			t.Errorf("expect offsets 10..13 but return %v", offsets)
+			break
+		}
+	}
+
+	w, err = OpenPartition(opts, "candles", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.NextOffset() != 14 {
+		t.Errorf("expect 14 but return %d", w.NextOffset())
+	}
+}
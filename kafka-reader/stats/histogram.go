@@ -0,0 +1,101 @@
+package stats
+
+import "math/bits"
+
+// Histogram counts sizes in power of two buckets: bucket 0 holds size 0 and
+// bucket i sizes in [2^(i-1), 2^i).
+type Histogram struct {
+	buckets [65]int64
+	count   int64
+	sum     int64
+	min     int64
+	max     int64
+}
+
+func (h *Histogram) Add(size int) {
+	v := int64(size)
+	h.buckets[bits.Len64(uint64(v))]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+}
+
+// Bucket is the number of sizes up to and including Le, above the previous
+// bucket.
+type Bucket struct {
+	Le    int64 `json:"le"`
+	Count int64 `json:"count"`
+}
+
+// upper returns the largest size of bucket i.
+func upper(i int) int64 {
+	if i == 0 {
+		return 0
+	}
+	return int64(uint64(1)<<uint(i) - 1)
+}
+
+// Buckets returns the non empty buckets in size order.
+func (h *Histogram) Buckets() []Bucket {
+	result := make([]Bucket, 0)
+	for i, c := range h.buckets {
+		if c > 0 {
+			result = append(result, Bucket{Le: upper(i), Count: c})
+		}
+	}
+	return result
+}
+
+// Quantile returns an upper bound of the q quantile, the largest size of the
+// bucket holding it, capped by the maximum size seen.
+func (h *Histogram) Quantile(q float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range h.buckets {
+		seen += c
+		if seen >= rank {
+			if u := upper(i); u < h.max {
+				return u
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// SizeSummary describes the sizes added to a Histogram.
+type SizeSummary struct {
+	Min     int64    `json:"min"`
+	Max     int64    `json:"max"`
+	Mean    float64  `json:"mean"`
+	P50     int64    `json:"p50"`
+	P95     int64    `json:"p95"`
+	P99     int64    `json:"p99"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+func (h *Histogram) Summary() SizeSummary {
+	s := SizeSummary{
+		Min:     h.min,
+		Max:     h.max,
+		P50:     h.Quantile(0.50),
+		P95:     h.Quantile(0.95),
+		P99:     h.Quantile(0.99),
+		Buckets: h.Buckets(),
+	}
+	if h.count > 0 {
+		s.Mean = float64(h.sum) / float64(h.count)
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits selecting a register, 2^14
+// registers give a standard error of about 0.8%.
+const hllPrecision = 14
+
+// HyperLogLog estimates the number of distinct values added to it in fixed
+// memory.
+type HyperLogLog struct {
+	registers [1 << hllPrecision]uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{}
+}
+
+// hash64 is FNV-1a followed by the splitmix64 finalizer, since FNV alone
+// does not spread short keys well enough over the high bits.
+func hash64(v []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(v)
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (h *HyperLogLog) Add(v []byte) {
+	x := hash64(v)
+	i := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+// Estimate returns the estimated number of distinct values, using linear
+// counting for small cardinalities where the raw estimate is biased.
+func (h *HyperLogLog) Estimate() uint64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,169 @@
+// Package stats accumulates the statistics of consumed messages reported by
+// the stats command: throughput, per partition lag, an estimate of the number
+// of distinct keys and the distribution of value sizes.
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Collector is safe for concurrent use, so that reports can be taken while
+// messages are added.
+type Collector struct {
+	mutex      sync.Mutex
+	start      time.Time
+	messages   int64
+	bytes      int64
+	nullKeys   int64
+	keys       *HyperLogLog
+	sizes      Histogram
+	partitions map[int]*partition
+}
+
+type partition struct {
+	messages   int64
+	bytes      int64
+	next       int64
+	lastOffset int64
+}
+
+func NewCollector(start time.Time) *Collector {
+	return &Collector{
+		start:      start,
+		keys:       NewHyperLogLog(),
+		partitions: make(map[int]*partition),
+	}
+}
+
+func (c *Collector) partition(id int) *partition {
+	p, ok := c.partitions[id]
+	if !ok {
+		p = &partition{next: -1, lastOffset: -1}
+		c.partitions[id] = p
+	}
+	return p
+}
+
+// Add counts a consumed message. Bytes count keys, values and headers.
+func (c *Collector) Add(m kafka.Message) {
+	size := len(m.Key) + len(m.Value)
+	for _, h := range m.Headers {
+		size += len(h.Key) + len(h.Value)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.messages++
+	c.bytes += int64(size)
+	if m.Key == nil {
+		c.nullKeys++
+	} else {
+		c.keys.Add(m.Key)
+	}
+	c.sizes.Add(len(m.Value))
+	p := c.partition(m.Partition)
+	p.messages++
+	p.bytes += int64(size)
+	p.next = m.Offset + 1
+}
+
+// SetPositions sets the offsets consumption starts from, so that partitions
+// report their lag before their first message.
+func (c *Collector) SetPositions(offsets map[int]int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for id, offset := range offsets {
+		if p := c.partition(id); p.next < 0 {
+			p.next = offset
+		}
+	}
+}
+
+// SetLastOffsets records the offsets of the next message to be produced to
+// each partition, the lag being the distance to them.
+func (c *Collector) SetLastOffsets(offsets map[int]int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for id, offset := range offsets {
+		c.partition(id).lastOffset = offset
+	}
+}
+
+// PartitionSummary describes a partition. Lag is -1 while it is unknown.
+type PartitionSummary struct {
+	Partition  int   `json:"partition"`
+	Messages   int64 `json:"messages"`
+	Bytes      int64 `json:"bytes"`
+	NextOffset int64 `json:"next_offset"`
+	LastOffset int64 `json:"last_offset"`
+	Lag        int64 `json:"lag"`
+}
+
+// Summary is a snapshot of a Collector.
+type Summary struct {
+	Elapsed        time.Duration      `json:"-"`
+	ElapsedSeconds float64            `json:"elapsed_seconds"`
+	Messages       int64              `json:"messages"`
+	Bytes          int64              `json:"bytes"`
+	MessagesPerSec float64            `json:"messages_per_sec"`
+	BytesPerSec    float64            `json:"bytes_per_sec"`
+	DistinctKeys   uint64             `json:"distinct_keys"`
+	NullKeys       int64              `json:"null_keys"`
+	TotalLag       int64              `json:"total_lag"`
+	Partitions     []PartitionSummary `json:"partitions"`
+	ValueSizes     SizeSummary        `json:"value_sizes"`
+}
+
+// Summary returns the statistics since the collector started, with rates
+// averaged over the whole period. See Rates for the rates of an interval.
+func (c *Collector) Summary(now time.Time) Summary {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	s := Summary{
+		Elapsed:      now.Sub(c.start),
+		Messages:     c.messages,
+		Bytes:        c.bytes,
+		DistinctKeys: c.keys.Estimate(),
+		NullKeys:     c.nullKeys,
+		Partitions:   make([]PartitionSummary, 0, len(c.partitions)),
+		ValueSizes:   c.sizes.Summary(),
+	}
+	s.ElapsedSeconds = s.Elapsed.Seconds()
+	if seconds := s.ElapsedSeconds; seconds > 0 {
+		s.MessagesPerSec = float64(s.Messages) / seconds
+		s.BytesPerSec = float64(s.Bytes) / seconds
+	}
+	for id, p := range c.partitions {
+		ps := PartitionSummary{
+			Partition:  id,
+			Messages:   p.messages,
+			Bytes:      p.bytes,
+			NextOffset: p.next,
+			LastOffset: p.lastOffset,
+			Lag:        -1,
+		}
+		if p.next >= 0 && p.lastOffset >= 0 {
+			ps.Lag = 0
+			if p.lastOffset > p.next {
+				ps.Lag = p.lastOffset - p.next
+			}
+			s.TotalLag += ps.Lag
+		}
+		s.Partitions = append(s.Partitions, ps)
+	}
+	sort.Slice(s.Partitions, func(i, j int) bool { return s.Partitions[i].Partition < s.Partitions[j].Partition })
+	return s
+}
+
+// Rates returns the messages and bytes per second between two summaries.
+func Rates(prev, cur Summary) (messagesPerSec, bytesPerSec float64) {
+	seconds := (cur.Elapsed - prev.Elapsed).Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+	return float64(cur.Messages-prev.Messages) / seconds, float64(cur.Bytes-prev.Bytes) / seconds
+}
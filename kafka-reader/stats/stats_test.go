@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 200000} {
+		h := NewHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.Add([]byte(fmt.Sprintf("key-%d", i)))
+			h.Add([]byte(fmt.Sprintf("key-%d", i)))
+		}
+		estimate := float64(h.Estimate())
+		if math.Abs(estimate-float64(n)) > float64(n)*0.03 {
+			t.Errorf("expect about %v but return %v", n, estimate)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for _, size := range []int{0, 1, 2, 3, 100, 100, 100, 100, 100, 5000} {
+		h.Add(size)
+	}
+	s := h.Summary()
+	if s.Min != 0 || s.Max != 5000 || s.Mean != 550.6 {
+		t.Errorf("expect min 0, max 5000, mean 550.6 but return %v, %v, %v", s.Min, s.Max, s.Mean)
+	}
+	if s.P50 != 127 || s.P99 != 5000 {
+		t.Errorf("expect p50 127, p99 5000 but return %v, %v", s.P50, s.P99)
+	}
+	expect := []Bucket{{0, 1}, {1, 1}, {3, 2}, {127, 5}, {8191, 1}}
+	if fmt.Sprint(s.Buckets) != fmt.Sprint(expect) {
+		t.Errorf("expect %v but return %v", expect, s.Buckets)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewCollector(start)
+	c.SetPositions(map[int]int64{0: 10, 1: 0})
+	c.SetLastOffsets(map[int]int64{0: 20, 1: 5, 2: 7})
+	c.Add(kafka.Message{Partition: 0, Offset: 10, Key: []byte("a"), Value: []byte("1234")})
+	c.Add(kafka.Message{Partition: 0, Offset: 11, Key: []byte("a"), Value: []byte("12")})
+	c.Add(kafka.Message{Partition: 1, Offset: 0, Value: []byte("123")})
+
+	s := c.Summary(start.Add(2 * time.Second))
+	if s.Messages != 3 || s.Bytes != 11 || s.MessagesPerSec != 1.5 || s.DistinctKeys != 1 || s.NullKeys != 1 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	lags := make([]int64, 0)
+	for _, p := range s.Partitions {
+		lags = append(lags, p.Lag)
+	}
+	if fmt.Sprint(lags) != "[8 4 -1]" || s.TotalLag != 12 {
+		t.Errorf("expect lags [8 4 -1] of total 12 but return %v of total %v", lags, s.TotalLag)
+	}
+
+	c.Add(kafka.Message{Partition: 1, Offset: 1, Value: []byte("1")})
+	messagesPerSec, bytesPerSec := Rates(s, c.Summary(start.Add(4*time.Second)))
+	if messagesPerSec != 0.5 || bytesPerSec != 0.5 {
+		t.Errorf("expect 0.5 msgs/s and 0.5 bytes/s but return %v, %v", messagesPerSec, bytesPerSec)
+	}
+}
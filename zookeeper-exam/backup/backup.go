@@ -0,0 +1,247 @@
+// Package backup exports a subtree of znodes, with their data, ACLs and
+// ephemeral flags, and restores it under another path or on another
+// ensemble, planning the changes first so that they can be reviewed.
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-zookeeper/zk"
+	"gopkg.in/yaml.v2"
+)
+
+// Conn is the part of *zk.Conn backups need.
+type Conn interface {
+	Get(path string) ([]byte, *zk.Stat, error)
+	GetACL(path string) ([]zk.ACL, *zk.Stat, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	SetACL(path string, acl []zk.ACL, version int32) (*zk.Stat, error)
+}
+
+// ACL is an ACL entry, with the permissions written as in zkCli, e.g. cdrwa.
+type ACL struct {
+	Scheme string `json:"scheme" yaml:"scheme"`
+	ID     string `json:"id" yaml:"id"`
+	Perms  string `json:"perms" yaml:"perms"`
+}
+
+var permLetters = []struct {
+	perm   int32
+	letter byte
+}{
+	{zk.PermCreate, 'c'},
+	{zk.PermDelete, 'd'},
+	{zk.PermRead, 'r'},
+	{zk.PermWrite, 'w'},
+	{zk.PermAdmin, 'a'},
+}
+
+func newACL(a zk.ACL) ACL {
+	perms := make([]byte, 0, len(permLetters))
+	for _, p := range permLetters {
+		if a.Perms&p.perm != 0 {
+			perms = append(perms, p.letter)
+		}
+	}
+	return ACL{Scheme: a.Scheme, ID: a.ID, Perms: string(perms)}
+}
+
+func (a ACL) zk() (zk.ACL, error) {
+	var perms int32
+	for i := 0; i < len(a.Perms); i++ {
+		found := false
+		for _, p := range permLetters {
+			if a.Perms[i] == p.letter {
+				perms |= p.perm
+				found = true
+			}
+		}
+		if !found {
+			return zk.ACL{}, fmt.Errorf("invalid permission %q in %s", a.Perms[i], a)
+		}
+	}
+	return zk.ACL{Perms: perms, Scheme: a.Scheme, ID: a.ID}, nil
+}
+
+func (a ACL) String() string {
+	return a.Scheme + ":" + a.ID + ":" + a.Perms
+}
+
+func aclString(acl []ACL) string {
+	s := make([]string, 0, len(acl))
+	for _, a := range acl {
+		s = append(s, a.String())
+	}
+	return strings.Join(s, ",")
+}
+
+// Node is a znode of a dump. Data that is not valid UTF-8 is written base64
+// encoded in DataBase64 instead of Data.
+type Node struct {
+	// Path is relative to the root of the dump, / being the root itself.
+	Path       string  `json:"path" yaml:"path"`
+	Data       *string `json:"data,omitempty" yaml:"data,omitempty"`
+	DataBase64 string  `json:"data_base64,omitempty" yaml:"data_base64,omitempty"`
+	ACL        []ACL   `json:"acl" yaml:"acl"`
+	// Ephemeral notes that the znode was ephemeral, which is not restored
+	// by default as it would be deleted with the session restoring it.
+	Ephemeral bool `json:"ephemeral,omitempty" yaml:"ephemeral,omitempty"`
+}
+
+func (n *Node) setData(data []byte) {
+	if data == nil {
+		return
+	}
+	if !utf8.Valid(data) {
+		n.DataBase64 = base64.StdEncoding.EncodeToString(data)
+		return
+	}
+	s := string(data)
+	n.Data = &s
+}
+
+// Bytes returns the data of the znode.
+func (n Node) Bytes() ([]byte, error) {
+	if n.Data != nil {
+		return []byte(*n.Data), nil
+	}
+	if n.DataBase64 != "" {
+		b, err := base64.StdEncoding.DecodeString(n.DataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data of %s: %w", n.Path, err)
+		}
+		return b, nil
+	}
+	return nil, nil
+}
+
+func (n Node) acl() ([]zk.ACL, error) {
+	acl := make([]zk.ACL, 0, len(n.ACL))
+	for _, a := range n.ACL {
+		z, err := a.zk()
+		if err != nil {
+			return nil, fmt.Errorf("invalid acl of %s: %w", n.Path, err)
+		}
+		acl = append(acl, z)
+	}
+	if len(acl) == 0 {
+		acl = zk.WorldACL(zk.PermAll)
+	}
+	return acl, nil
+}
+
+// Dump is a subtree of znodes, parents before their children.
+type Dump struct {
+	Root    string    `json:"root" yaml:"root"`
+	Created time.Time `json:"created" yaml:"created"`
+	Nodes   []Node    `json:"nodes" yaml:"nodes"`
+}
+
+// Take dumps the subtree under root. Znodes deleted while dumping are left
+// out.
+func Take(conn Conn, root string) (*Dump, error) {
+	root = path.Clean(root)
+	d := &Dump{Root: root, Created: time.Now().UTC()}
+	if err := d.add(conn, root); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func relative(root, p string) string {
+	if p == root {
+		return "/"
+	}
+	if root == "/" {
+		return p
+	}
+	return strings.TrimPrefix(p, root)
+}
+
+func (d *Dump) add(conn Conn, p string) error {
+	data, stat, err := conn.Get(p)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", p, err)
+	}
+	acl, _, err := conn.GetACL(p)
+	if err != nil {
+		return fmt.Errorf("failed to get acl of %s: %w", p, err)
+	}
+	n := Node{Path: relative(d.Root, p), Ephemeral: stat.EphemeralOwner != 0}
+	n.setData(data)
+	for _, a := range acl {
+		n.ACL = append(n.ACL, newACL(a))
+	}
+	d.Nodes = append(d.Nodes, n)
+
+	children, _, err := conn.Children(p)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", p, err)
+	}
+	sort.Strings(children)
+	for _, c := range children {
+		if err := d.add(conn, path.Join(p, c)); err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Formats are the formats dumps are written in.
+var Formats = []string{"json", "yaml"}
+
+// FormatOf returns the format of a file by its extension, json by default.
+func FormatOf(file string) string {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
+
+// Write writes the dump in format.
+func (d *Dump) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case "yaml":
+		return yaml.NewEncoder(w).Encode(d)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Read reads a dump written in format.
+func Read(r io.Reader, format string) (*Dump, error) {
+	var d Dump
+	var err error
+	switch format {
+	case "json":
+		err = json.NewDecoder(r).Decode(&d)
+	case "yaml":
+		err = yaml.NewDecoder(r).Decode(&d)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid dump: %w", err)
+	}
+	for _, n := range d.Nodes {
+		if !strings.HasPrefix(n.Path, "/") || path.Clean(n.Path) != n.Path {
+			return nil, fmt.Errorf("invalid dump: invalid path %q", n.Path)
+		}
+	}
+	return &d, nil
+}
@@ -0,0 +1,193 @@
+package backup
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"zookeeper-exam/zktest"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// appACL restricts app to the digest identity the test sessions add.
+var appACL = zk.DigestACL(zk.PermRead|zk.PermWrite|zk.PermAdmin, "user", "secret")
+
+func connect(server *zktest.Server) *zktest.Conn {
+	conn := server.Connect()
+	conn.AddAuth("digest", []byte("user:secret"))
+	return conn
+}
+
+func newSource(t *testing.T, conn *zktest.Conn) {
+	t.Helper()
+	nodes := []struct {
+		path  string
+		data  string
+		flags int32
+		acl   []zk.ACL
+	}{
+		{"/jobs", "", 0, nil},
+		{"/jobs/meta", "m", 0, nil},
+		{"/jobs/meta/app", "{\"v\": 1}", 0, appACL},
+		{"/jobs/meta/bin", "\xff\x00", 0, nil},
+		{"/jobs/meta/owner", "host-1", zk.FlagEphemeral, nil},
+	}
+	for _, n := range nodes {
+		acl := n.acl
+		if acl == nil {
+			acl = zk.WorldACL(zk.PermAll)
+		}
+		if _, err := conn.Create(n.path, []byte(n.data), n.flags, acl); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func actions(steps []Step) map[string]Action {
+	result := make(map[string]Action, len(steps))
+	for _, s := range steps {
+		result[s.Path] = s.Action
+	}
+	return result
+}
+
+func TestTakeAndFormats(t *testing.T) {
+	conn := connect(zktest.NewServer())
+	newSource(t, conn)
+	d, err := Take(conn, "/jobs/meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, 0)
+	for _, n := range d.Nodes {
+		paths = append(paths, n.Path)
+	}
+	if expect := []string{"/", "/app", "/bin", "/owner"}; !reflect.DeepEqual(paths, expect) {
+		t.Errorf("expect %v but return %v", expect, paths)
+	}
+	if n, expect := d.Nodes[1], "digest:"+appACL[0].ID+":rwa"; aclString(n.ACL) != expect {
+		t.Errorf("expect %s but return %s", expect, aclString(n.ACL))
+	}
+	if b, _ := d.Nodes[2].Bytes(); string(b) != "\xff\x00" || d.Nodes[2].Data != nil {
+		t.Errorf("expect binary data base64 encoded but return %+v", d.Nodes[2])
+	}
+	if !d.Nodes[3].Ephemeral || d.Nodes[1].Ephemeral {
+		t.Errorf("expect only owner to be ephemeral")
+	}
+
+	for _, format := range Formats {
+		var b bytes.Buffer
+		if err := d.Write(&b, format); err != nil {
+			t.Fatal(err)
+		}
+		read, err := Read(&b, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read.Nodes, d.Nodes) || read.Root != d.Root || !read.Created.Equal(d.Created) {
+			t.Errorf("expect %+v but return %+v in %s", d, read, format)
+		}
+	}
+	if FormatOf("dump.YML") != "yaml" || FormatOf("dump.json") != "json" {
+		t.Errorf("expect formats by extension")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	server := zktest.NewServer()
+	conn := connect(server)
+	newSource(t, conn)
+	d, err := Take(conn, "/jobs/meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := connect(server)
+	steps, err := Restore(target, d, "/env/dev/meta", RestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]Action{
+		"/env":                Create,
+		"/env/dev":            Create,
+		"/env/dev/meta":       Create,
+		"/env/dev/meta/app":   Create,
+		"/env/dev/meta/bin":   Create,
+		"/env/dev/meta/owner": Skip,
+	}
+	if a := actions(steps); !reflect.DeepEqual(a, expect) {
+		t.Errorf("expect %v but return %v", expect, a)
+	}
+	if ok, _, _ := target.Exists("/env"); ok {
+		t.Errorf("expect a dry run to change nothing")
+	}
+
+	if _, err := Restore(target, d, "/env/dev/meta", RestoreOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Take(target, "/env/dev/meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Nodes, d.Nodes[:3]) {
+		t.Errorf("expect %+v but return %+v", d.Nodes[:3], restored.Nodes)
+	}
+
+	target.Set("/env/dev/meta/app", []byte("changed"), -1)
+	target.Create("/env/dev/meta/local", nil, 0, zk.WorldACL(zk.PermAll))
+	steps, err = Restore(target, d, "/env/dev/meta", RestoreOptions{Ephemeral: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = map[string]Action{
+		"/env/dev/meta":       Unchanged,
+		"/env/dev/meta/app":   Update,
+		"/env/dev/meta/bin":   Unchanged,
+		"/env/dev/meta/owner": Create,
+		"/env/dev/meta/local": Extra,
+	}
+	if a := actions(steps); !reflect.DeepEqual(a, expect) {
+		t.Errorf("expect %v but return %v", expect, a)
+	}
+	if data, _, _ := target.Get("/env/dev/meta/app"); string(data) != "{\"v\": 1}" {
+		t.Errorf("expect the data of app restored but return %s", data)
+	}
+	if _, stat, _ := target.Exists("/env/dev/meta/owner"); stat.EphemeralOwner != 0 {
+		t.Errorf("expect owner restored as persistent")
+	}
+}
+
+func TestRestoreRestrictedACL(t *testing.T) {
+	server := zktest.NewServer()
+	conn := server.Connect()
+	for _, p := range []string{"/src", "/src/locked", "/src/locked/child", "/src/locked/child/leaf"} {
+		if _, err := conn.Create(p, []byte(p), 0, zk.WorldACL(zk.PermAll)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// locked and child can be read but neither given children nor changed
+	for _, p := range []string{"/src/locked/child", "/src/locked"} {
+		if _, err := conn.SetACL(p, zk.WorldACL(zk.PermRead), -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := Take(conn, "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Restore(conn, d, "/dst", RestoreOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Take(conn, "/dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Nodes, d.Nodes) {
+		t.Errorf("expect %+v but return %+v", d.Nodes, restored.Nodes)
+	}
+	if _, err := conn.Create("/dst/locked/other", nil, 0, zk.WorldACL(zk.PermAll)); err != zk.ErrNoAuth {
+		t.Errorf("expect the acl of locked restored but return %v", err)
+	}
+}
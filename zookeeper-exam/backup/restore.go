@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// Action is what restoring does to a znode.
+type Action string
+
+const (
+	// Create creates a missing znode.
+	Create Action = "create"
+	// Update sets the data or the ACL of an existing znode.
+	Update Action = "update"
+	// Unchanged leaves a znode matching the dump as is.
+	Unchanged Action = "unchanged"
+	// Skip leaves out a znode that was ephemeral.
+	Skip Action = "skip"
+	// Extra notes a znode under the target that is not in the dump. It is
+	// left as is.
+	Extra Action = "extra"
+)
+
+// Step is the change restoring makes to a znode. Old and New describe the
+// data and ACL before and after, when they differ.
+type Step struct {
+	Path   string `json:"path"`
+	Action Action `json:"action"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+
+	data []byte
+	acl  []zk.ACL
+	// setData and setACL tell what an update changes
+	setData bool
+	setACL  bool
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// DryRun only plans the steps.
+	DryRun bool
+	// Ephemeral restores the znodes that were ephemeral, as persistent
+	// znodes.
+	Ephemeral bool
+}
+
+// describe summarizes data and ACL for a step.
+func describe(data []byte, acl string) string {
+	var n Node
+	n.setData(data)
+	s := "data=<empty>"
+	switch {
+	case n.Data != nil:
+		s = fmt.Sprintf("data=%q", *n.Data)
+	case n.DataBase64 != "":
+		s = "data=base64:" + n.DataBase64
+	}
+	return s + " acl=" + acl
+}
+
+// Plan returns the steps restoring d under target: missing ancestors of
+// target first, then the znodes of the dump, then the extra znodes.
+func Plan(conn Conn, d *Dump, target string, opts RestoreOptions) ([]Step, error) {
+	target = path.Clean(target)
+	steps := make([]Step, 0, len(d.Nodes))
+	parts := strings.Split(strings.Trim(target, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		p := "/" + strings.Join(parts[:i+1], "/")
+		if _, _, err := conn.Get(p); errors.Is(err, zk.ErrNoNode) {
+			steps = append(steps, Step{Path: p, Action: Create, New: describe(nil, aclString([]ACL{newACL(openACL[0])})), acl: openACL})
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", p, err)
+		}
+	}
+
+	restored := make(map[string]bool, len(d.Nodes))
+	for _, n := range d.Nodes {
+		p := path.Join(target, n.Path)
+		restored[p] = true
+		step := Step{Path: p}
+		if n.Ephemeral && !opts.Ephemeral {
+			step.Action = Skip
+			steps = append(steps, step)
+			continue
+		}
+		data, err := n.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		acl, err := n.acl()
+		if err != nil {
+			return nil, err
+		}
+		step.data, step.acl = data, acl
+		acls := make([]ACL, 0, len(acl))
+		for _, a := range acl {
+			acls = append(acls, newACL(a))
+		}
+		step.New = describe(data, aclString(acls))
+
+		old, _, err := conn.Get(p)
+		if errors.Is(err, zk.ErrNoNode) {
+			step.Action = Create
+			steps = append(steps, step)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", p, err)
+		}
+		oldACL, _, err := conn.GetACL(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get acl of %s: %w", p, err)
+		}
+		oldACLs := make([]ACL, 0, len(oldACL))
+		for _, a := range oldACL {
+			oldACLs = append(oldACLs, newACL(a))
+		}
+		step.setData = string(old) != string(data)
+		step.setACL = aclString(oldACLs) != aclString(acls)
+		if !step.setData && !step.setACL {
+			step.Action, step.New = Unchanged, ""
+			steps = append(steps, step)
+			continue
+		}
+		step.Action, step.Old = Update, describe(old, aclString(oldACLs))
+		steps = append(steps, step)
+	}
+
+	extra, err := extraNodes(conn, target, restored)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range extra {
+		steps = append(steps, Step{Path: p, Action: Extra})
+	}
+	return steps, nil
+}
+
+// extraNodes returns the znodes under target that are not restored.
+func extraNodes(conn Conn, target string, restored map[string]bool) ([]string, error) {
+	extra := make([]string, 0)
+	var walk func(p string) error
+	walk = func(p string) error {
+		children, _, err := conn.Children(p)
+		if errors.Is(err, zk.ErrNoNode) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", p, err)
+		}
+		sort.Strings(children)
+		for _, c := range children {
+			child := path.Join(p, c)
+			if !restored[child] {
+				extra = append(extra, child)
+			}
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(target); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// openACL is the ACL znodes are created with before their dumped ACL is
+// set, so that a restrictive ACL does not keep their children from being
+// created.
+var openACL = zk.WorldACL(zk.PermAll)
+
+// Restore plans restoring d under target and, unless opts.DryRun, applies
+// the plan. It returns the steps, up to the failing one on error.
+//
+// Znodes are created and updated first, created ones with an open ACL,
+// then the dumped ACLs are set from the deepest znodes up.
+func Restore(conn Conn, d *Dump, target string, opts RestoreOptions) ([]Step, error) {
+	steps, err := Plan(conn, d, target, opts)
+	if err != nil || opts.DryRun {
+		return steps, err
+	}
+	acls := make([]Step, 0)
+	for i, s := range steps {
+		if err := apply(conn, s); err != nil {
+			return steps[:i+1], err
+		}
+		if s.Action == Create && !sameACL(s.acl, openACL) || s.Action == Update && s.setACL {
+			acls = append(acls, s)
+		}
+	}
+	sort.SliceStable(acls, func(i, j int) bool {
+		return strings.Count(acls[i].Path, "/") > strings.Count(acls[j].Path, "/")
+	})
+	for _, s := range acls {
+		if _, err := conn.SetACL(s.Path, s.acl, -1); err != nil {
+			return steps, fmt.Errorf("failed to set acl of %s: %w", s.Path, err)
+		}
+	}
+	return steps, nil
+}
+
+func sameACL(a, b []zk.ACL) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func apply(conn Conn, s Step) error {
+	switch s.Action {
+	case Create:
+		if _, err := conn.Create(s.Path, s.data, 0, openACL); err != nil {
+			return fmt.Errorf("failed to create %s: %w", s.Path, err)
+		}
+	case Update:
+		if s.setData {
+			if _, err := conn.Set(s.Path, s.data, -1); err != nil {
+				return fmt.Errorf("failed to set %s: %w", s.Path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"zookeeper-exam/backup"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupFile   string
+	backupFormat string
+
+	restoreTarget    string
+	restoreDryRun    bool
+	restoreEphemeral bool
+
+	dumpCmd = &cobra.Command{
+		Use:   "dump PATH",
+		Short: "export the znodes under PATH, with their data and ACLs, to a JSON or YAML file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDump(cmd.Context(), args[0])
+		},
+	}
+
+	restoreCmd = &cobra.Command{
+		Use:   "restore FILE",
+		Short: "restore the znodes of a dump, under their original path or --to",
+		Long: `Restore the znodes of a dump, creating missing znodes and updating the data
+and ACLs of existing ones. Znodes that are not in the dump are reported as
+extra and left as is. Znodes that were ephemeral are skipped unless
+--ephemeral restores them as persistent znodes.
+
+With --dry-run, only print the changes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRestore(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	dumpCmd.Flags().StringVarP(&backupFile, "file", "f", "", "file to write (default stdout)")
+	dumpCmd.Flags().StringVar(&backupFormat, "format", "", "format: "+strings.Join(backup.Formats, " or ")+" (default by the extension of the file, json otherwise)")
+
+	restoreCmd.Flags().StringVar(&backupFormat, "format", "", "format: "+strings.Join(backup.Formats, " or ")+" (default by the extension of the file)")
+	restoreCmd.Flags().StringVar(&restoreTarget, "to", "", "path to restore the root of the dump at (default the root of the dump)")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "only print the changes")
+	restoreCmd.Flags().BoolVar(&restoreEphemeral, "ephemeral", false, "restore the znodes that were ephemeral as persistent znodes")
+
+	rootCmd.AddCommand(dumpCmd, restoreCmd)
+}
+
+func runDump(ctx context.Context, root string) error {
+	format := backupFormat
+	if format == "" {
+		format = backup.FormatOf(backupFile)
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	d, err := backup.Take(conn, root)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if backupFile != "" {
+		f, err := os.Create(backupFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := d.Write(w, format); err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+	log.Printf("dumped %d znodes under %s\n", len(d.Nodes), root)
+	return nil
+}
+
+func runRestore(ctx context.Context, file string) error {
+	format := backupFormat
+	if format == "" {
+		format = backup.FormatOf(file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	d, err := backup.Read(f, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	target := restoreTarget
+	if target == "" {
+		target = d.Root
+	}
+
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	steps, restoreErr := backup.Restore(conn, d, target, backup.RestoreOptions{DryRun: restoreDryRun, Ephemeral: restoreEphemeral})
+
+	counts := make(map[backup.Action]int)
+	rows := make([][]string, 0, len(steps))
+	for _, s := range steps {
+		counts[s.Action]++
+		switch s.Action {
+		case backup.Unchanged:
+			continue
+		case backup.Update:
+			rows = append(rows, []string{string(s.Action), s.Path, s.Old + " -> " + s.New})
+		default:
+			rows = append(rows, []string{string(s.Action), s.Path, s.New})
+		}
+	}
+	if err := render(steps, []string{"ACTION", "PATH", "CHANGE"}, rows); err != nil {
+		return err
+	}
+	verb := "restored"
+	if restoreDryRun {
+		verb = "would restore"
+	}
+	log.Printf("%s %s to %s: %d created, %d updated, %d unchanged, %d skipped, %d extra\n",
+		verb, file, target, counts[backup.Create], counts[backup.Update], counts[backup.Unchanged], counts[backup.Skip], counts[backup.Extra])
+	return restoreErr
+}
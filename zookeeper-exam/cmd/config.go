@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"zookeeper-exam/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configWatch bool
+
+	configCmd = &cobra.Command{
+		Use:   "config ROOT",
+		Short: "print the znodes under ROOT as configuration keys and values",
+		Long: `Print the znodes under ROOT as configuration keys, their path relative to
+ROOT, and values. With --watch, then print each change until interrupted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfig(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	configCmd.Flags().BoolVarP(&configWatch, "watch", "w", false, "print changes until interrupted")
+
+	rootCmd.AddCommand(configCmd)
+}
+
+type configChange struct {
+	Time    time.Time `json:"time"`
+	Key     string    `json:"key"`
+	Old     *string   `json:"old"`
+	New     *string   `json:"new"`
+	Deleted bool      `json:"deleted,omitempty"`
+}
+
+func optionalString(v config.Value) *string {
+	if v == nil {
+		return nil
+	}
+	s := v.String()
+	return &s
+}
+
+func printChange(c config.Change, at time.Time) {
+	change := configChange{Time: at, Key: c.Key, Old: optionalString(c.Old), New: optionalString(c.New), Deleted: c.Deleted}
+	if output == "json" {
+		printLine(change)
+		return
+	}
+	switch {
+	case c.Deleted:
+		fmt.Printf("%s deleted %s\n", change.Time.Format(time.RFC3339), c.Key)
+	case c.Old == nil:
+		fmt.Printf("%s created %s = %s\n", change.Time.Format(time.RFC3339), c.Key, c.New)
+	default:
+		fmt.Printf("%s changed %s = %s (was %s)\n", change.Time.Format(time.RFC3339), c.Key, c.New, c.Old)
+	}
+}
+
+func runConfig(ctx context.Context, root string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	storeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	store := config.NewStore(conn, root)
+	if err := store.Start(storeCtx); err != nil {
+		return err
+	}
+
+	// changes are buffered from before the snapshot until it is printed, so
+	// that none is missed in between, at the cost of printing those already
+	// in the snapshot again
+	type timedChange struct {
+		change config.Change
+		at     time.Time
+	}
+	changes := make(chan timedChange, 64)
+	if configWatch {
+		unsubscribe := store.Subscribe("", func(c config.Change) {
+			select {
+			case changes <- timedChange{c, time.Now()}:
+			case <-storeCtx.Done():
+			}
+		})
+		defer unsubscribe()
+	}
+
+	snapshot := store.Snapshot()
+	keys := make([]string, 0, len(snapshot))
+	values := make(map[string]string, len(snapshot))
+	for k, v := range snapshot {
+		keys = append(keys, k)
+		values[k] = v.String()
+	}
+	sort.Strings(keys)
+	rows := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, []string{k, values[k]})
+	}
+	if err := render(values, []string{"KEY", "VALUE"}, rows); err != nil {
+		return err
+	}
+	if !configWatch {
+		return nil
+	}
+	for {
+		select {
+		case c := <-changes:
+			printChange(c.change, c.at)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
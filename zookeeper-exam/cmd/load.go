@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	"zookeeper-exam/load"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/spf13/cobra"
+)
+
+// defaultLoadDuration is the duration of a load given neither --duration nor
+// --ops.
+const defaultLoadDuration = 30 * time.Second
+
+var (
+	loadOptions load.Options
+	loadPaths   []string
+	loadRoot    string
+	loadKeys    int
+	loadCleanup bool
+
+	loadCmd = &cobra.Command{
+		Use:   "load",
+		Short: "generate reads and writes and report throughput, errors and latencies",
+		Long: `Generate a mix of reads and writes against znodes and report throughput,
+errors by kind and latency percentiles.
+
+Unless --paths is given, --keys znodes are created under --root first, and
+with --cleanup those created by the run are deleted afterwards, existing
+znodes being kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("duration") && loadOptions.Ops <= 0 {
+				loadOptions.Duration = defaultLoadDuration
+			}
+			return runLoad(cmd.Context())
+		},
+	}
+)
+
+func init() {
+	loadCmd.Flags().IntVarP(&loadOptions.Concurrency, "concurrency", "c", 16, "number of operations in flight")
+	loadCmd.Flags().DurationVarP(&loadOptions.Duration, "duration", "d", 0, "stop after this long (default "+defaultLoadDuration.String()+" without --ops, 0 means no limit)")
+	loadCmd.Flags().Int64VarP(&loadOptions.Ops, "ops", "n", 0, "stop after this many operations (0 means no limit)")
+	loadCmd.Flags().Float64Var(&loadOptions.QPS, "qps", 0, "target operations per second over all workers (0 means as fast as possible)")
+	loadCmd.Flags().Float64VarP(&loadOptions.WriteRatio, "write-ratio", "w", 0.1, "fraction of operations that are writes")
+	loadCmd.Flags().IntVar(&loadOptions.ValueSize, "value-size", 64, "size of the data written, in bytes")
+	loadCmd.Flags().DurationVar(&loadOptions.ProgressInterval, "progress-interval", 5*time.Second, "log progress this often (0 means never)")
+	loadCmd.Flags().StringSliceVar(&loadPaths, "paths", []string{}, "existing znodes to read and write, instead of creating them")
+	loadCmd.Flags().StringVar(&loadRoot, "root", "/zookeeper-exam-load", "parent of the znodes created for the load")
+	loadCmd.Flags().IntVar(&loadKeys, "keys", 100, "number of znodes created for the load")
+	loadCmd.Flags().BoolVar(&loadCleanup, "cleanup", false, "delete the znodes created for the load afterwards")
+
+	rootCmd.AddCommand(loadCmd)
+}
+
+// nodeCreator is the part of *zk.Conn creating and cleaning up the znodes
+// of a load needs.
+type nodeCreator interface {
+	nodeDeleter
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+}
+
+// createLoadPaths creates the znodes of the load, keeping existing ones. It
+// returns the znodes of the load and, in creation order, those created by
+// this run, including --root and its parents.
+func createLoadPaths(c nodeCreator) (paths, created []string, err error) {
+	create := func(p string) error {
+		_, err := c.Create(p, nil, 0, zk.WorldACL(zk.PermAll))
+		switch {
+		case err == nil:
+			created = append(created, p)
+		case !errors.Is(err, zk.ErrNodeExists):
+			return fmt.Errorf("failed to create %s: %w", p, err)
+		}
+		return nil
+	}
+	for _, p := range parentPaths(path.Join(loadRoot, "key")) {
+		if err := create(p); err != nil {
+			return nil, created, err
+		}
+	}
+	paths = make([]string, 0, loadKeys)
+	for i := 0; i < loadKeys; i++ {
+		p := path.Join(loadRoot, fmt.Sprintf("key-%06d", i))
+		if err := create(p); err != nil {
+			return nil, created, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, created, nil
+}
+
+// cleanupLoadPaths deletes the znodes created by createLoadPaths, children
+// first. Parents that were given other children since are kept.
+func cleanupLoadPaths(d nodeDeleter, created []string) error {
+	for i := len(created) - 1; i >= 0; i-- {
+		err := d.Delete(created[i], -1)
+		switch {
+		case err == nil, errors.Is(err, zk.ErrNoNode):
+		case errors.Is(err, zk.ErrNotEmpty):
+			log.Printf("keeping %s, which is not empty\n", created[i])
+		default:
+			return fmt.Errorf("failed to delete %s: %w", created[i], err)
+		}
+	}
+	return nil
+}
+
+func runLoad(ctx context.Context) (err error) {
+	if loadOptions.Duration <= 0 && loadOptions.Ops <= 0 {
+		return errors.New("either --duration or --ops is required")
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	opts := loadOptions
+	opts.Paths = loadPaths
+	opts.Seed = time.Now().UnixNano()
+	if len(opts.Paths) == 0 {
+		if loadKeys <= 0 {
+			return errors.New("--keys must be positive")
+		}
+		log.Printf("creating %d znodes under %s\n", loadKeys, loadRoot)
+		var created []string
+		opts.Paths, created, err = createLoadPaths(conn)
+		if loadCleanup {
+			// cleanup runs after an interrupt or a failed creation too
+			defer func() {
+				if cerr := cleanupLoadPaths(conn, created); cerr != nil && err == nil {
+					err = cerr
+				}
+			}()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var last int64
+	lastAt := time.Now()
+	opts.Progress = func(done, errors int64) {
+		now := time.Now()
+		log.Printf("ops: %d, errors: %d, ops/sec: %.1f\n", done, errors, float64(done-last)/now.Sub(lastAt).Seconds())
+		last, lastAt = done, now
+	}
+
+	log.Printf("running with %d workers\n", opts.Concurrency)
+	report, err := load.Run(ctx, conn, opts)
+	if err != nil {
+		return err
+	}
+	return renderLoad(report)
+}
+
+func micros(v int64) string {
+	return (time.Duration(v) * time.Microsecond).String()
+}
+
+func renderLoad(r load.Report) error {
+	if output == "json" {
+		return render(r, nil, nil)
+	}
+	overview := [][]string{
+		{"elapsed", r.Elapsed.Round(time.Millisecond).String()},
+		{"ops", strconv.FormatInt(r.Ops, 10)},
+		{"reads", strconv.FormatInt(r.Reads, 10)},
+		{"writes", strconv.FormatInt(r.Writes, 10)},
+		{"ops/sec", strconv.FormatFloat(r.OpsPerSec, 'f', 1, 64)},
+		{"errors", strconv.FormatInt(r.ErrorCount, 10)},
+	}
+	if err := render(r, []string{"STAT", "VALUE"}, overview); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	latencies := make([][]string, 0, 3)
+	for _, l := range []struct {
+		name string
+		s    load.LatencySummary
+	}{{"read", r.Read}, {"write", r.Write}, {"all", r.All}} {
+		latencies = append(latencies, []string{
+			l.name,
+			strconv.FormatInt(l.s.Count, 10),
+			micros(l.s.P50),
+			micros(l.s.P95),
+			micros(l.s.P99),
+			micros(l.s.Max),
+			micros(int64(l.s.Mean)),
+		})
+	}
+	if err := render(r, []string{"OP", "COUNT", "P50", "P95", "P99", "MAX", "MEAN"}, latencies); err != nil {
+		return err
+	}
+
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	fmt.Println()
+	errs := make([][]string, 0, len(r.Errors))
+	for _, k := range r.ErrorKinds() {
+		errs = append(errs, []string{k, strconv.FormatInt(r.Errors[k], 10)})
+	}
+	return render(r, []string{"ERROR", "COUNT"}, errs)
+}
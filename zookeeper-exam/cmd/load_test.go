@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-zookeeper/zk"
+)
+
+func (f *fakeNodes) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	if _, ok := f.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	if _, ok := f.nodes[path.Dir(p)]; !ok {
+		return "", zk.ErrNoNode
+	}
+	f.nodes[p] = string(data)
+	return p, nil
+}
+
+func sortedNodes(f *fakeNodes) []string {
+	nodes := make([]string, 0, len(f.nodes))
+	for n := range f.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func TestLoadPathsCleanup(t *testing.T) {
+	defer func(root string, keys int) { loadRoot, loadKeys = root, keys }(loadRoot, loadKeys)
+	loadKeys = 2
+
+	tests := []struct {
+		root    string
+		created []string
+	}{
+		// the existing key and the other children of /app are kept
+		{"/app", []string{"/app/key-000001"}},
+		{"/load/run", []string{"/load", "/load/run", "/load/run/key-000000", "/load/run/key-000001"}},
+	}
+	for _, test := range tests {
+		f := newFakeNodes()
+		f.nodes["/app/key-000000"] = "kept"
+		before := sortedNodes(f)
+
+		loadRoot = test.root
+		paths, created, err := createLoadPaths(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect := []string{test.root + "/key-000000", test.root + "/key-000001"}; !reflect.DeepEqual(paths, expect) {
+			t.Errorf("expect %v but return %v", expect, paths)
+		}
+		if !reflect.DeepEqual(created, test.created) {
+			t.Errorf("expect %v but return %v", test.created, created)
+		}
+		if err := cleanupLoadPaths(f, created); err != nil {
+			t.Fatal(err)
+		}
+		if after := sortedNodes(f); !reflect.DeepEqual(after, before) {
+			t.Errorf("expect %v but return %v", before, after)
+		}
+	}
+
+	// a root given other children during the load is kept
+	f := newFakeNodes()
+	loadRoot = "/load"
+	_, created, _ := createLoadPaths(f)
+	f.nodes["/load/other"] = ""
+	if err := cleanupLoadPaths(f, created); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.nodes["/load/other"]; !ok {
+		t.Errorf("expect /load/other kept")
+	}
+	if _, ok := f.nodes["/load/key-000000"]; ok {
+		t.Errorf("expect /load/key-000000 deleted")
+	}
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	getStat bool
+
+	setVersion int32
+
+	createEphemeral  bool
+	createSequential bool
+	createParents    bool
+
+	deleteRecursive bool
+	deleteVersion   int32
+
+	lsCmd = &cobra.Command{
+		Use:   "ls PATH",
+		Short: "list the children of a znode",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLs(cmd.Context(), args[0])
+		},
+	}
+
+	getCmd = &cobra.Command{
+		Use:   "get PATH",
+		Short: "print the data of a znode",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGet(cmd.Context(), args[0])
+		},
+	}
+
+	setCmd = &cobra.Command{
+		Use:   "set PATH DATA",
+		Short: "write the data of a znode",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSet(cmd.Context(), args[0], []byte(args[1]))
+		},
+	}
+
+	createCmd = &cobra.Command{
+		Use:   "create PATH [DATA]",
+		Short: "create a znode",
+		Long: `Create a znode, persistent by default.
+
+An ephemeral znode only lives as long as the session creating it, so with
+--ephemeral the command keeps its session open until interrupted.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			if len(args) > 1 {
+				data = []byte(args[1])
+			}
+			return runCreate(cmd.Context(), args[0], data)
+		},
+	}
+
+	deleteCmd = &cobra.Command{
+		Use:   "delete PATH",
+		Short: "delete a znode, and with -r all its descendants",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDelete(cmd.Context(), args[0])
+		},
+	}
+
+	statCmd = &cobra.Command{
+		Use:   "stat PATH",
+		Short: "print the stat of a znode",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStat(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	getCmd.Flags().BoolVar(&getStat, "stat", false, "also print the stat of the znode")
+
+	setCmd.Flags().Int32Var(&setVersion, "version", -1, "only write if the znode is at this version (-1 means any version)")
+
+	createCmd.Flags().BoolVarP(&createEphemeral, "ephemeral", "e", false, "create an ephemeral znode")
+	createCmd.Flags().BoolVar(&createSequential, "sequential", false, "append a sequence number to the name of the znode")
+	createCmd.Flags().BoolVarP(&createParents, "parents", "p", false, "create missing parents as empty persistent znodes")
+
+	deleteCmd.Flags().BoolVarP(&deleteRecursive, "recursive", "r", false, "delete all descendants first, refused for / and /zookeeper")
+	deleteCmd.Flags().Int32Var(&deleteVersion, "version", -1, "only delete if the znode is at this version (-1 means any version)")
+
+	rootCmd.AddCommand(lsCmd, getCmd, setCmd, createCmd, deleteCmd, statCmd)
+}
+
+type lsResult struct {
+	Path     string   `json:"path"`
+	Children []string `json:"children"`
+}
+
+func runLs(ctx context.Context, path string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	children, _, err := conn.Children(path)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", path, err)
+	}
+	sort.Strings(children)
+	rows := make([][]string, 0, len(children))
+	for _, c := range children {
+		rows = append(rows, []string{c})
+	}
+	return render(lsResult{Path: path, Children: children}, nil, rows)
+}
+
+type getResult struct {
+	Path string `json:"path"`
+	nodeData
+	Stat *nodeStat `json:"stat,omitempty"`
+}
+
+func runGet(ctx context.Context, path string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	data, stat, err := conn.Get(path)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", path, err)
+	}
+	result := getResult{Path: path, nodeData: newNodeData(data)}
+	if getStat {
+		result.Stat = newNodeStat(stat)
+	}
+	if output == "json" {
+		return render(result, nil, nil)
+	}
+	if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if getStat {
+		return render(nil, nil, result.Stat.rows())
+	}
+	return nil
+}
+
+type statResult struct {
+	Path string    `json:"path"`
+	Stat *nodeStat `json:"stat"`
+}
+
+func renderStat(path string, stat *zk.Stat) error {
+	s := newNodeStat(stat)
+	return render(statResult{Path: path, Stat: s}, nil, s.rows())
+}
+
+func runSet(ctx context.Context, path string, data []byte) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stat, err := conn.Set(path, data, setVersion)
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %w", path, err)
+	}
+	return renderStat(path, stat)
+}
+
+func runStat(ctx context.Context, path string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	exists, stat, err := conn.Exists(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !exists {
+		return fmt.Errorf("failed to stat %s: %w", path, zk.ErrNoNode)
+	}
+	return renderStat(path, stat)
+}
+
+// createParentNodes creates the missing ancestors of path.
+func createParentNodes(conn *zk.Conn, path string) error {
+	for _, p := range parentPaths(path) {
+		if _, err := conn.Create(p, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return fmt.Errorf("failed to create %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+type createResult struct {
+	Path string `json:"path"`
+}
+
+func runCreate(ctx context.Context, path string, data []byte) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if createParents {
+		if err := createParentNodes(conn, path); err != nil {
+			return err
+		}
+	}
+	var flags int32
+	if createEphemeral {
+		flags |= zk.FlagEphemeral
+	}
+	if createSequential {
+		flags |= zk.FlagSequence
+	}
+	created, err := conn.Create(path, data, flags, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := render(createResult{Path: created}, nil, [][]string{{created}}); err != nil {
+		return err
+	}
+	if createEphemeral {
+		<-ctx.Done()
+	}
+	return nil
+}
+
+func runDelete(ctx context.Context, path string) error {
+	if deleteRecursive {
+		if err := checkDeleteTree(path); err != nil {
+			return err
+		}
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deleteRecursive {
+		return deleteTree(ctx, conn, path, deleteVersion)
+	}
+	if err := conn.Delete(path, deleteVersion); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return nil
+}
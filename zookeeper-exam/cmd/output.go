@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-zookeeper/zk"
+)
+
+var output string
+
+func checkOutput() error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output %q", output)
+	}
+	return nil
+}
+
+// render prints v as indented JSON with --output json, and rows as an
+// aligned table under header otherwise, without header when it is nil.
+func render(v interface{}, header []string, rows [][]string) error {
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// printLine prints v as a single line of JSON, for streamed output.
+func printLine(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", b)
+	return nil
+}
+
+// nodeStat is the JSON representation of a zk.Stat.
+type nodeStat struct {
+	Czxid          int64     `json:"czxid"`
+	Mzxid          int64     `json:"mzxid"`
+	Pzxid          int64     `json:"pzxid"`
+	Ctime          time.Time `json:"ctime"`
+	Mtime          time.Time `json:"mtime"`
+	Version        int32     `json:"version"`
+	Cversion       int32     `json:"cversion"`
+	Aversion       int32     `json:"aversion"`
+	EphemeralOwner int64     `json:"ephemeral_owner"`
+	DataLength     int32     `json:"data_length"`
+	NumChildren    int32     `json:"num_children"`
+}
+
+func millisTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func newNodeStat(s *zk.Stat) *nodeStat {
+	if s == nil {
+		return nil
+	}
+	return &nodeStat{
+		Czxid:          s.Czxid,
+		Mzxid:          s.Mzxid,
+		Pzxid:          s.Pzxid,
+		Ctime:          millisTime(s.Ctime),
+		Mtime:          millisTime(s.Mtime),
+		Version:        s.Version,
+		Cversion:       s.Cversion,
+		Aversion:       s.Aversion,
+		EphemeralOwner: s.EphemeralOwner,
+		DataLength:     s.DataLength,
+		NumChildren:    s.NumChildren,
+	}
+}
+
+func (s *nodeStat) rows() [][]string {
+	return [][]string{
+		{"czxid", "0x" + strconv.FormatInt(s.Czxid, 16)},
+		{"mzxid", "0x" + strconv.FormatInt(s.Mzxid, 16)},
+		{"pzxid", "0x" + strconv.FormatInt(s.Pzxid, 16)},
+		{"ctime", s.Ctime.Format(time.RFC3339Nano)},
+		{"mtime", s.Mtime.Format(time.RFC3339Nano)},
+		{"version", strconv.Itoa(int(s.Version))},
+		{"cversion", strconv.Itoa(int(s.Cversion))},
+		{"aversion", strconv.Itoa(int(s.Aversion))},
+		{"ephemeral_owner", "0x" + strconv.FormatInt(s.EphemeralOwner, 16)},
+		{"data_length", strconv.Itoa(int(s.DataLength))},
+		{"num_children", strconv.Itoa(int(s.NumChildren))},
+	}
+}
+
+// nodeData is the JSON representation of the data of a znode. Data that is
+// not valid UTF-8 is written base64 encoded in DataBase64 instead.
+type nodeData struct {
+	Data       *string `json:"data,omitempty"`
+	DataBase64 string  `json:"data_base64,omitempty"`
+}
+
+func newNodeData(data []byte) nodeData {
+	if data == nil {
+		return nodeData{}
+	}
+	if !utf8.Valid(data) {
+		return nodeData{DataBase64: base64.StdEncoding.EncodeToString(data)}
+	}
+	s := string(data)
+	return nodeData{Data: &s}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"zookeeper-exam/zklog"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/spf13/cobra"
+)
+
+// DefaultServers are the zookeeper servers used without --servers or
+// $ZK_SERVERS.
+const DefaultServers = "localhost:2181"
+
+var (
+	servers        []string
+	sessionTimeout time.Duration
+	logLevel       string
+	logFormat      string
+
+	logger *zklog.Logger
+
+	rootCmd = &cobra.Command{
+		Use:          "zookeeper-exam",
+		Short:        "browse and edit the znodes of a zookeeper ensemble",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(); err != nil {
+				return err
+			}
+			return initLogger()
+		},
+	}
+)
+
+// Execute runs the command line. Its context is cancelled on SIGINT or
+// SIGTERM so that commands can stop gracefully.
+func Execute() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+func init() {
+	defaultServers := DefaultServers
+	if s, ok := os.LookupEnv("ZK_SERVERS"); ok {
+		defaultServers = s
+	}
+	rootCmd.PersistentFlags().StringSliceVarP(&servers, "servers", "s", strings.Split(defaultServers, ","), "zookeeper servers, as comma separated host:port ($ZK_SERVERS)")
+	rootCmd.PersistentFlags().DurationVar(&sessionTimeout, "session-timeout", 10*time.Second, "session timeout, also the time allowed to establish the session")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "text", "output format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "level of the messages logged to stderr: "+strings.Join(zklog.Levels, ", "))
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "format of the messages logged to stderr: text or json")
+}
+
+func initLogger() error {
+	level, err := zklog.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	switch logFormat {
+	case "text":
+		logger = zklog.New(zklog.TextHandler(os.Stderr), level)
+	case "json":
+		logger = zklog.New(zklog.JSONHandler(os.Stderr), level)
+	default:
+		return fmt.Errorf("unknown log format %q", logFormat)
+	}
+	return nil
+}
+
+// connect opens a session and waits until it is established, so that a
+// wrong server list fails fast rather than on the first request. The state
+// changes of the session are logged until ctx is done.
+func connect(ctx context.Context) (*zk.Conn, error) {
+	conn, events, err := zk.Connect(servers, sessionTimeout, zk.WithLogger(logger.ZK()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", strings.Join(servers, ","), err)
+	}
+	session := zklog.Watch(ctx, events, logger)
+
+	waitCtx, cancel := context.WithTimeout(ctx, sessionTimeout)
+	defer cancel()
+	state, err := session.Wait(waitCtx, zk.StateHasSession, zk.StateAuthFailed)
+	switch {
+	case ctx.Err() != nil:
+		conn.Close()
+		return nil, ctx.Err()
+	case err != nil:
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: no session after %v", strings.Join(servers, ","), sessionTimeout)
+	case state == zk.StateAuthFailed:
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: authentication failed", strings.Join(servers, ","))
+	}
+	return conn, nil
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeDepth int
+	treeData  bool
+
+	treeCmd = &cobra.Command{
+		Use:   "tree [PATH]",
+		Short: "print the znodes under a path, / by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := "/"
+			if len(args) > 0 {
+				root = args[0]
+			}
+			return runTree(cmd.Context(), root)
+		},
+	}
+)
+
+func init() {
+	treeCmd.Flags().IntVar(&treeDepth, "depth", -1, "maximum depth below PATH (-1 means unlimited)")
+	treeCmd.Flags().BoolVar(&treeData, "data", false, "also print the data of each znode")
+
+	rootCmd.AddCommand(treeCmd)
+}
+
+// nodeReader is the part of *zk.Conn walking a tree needs.
+type nodeReader interface {
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+}
+
+// nodeDeleter is the part of *zk.Conn deleting a tree needs.
+type nodeDeleter interface {
+	nodeReader
+	Delete(path string, version int32) error
+}
+
+// parentPaths returns the ancestors of p, from the top, without "/".
+func parentPaths(p string) []string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	parents := make([]string, 0, len(parts))
+	for i := 1; i < len(parts); i++ {
+		parents = append(parents, "/"+strings.Join(parts[:i], "/"))
+	}
+	return parents
+}
+
+// treeNode is a znode and its descendants.
+type treeNode struct {
+	Path string `json:"path"`
+	nodeData
+	Children []*treeNode `json:"children,omitempty"`
+}
+
+// buildTree reads the znodes under root down to depth, -1 meaning all of
+// them. Znodes deleted while reading are left out.
+func buildTree(ctx context.Context, r nodeReader, root string, depth int, withData bool) (*treeNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	node := &treeNode{Path: root}
+	if withData {
+		data, _, err := r.Get(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", root, err)
+		}
+		node.nodeData = newNodeData(data)
+	}
+	if depth == 0 {
+		return node, nil
+	}
+	children, _, err := r.Children(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+	sort.Strings(children)
+	for _, c := range children {
+		child, err := buildTree(ctx, r, path.Join(root, c), depth-1, withData)
+		if errors.Is(err, zk.ErrNoNode) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
+
+// lines renders the tree as indented names, the root by its full path.
+func (n *treeNode) lines(depth int, withData bool) []string {
+	name := n.Path
+	if depth > 0 {
+		name = path.Base(n.Path)
+	}
+	line := strings.Repeat("  ", depth) + name
+	if withData {
+		switch {
+		case n.Data != nil:
+			line += " = " + *n.Data
+		case n.DataBase64 != "":
+			line += " = base64:" + n.DataBase64
+		}
+	}
+	lines := []string{line}
+	for _, c := range n.Children {
+		lines = append(lines, c.lines(depth+1, withData)...)
+	}
+	return lines
+}
+
+func runTree(ctx context.Context, root string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	tree, err := buildTree(ctx, conn, root, treeDepth, treeData)
+	if err != nil {
+		return err
+	}
+	lines := tree.lines(0, treeData)
+	rows := make([][]string, 0, len(lines))
+	for _, l := range lines {
+		rows = append(rows, []string{l})
+	}
+	return render(tree, nil, rows)
+}
+
+// checkDeleteTree refuses to delete the root znode or the znodes of
+// zookeeper itself recursively.
+func checkDeleteTree(root string) error {
+	root = path.Clean(root)
+	if root == "/" || root == "/zookeeper" || strings.HasPrefix(root, "/zookeeper/") {
+		return fmt.Errorf("refusing to delete %s recursively", root)
+	}
+	return nil
+}
+
+// deleteTree deletes root after all its descendants, version only applying
+// to root. Descendants deleted concurrently are not an error.
+func deleteTree(ctx context.Context, d nodeDeleter, root string, version int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	children, _, err := d.Children(root)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", root, err)
+	}
+	for _, c := range children {
+		err := deleteTree(ctx, d, path.Join(root, c), -1)
+		if err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return err
+		}
+	}
+	if err := d.Delete(root, version); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", root, err)
+	}
+	return nil
+}
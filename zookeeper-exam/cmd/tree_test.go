@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// fakeNodes is a nodeDeleter over a map of paths to data.
+type fakeNodes struct {
+	nodes   map[string]string
+	deleted []string
+}
+
+func (f *fakeNodes) Children(p string) ([]string, *zk.Stat, error) {
+	if _, ok := f.nodes[p]; !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	children := make([]string, 0)
+	for n := range f.nodes {
+		if n != "/" && path.Dir(n) == p {
+			children = append(children, path.Base(n))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(children)))
+	return children, &zk.Stat{NumChildren: int32(len(children))}, nil
+}
+
+func (f *fakeNodes) Get(p string) ([]byte, *zk.Stat, error) {
+	data, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return []byte(data), &zk.Stat{}, nil
+}
+
+func (f *fakeNodes) Delete(p string, version int32) error {
+	if _, ok := f.nodes[p]; !ok {
+		return zk.ErrNoNode
+	}
+	if children, _, _ := f.Children(p); len(children) > 0 {
+		return zk.ErrNotEmpty
+	}
+	delete(f.nodes, p)
+	f.deleted = append(f.deleted, p)
+	return nil
+}
+
+func newFakeNodes() *fakeNodes {
+	return &fakeNodes{nodes: map[string]string{
+		"/":        "",
+		"/app":     "",
+		"/app/b":   "2",
+		"/app/a":   "1",
+		"/app/a/x": "\xff",
+		"/other":   "o",
+	}}
+}
+
+func TestParentPaths(t *testing.T) {
+	tests := map[string][]string{
+		"/a":      {},
+		"/a/b/c":  {"/a", "/a/b"},
+		"/a/b/c/": {"/a", "/a/b"},
+		"a/b":     {"/a"},
+	}
+	for p, expect := range tests {
+		if parents := parentPaths(p); !reflect.DeepEqual(parents, expect) {
+			t.Errorf("expect %v but return %v", expect, parents)
+		}
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	tree, err := buildTree(context.Background(), newFakeNodes(), "/app", -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"/app = ", "  a = 1", "    x = base64:/w==", "  b = 2"}
+	if lines := tree.lines(0, true); !reflect.DeepEqual(lines, expect) {
+		t.Errorf("expect %v but return %v", expect, lines)
+	}
+
+	tree, err = buildTree(context.Background(), newFakeNodes(), "/", 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []string{"/", "  app", "  other"}
+	if lines := tree.lines(0, false); !reflect.DeepEqual(lines, expect) {
+		t.Errorf("expect %v but return %v", expect, lines)
+	}
+}
+
+func TestDeleteTree(t *testing.T) {
+	nodes := newFakeNodes()
+	if err := deleteTree(context.Background(), nodes, "/app", -1); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"/app/b", "/app/a/x", "/app/a", "/app"}
+	if !reflect.DeepEqual(nodes.deleted, expect) {
+		t.Errorf("expect %v but return %v", expect, nodes.deleted)
+	}
+	for p := range nodes.nodes {
+		if strings.HasPrefix(p, "/app") {
+			t.Errorf("expect %s to be deleted", p)
+		}
+	}
+	if err := deleteTree(context.Background(), nodes, "/app", -1); err == nil {
+		t.Errorf("expect an error deleting a missing znode")
+	}
+}
+
+func TestCheckDeleteTree(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"/", false},
+		{"//", false},
+		{"/zookeeper", false},
+		{"/zookeeper/quota", false},
+		{"/zookeeper/../zookeeper", false},
+		{"/app", true},
+		{"/zookeeper-app", true},
+	}
+	for _, test := range tests {
+		if err := checkDeleteTree(test.path); (err == nil) != test.ok {
+			t.Errorf("expect ok %v for %s but return %v", test.ok, test.path, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchChildren  bool
+	watchMaxEvents int
+
+	watchCmd = &cobra.Command{
+		Use:   "watch PATH",
+		Short: "print the data, or children, of a znode each time it changes",
+		Long: `Print the data, or with --children the children, of a znode, then again
+each time it changes, until interrupted. A missing znode is watched until it
+is created.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWatch(cmd.Context(), args[0])
+		},
+	}
+)
+
+func init() {
+	watchCmd.Flags().BoolVarP(&watchChildren, "children", "c", false, "watch the children instead of the data")
+	watchCmd.Flags().IntVarP(&watchMaxEvents, "max-events", "n", 0, "exit after this many changes (0 means unlimited)")
+
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchRecord is the state of the watched znode, printed initially and
+// after each event.
+type watchRecord struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Path   string    `json:"path"`
+	Exists bool      `json:"exists"`
+	nodeData
+	Children []string  `json:"children,omitempty"`
+	Stat     *nodeStat `json:"stat,omitempty"`
+}
+
+func (r watchRecord) print() error {
+	if output == "json" {
+		return printLine(r)
+	}
+	state := "(missing)"
+	switch {
+	case !r.Exists:
+	case watchChildren:
+		state = "[" + strings.Join(r.Children, ", ") + "]"
+	case r.Data != nil:
+		state = *r.Data
+	case r.DataBase64 != "":
+		state = "base64:" + r.DataBase64
+	default:
+		state = ""
+	}
+	fmt.Printf("%s %s %s %s\n", r.Time.Format(time.RFC3339), r.Event, r.Path, state)
+	return nil
+}
+
+// arm reads the znode and sets a watch on it, or on its creation when it
+// does not exist.
+func arm(conn *zk.Conn, path string) (watchRecord, <-chan zk.Event, error) {
+	r := watchRecord{Path: path, Exists: true}
+	var (
+		stat *zk.Stat
+		ch   <-chan zk.Event
+		err  error
+	)
+	if watchChildren {
+		r.Children, stat, ch, err = conn.ChildrenW(path)
+		sort.Strings(r.Children)
+	} else {
+		var data []byte
+		data, stat, ch, err = conn.GetW(path)
+		r.nodeData = newNodeData(data)
+	}
+	if errors.Is(err, zk.ErrNoNode) {
+		r.Exists, stat, ch, err = conn.ExistsW(path)
+		if err == nil && r.Exists {
+			// created in between, read it again
+			return arm(conn, path)
+		}
+	}
+	if err != nil {
+		return r, nil, fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+	r.Stat = newNodeStat(stat)
+	return r, ch, nil
+}
+
+func runWatch(ctx context.Context, path string) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	event := "initial"
+	for count := 0; ; count++ {
+		r, ch, err := arm(conn, path)
+		if err != nil {
+			return err
+		}
+		r.Time, r.Event = time.Now(), event
+		if err := r.print(); err != nil {
+			return err
+		}
+		if watchMaxEvents > 0 && count >= watchMaxEvents {
+			return nil
+		}
+		select {
+		case e := <-ch:
+			if e.Err != nil && !errors.Is(e.Err, zk.ErrSessionExpired) && !errors.Is(e.Err, zk.ErrClosing) {
+				return fmt.Errorf("failed to watch %s: %w", path, e.Err)
+			}
+			event = e.Type.String()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
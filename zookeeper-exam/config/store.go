@@ -0,0 +1,405 @@
+// Package config keeps a subtree of znodes in memory as a map of
+// configuration keys to values, updated through watches, so that reads are
+// served locally.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// Conn is the part of *zk.Conn the store needs.
+type Conn interface {
+	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+}
+
+// Change is a change of a key. Old is nil for a created key and New for a
+// deleted one.
+type Change struct {
+	Key     string
+	Old     Value
+	New     Value
+	Deleted bool
+}
+
+type subscription struct {
+	prefix string
+	fn     func(Change)
+}
+
+type watchKind int
+
+const (
+	dataWatch watchKind = iota
+	childWatch
+)
+
+// watchEvent is an event of a watch set while loading generation gen.
+type watchEvent struct {
+	gen   int
+	kind  watchKind
+	path  string
+	event zk.Event
+}
+
+// Store holds the znodes under a root, keyed by their path relative to the
+// root, e.g. db/url for <root>/db/url. The data of the root itself is not
+// part of it.
+//
+// Changes are applied as their watches fire. When the watches are lost, as
+// when the session expires, or applying a change fails, the whole subtree
+// is read again and the differences notified, so that the store recovers
+// from reconnections.
+type Store struct {
+	// RetryInterval is the wait before reading the subtree again after a
+	// failure, 1 second by default.
+	RetryInterval time.Duration
+
+	conn   Conn
+	root   string
+	events chan watchEvent
+
+	mutex  sync.RWMutex
+	values map[string]Value
+	subs   map[int]subscription
+	nextID int
+
+	// accessed by the update loop only
+	gen int
+}
+
+// NewStore returns a store of the subtree under root, empty until started.
+func NewStore(conn Conn, root string) *Store {
+	return &Store{
+		RetryInterval: time.Second,
+		conn:          conn,
+		root:          path.Clean(root),
+		events:        make(chan watchEvent),
+		values:        make(map[string]Value),
+		subs:          make(map[int]subscription),
+	}
+}
+
+// Start reads the subtree, then keeps it updated until ctx is done.
+func (s *Store) Start(ctx context.Context) error {
+	values, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.values = values
+	s.mutex.Unlock()
+	go s.run(ctx)
+	return nil
+}
+
+// Get returns the value of key.
+func (s *Store) Get(key string) (Value, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Keys returns the sorted keys.
+func (s *Store) Keys() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot returns a copy of all the keys and values.
+func (s *Store) Snapshot() map[string]Value {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	values := make(map[string]Value, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+
+// Subscribe calls fn with each change of the keys starting with prefix,
+// once the store reflects it. Changes are delivered one at a time, in
+// order, so fn should return promptly. The returned function unsubscribes.
+func (s *Store) Subscribe(prefix string, fn func(Change)) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.subs[id] = subscription{prefix: prefix, fn: fn}
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+func (s *Store) key(p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, s.root), "/")
+}
+
+func (s *Store) path(key string) string {
+	return path.Join(s.root, key)
+}
+
+// watch forwards the event of a watch to the update loop.
+func (s *Store) watch(ctx context.Context, kind watchKind, p string, ch <-chan zk.Event) {
+	gen := s.gen
+	go func() {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			select {
+			case s.events <- watchEvent{gen: gen, kind: kind, path: p, event: e}:
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// load reads the subtree under the root in a new generation of watches. A
+// missing root is watched for its creation.
+func (s *Store) load(ctx context.Context) (map[string]Value, error) {
+	for {
+		s.gen++
+		values := make(map[string]Value)
+		err := s.loadNode(ctx, s.root, values)
+		if !errors.Is(err, zk.ErrNoNode) {
+			if err != nil {
+				return nil, err
+			}
+			return values, nil
+		}
+		exists, _, ch, err := s.conn.ExistsW(s.root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", s.root, err)
+		}
+		if !exists {
+			s.watch(ctx, dataWatch, s.root, ch)
+			return values, nil
+		}
+		// created in between, which the watch of an existing znode does not
+		// report, so it is read again
+	}
+}
+
+// loadNode reads p and its descendants into values, setting their watches.
+func (s *Store) loadNode(ctx context.Context, p string, values map[string]Value) error {
+	data, _, ch, err := s.conn.GetW(p)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", p, err)
+	}
+	s.watch(ctx, dataWatch, p, ch)
+	if p != s.root {
+		values[s.key(p)] = data
+	}
+	children, _, ch, err := s.conn.ChildrenW(p)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", p, err)
+	}
+	s.watch(ctx, childWatch, p, ch)
+	for _, c := range children {
+		err := s.loadNode(ctx, path.Join(p, c), values)
+		if err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) run(ctx context.Context) {
+	var retry <-chan time.Time
+	for {
+		select {
+		case e := <-s.events:
+			if e.gen != s.gen {
+				continue
+			}
+			if err := s.apply(ctx, e); err != nil {
+				retry = time.After(s.RetryInterval)
+			}
+		case <-retry:
+			retry = nil
+			if err := s.reload(ctx); err != nil {
+				retry = time.After(s.RetryInterval)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload reads the whole subtree again and notifies the differences.
+func (s *Store) reload(ctx context.Context) error {
+	values, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	old := s.values
+	changes := make([]Change, 0)
+	for k, v := range values {
+		if o, ok := old[k]; !ok || string(o) != string(v) {
+			changes = append(changes, Change{Key: k, Old: o, New: v})
+		}
+	}
+	for k, o := range old {
+		if _, ok := values[k]; !ok {
+			changes = append(changes, Change{Key: k, Old: o, Deleted: true})
+		}
+	}
+	s.values = values
+	s.mutex.Unlock()
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	s.notify(changes)
+	return nil
+}
+
+// apply updates the store from the event of a watch, or reloads it when
+// the watches were lost.
+func (s *Store) apply(ctx context.Context, e watchEvent) error {
+	switch {
+	case e.event.Type == zk.EventNotWatching:
+		return s.reload(ctx)
+	case e.kind == dataWatch && e.event.Type == zk.EventNodeDeleted:
+		if e.path == s.root {
+			return s.reload(ctx)
+		}
+		s.remove(e.path)
+		return nil
+	case e.kind == dataWatch && e.path == s.root && e.event.Type == zk.EventNodeCreated:
+		return s.reload(ctx)
+	case e.kind == dataWatch:
+		data, _, ch, err := s.conn.GetW(e.path)
+		if errors.Is(err, zk.ErrNoNode) {
+			s.remove(e.path)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.watch(ctx, dataWatch, e.path, ch)
+		if e.path != s.root {
+			s.set(s.key(e.path), data)
+		}
+		return nil
+	case e.kind == childWatch && e.event.Type == zk.EventNodeChildrenChanged:
+		return s.applyChildren(ctx, e.path)
+	}
+	return nil
+}
+
+// applyChildren loads the new children of p and removes the deleted ones.
+func (s *Store) applyChildren(ctx context.Context, p string) error {
+	children, _, ch, err := s.conn.ChildrenW(p)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.watch(ctx, childWatch, p, ch)
+	current := make(map[string]bool, len(children))
+	for _, c := range children {
+		child := path.Join(p, c)
+		current[s.key(child)] = true
+		if _, ok := s.Get(s.key(child)); ok {
+			continue
+		}
+		values := make(map[string]Value)
+		if err := s.loadNode(ctx, child, values); err != nil && !errors.Is(err, zk.ErrNoNode) {
+			return err
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s.set(k, values[k])
+		}
+	}
+
+	prefix := s.key(p)
+	for _, k := range s.Keys() {
+		parent := path.Dir(k)
+		if parent == "." {
+			parent = ""
+		}
+		if parent == prefix && !current[k] {
+			s.remove(s.path(k))
+		}
+	}
+	return nil
+}
+
+func (s *Store) set(key string, v Value) {
+	s.mutex.Lock()
+	old, ok := s.values[key]
+	if ok && string(old) == string(v) {
+		s.mutex.Unlock()
+		return
+	}
+	s.values[key] = v
+	s.mutex.Unlock()
+	s.notify([]Change{{Key: key, Old: old, New: v}})
+}
+
+// remove deletes the key of p and its descendants.
+func (s *Store) remove(p string) {
+	key := s.key(p)
+	s.mutex.Lock()
+	changes := make([]Change, 0)
+	for k, v := range s.values {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			changes = append(changes, Change{Key: k, Old: v, Deleted: true})
+			delete(s.values, k)
+		}
+	}
+	s.mutex.Unlock()
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key > changes[j].Key })
+	s.notify(changes)
+}
+
+func (s *Store) notify(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	s.mutex.RLock()
+	subs := make([]subscription, 0, len(s.subs))
+	ids := make([]int, 0, len(s.subs))
+	for id := range s.subs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		subs = append(subs, s.subs[id])
+	}
+	s.mutex.RUnlock()
+	for _, c := range changes {
+		for _, sub := range subs {
+			if strings.HasPrefix(c.Key, sub.prefix) {
+				sub.fn(c)
+			}
+		}
+	}
+}
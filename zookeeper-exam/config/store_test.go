@@ -0,0 +1,213 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"zookeeper-exam/zktest"
+
+	"github.com/go-zookeeper/zk"
+)
+
+type changes struct {
+	mutex sync.Mutex
+	list  []Change
+}
+
+func (c *changes) add(change Change) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.list = append(c.list, change)
+}
+
+// wait waits for n changes and returns them.
+func (c *changes) wait(t *testing.T, n int) []Change {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.mutex.Lock()
+		if len(c.list) >= n {
+			list := c.list
+			c.list = nil
+			c.mutex.Unlock()
+			return list
+		}
+		c.mutex.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expect %d changes", n)
+	return nil
+}
+
+func mustCreate(t *testing.T, conn *zktest.Conn, p, data string) {
+	t.Helper()
+	if _, err := conn.Create(p, []byte(data), 0, zk.WorldACL(zk.PermAll)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	server := zktest.NewServer()
+	admin := server.Connect()
+	mustCreate(t, admin, "/app", "")
+	mustCreate(t, admin, "/app/config", "root")
+	mustCreate(t, admin, "/app/config/key1", "v1")
+	mustCreate(t, admin, "/app/config/db", "")
+	mustCreate(t, admin, "/app/config/db/pool", "10")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewStore(server.Connect(), "/app/config")
+	if err := store.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := store.Keys(); !reflect.DeepEqual(keys, []string{"db", "db/pool", "key1"}) {
+		t.Errorf("expect keys db, db/pool and key1 but return %v", keys)
+	}
+	if v, ok := store.Get("db/pool"); !ok || v.String() != "10" {
+		t.Errorf("expect 10 but return %v, %v", v, ok)
+	}
+
+	all, db := &changes{}, &changes{}
+	store.Subscribe("", all.add)
+	unsubscribe := store.Subscribe("db/", db.add)
+
+	admin.Set("/app/config/key1", []byte("v2"), -1)
+	if c := all.wait(t, 1); c[0].Key != "key1" || c[0].Old.String() != "v1" || c[0].New.String() != "v2" {
+		t.Errorf("expect key1 changed from v1 to v2 but return %+v", c)
+	}
+	if v, _ := store.Get("key1"); v.String() != "v2" {
+		t.Errorf("expect v2 but return %v", v)
+	}
+
+	mustCreate(t, admin, "/app/config/db/url", "jdbc:x")
+	if c := db.wait(t, 1); c[0].Key != "db/url" || c[0].Old != nil || c[0].New.String() != "jdbc:x" {
+		t.Errorf("expect db/url created but return %+v", c)
+	}
+	all.wait(t, 1)
+
+	unsubscribe()
+	admin.Delete("/app/config/db/url", -1)
+	admin.Delete("/app/config/db/pool", -1)
+	admin.Delete("/app/config/db", -1)
+	c := all.wait(t, 3)
+	deleted := make([]string, 0)
+	for _, change := range c {
+		if change.Deleted {
+			deleted = append(deleted, change.Key)
+		}
+	}
+	if len(deleted) != 3 {
+		t.Errorf("expect 3 deletions but return %+v", c)
+	}
+	if keys := store.Keys(); !reflect.DeepEqual(keys, []string{"key1"}) {
+		t.Errorf("expect only key1 but return %v", keys)
+	}
+	if len(db.list) != 0 {
+		t.Errorf("expect no change after unsubscribing but return %+v", db.list)
+	}
+}
+
+func TestStoreSessionExpiry(t *testing.T) {
+	server := zktest.NewServer()
+	admin := server.Connect()
+	mustCreate(t, admin, "/config", "")
+	mustCreate(t, admin, "/config/a", "1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := server.Connect()
+	store := NewStore(conn, "/config")
+	store.RetryInterval = 10 * time.Millisecond
+	if err := store.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	all := &changes{}
+	store.Subscribe("", all.add)
+
+	// changes made while the watches are lost are caught up on reload
+	conn.Expire()
+	admin.Set("/config/a", []byte("2"), -1)
+	mustCreate(t, admin, "/config/b", "3")
+	all.wait(t, 2)
+	time.Sleep(20 * time.Millisecond)
+	if snapshot := store.Snapshot(); snapshot["a"].String() != "2" || snapshot["b"].String() != "3" {
+		t.Errorf("expect a=2 and b=3 but return %v", snapshot)
+	}
+
+	// a missing root is watched until created
+	admin.Delete("/config/a", -1)
+	admin.Delete("/config/b", -1)
+	admin.Delete("/config", -1)
+	all.wait(t, 2)
+	mustCreate(t, admin, "/config", "")
+	mustCreate(t, admin, "/config/c", "4")
+	all.wait(t, 1)
+	if v, ok := store.Get("c"); !ok || v.String() != "4" {
+		t.Errorf("expect 4 but return %v, %v", v, ok)
+	}
+}
+
+// createOnMissConn creates the root of a store after its first read fails,
+// before the store watches for its creation.
+type createOnMissConn struct {
+	*zktest.Conn
+	admin   *zktest.Conn
+	root    string
+	created bool
+}
+
+func (c *createOnMissConn) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	data, stat, ch, err := c.Conn.GetW(p)
+	if p == c.root && errors.Is(err, zk.ErrNoNode) && !c.created {
+		c.created = true
+		c.admin.Create(p, nil, 0, zk.WorldACL(zk.PermAll))
+		c.admin.Create(p+"/a", []byte("1"), 0, zk.WorldACL(zk.PermAll))
+	}
+	return data, stat, ch, err
+}
+
+func TestStoreRootCreatedWhileLoading(t *testing.T) {
+	server := zktest.NewServer()
+	conn := &createOnMissConn{Conn: server.Connect(), admin: server.Connect(), root: "/config"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewStore(conn, "/config")
+	if err := store.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := store.Get("a"); !ok || v.String() != "1" {
+		t.Errorf("expect 1 but return %v, %v", v, ok)
+	}
+
+	all := &changes{}
+	store.Subscribe("", all.add)
+	conn.admin.Set("/config/a", []byte("2"), -1)
+	if c := all.wait(t, 1); c[0].Key != "a" || c[0].New.String() != "2" {
+		t.Errorf("expect a changed to 2 but return %+v", c)
+	}
+}
+
+func TestValue(t *testing.T) {
+	if i, err := Value(" 42\n").Int(); err != nil || i != 42 {
+		t.Errorf("expect 42 but return %v, %v", i, err)
+	}
+	if b, err := Value("true").Bool(); err != nil || !b {
+		t.Errorf("expect true but return %v, %v", b, err)
+	}
+	if d, err := Value("1m30s").Duration(); err != nil || d != 90*time.Second {
+		t.Errorf("expect 1m30s but return %v, %v", d, err)
+	}
+	var v struct{ Hosts []string }
+	if err := Value(`{"hosts": ["a", "b"]}`).Unmarshal(&v); err != nil || len(v.Hosts) != 2 {
+		t.Errorf("expect 2 hosts but return %v, %v", v, err)
+	}
+	if _, err := Value("x").Float(); err == nil {
+		t.Errorf("expect an error parsing x")
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is the data of a znode, with conversions to the usual types of
+// configuration. Surrounding spaces are ignored by the conversions.
+type Value []byte
+
+func (v Value) String() string {
+	return string(v)
+}
+
+func (v Value) trimmed() string {
+	return strings.TrimSpace(string(v))
+}
+
+func (v Value) Int() (int64, error) {
+	return strconv.ParseInt(v.trimmed(), 10, 64)
+}
+
+func (v Value) Float() (float64, error) {
+	return strconv.ParseFloat(v.trimmed(), 64)
+}
+
+func (v Value) Bool() (bool, error) {
+	return strconv.ParseBool(v.trimmed())
+}
+
+// Duration parses a duration such as 1m30s.
+func (v Value) Duration() (time.Duration, error) {
+	return time.ParseDuration(v.trimmed())
+}
+
+// Unmarshal decodes the value as JSON into out.
+func (v Value) Unmarshal(out interface{}) error {
+	return json.Unmarshal(v, out)
+}
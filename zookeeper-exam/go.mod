@@ -2,4 +2,8 @@ module zookeeper-exam
 
 go 1.16
 
-require github.com/go-zookeeper/zk v1.0.2
+require (
+	github.com/go-zookeeper/zk v1.0.2
+	github.com/spf13/cobra v1.1.3
+	gopkg.in/yaml.v2 v2.4.0
+)
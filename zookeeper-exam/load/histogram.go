@@ -0,0 +1,143 @@
+package load
+
+import (
+	"math"
+	"math/bits"
+)
+
+// subBucketBits sets the precision of a Histogram: values are counted
+// exactly below 2^subBucketBits and within 1/2^(subBucketBits-1) above.
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+)
+
+// Histogram counts non negative values in log linear buckets, as an HDR
+// histogram does, so that quantiles keep a fixed relative precision over
+// any range while recording stays constant time and allocation free once
+// the histogram has grown to the largest value.
+type Histogram struct {
+	counts []int64
+	count  int64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+// bucketOf returns the index of the bucket counting v.
+func bucketOf(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return shift*subBucketHalf + int(v>>uint(shift))
+}
+
+// highest returns the largest value counted by bucket i.
+func highest(i int) int64 {
+	if i < subBucketCount {
+		return int64(i)
+	}
+	shift := i/subBucketHalf - 1
+	sub := int64(i - shift*subBucketHalf)
+	return (sub+1)<<uint(shift) - 1
+}
+
+// Record counts v, negative values counting as 0.
+func (h *Histogram) Record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	i := bucketOf(v)
+	if i >= len(h.counts) {
+		counts := make([]int64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += float64(v)
+}
+
+// Merge adds the values counted by o.
+func (h *Histogram) Merge(o *Histogram) {
+	if o.count == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		counts := make([]int64, len(o.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.count += o.count
+	h.sum += o.sum
+}
+
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+// Quantile returns the value below or at which a fraction q of the values
+// are, within the precision of the histogram and never above the maximum.
+func (h *Histogram) Quantile(q float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			if v := highest(i); v < h.max {
+				return v
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// LatencySummary summarizes latencies, in microseconds.
+type LatencySummary struct {
+	Count int64   `json:"count"`
+	Min   int64   `json:"min_us"`
+	Mean  float64 `json:"mean_us"`
+	P50   int64   `json:"p50_us"`
+	P95   int64   `json:"p95_us"`
+	P99   int64   `json:"p99_us"`
+	Max   int64   `json:"max_us"`
+}
+
+func (h *Histogram) Summary() LatencySummary {
+	s := LatencySummary{
+		Count: h.count,
+		Min:   h.min,
+		Max:   h.max,
+		P50:   h.Quantile(0.5),
+		P95:   h.Quantile(0.95),
+		P99:   h.Quantile(0.99),
+	}
+	if h.count > 0 {
+		s.Mean = h.sum / float64(h.count)
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package load
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBuckets(t *testing.T) {
+	last := -1
+	for v := int64(0); v < 1<<20; v++ {
+		i := bucketOf(v)
+		if i != last && i != last+1 {
+			t.Fatalf("expect bucket %d or %d for %d but return %d", last, last+1, v, i)
+		}
+		if highest(i) < v {
+			t.Fatalf("expect bucket %d to count %d but it ends at %d", i, v, highest(i))
+		}
+		if v > 0 && bucketOf(v-1) != i && highest(bucketOf(v-1)) != v-1 {
+			t.Fatalf("expect bucket %d to end at %d but return %d", bucketOf(v-1), v-1, highest(bucketOf(v-1)))
+		}
+		last = i
+	}
+	if i := bucketOf(math.MaxInt64); highest(i) != math.MaxInt64 {
+		t.Errorf("expect %d but return %d", int64(math.MaxInt64), highest(i))
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	h := &Histogram{}
+	for v := int64(1); v <= 10000; v++ {
+		h.Record(v)
+	}
+	tests := []struct {
+		q      float64
+		expect int64
+	}{
+		{0.5, 5000},
+		{0.95, 9500},
+		{0.99, 9900},
+		{1, 10000},
+	}
+	for _, test := range tests {
+		v := h.Quantile(test.q)
+		if math.Abs(float64(v-test.expect)) > float64(test.expect)/subBucketHalf {
+			t.Errorf("expect %v but return %v", test.expect, v)
+		}
+	}
+
+	s := h.Summary()
+	if s.Count != 10000 || s.Min != 1 || s.Max != 10000 || s.Mean != 5000.5 {
+		t.Errorf("expect 10000 values in [1, 10000] of mean 5000.5 but return %+v", s)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := &Histogram{}, &Histogram{}
+	for v := int64(0); v < 100; v++ {
+		a.Record(v)
+		b.Record(v * 1000)
+	}
+	a.Merge(b)
+	a.Merge(&Histogram{})
+	if a.Count() != 200 || a.Quantile(1) != 99000 || a.Quantile(0.5) != 98 {
+		t.Errorf("expect 200 values up to 99000 of median 98 but return %+v", a.Summary())
+	}
+}
@@ -0,0 +1,222 @@
+// Package load generates a mix of reads and writes against zookeeper znodes
+// and measures throughput, errors and latencies.
+package load
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// Client is the part of *zk.Conn the load needs.
+type Client interface {
+	Get(path string) ([]byte, *zk.Stat, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+}
+
+// Options configures a load. It runs until Duration elapses, Ops operations
+// were issued or the context is cancelled, whichever comes first.
+type Options struct {
+	// Paths are the znodes read and written, picked at random.
+	Paths []string
+	// Concurrency is the number of operations in flight, 1 by default.
+	Concurrency int
+	Duration    time.Duration
+	Ops         int64
+	// QPS is the target rate of operations over all workers, 0 means as
+	// fast as possible. With a target rate, latencies are measured from
+	// when an operation was due rather than when it was sent, so that a
+	// slow server is not hidden by workers falling behind schedule.
+	QPS float64
+	// WriteRatio is the fraction of operations that are writes.
+	WriteRatio float64
+	// ValueSize is the size of the data written.
+	ValueSize int
+	// Seed seeds the choice of operations and paths.
+	Seed int64
+	// Progress, when set, is called every ProgressInterval with the
+	// operations completed so far.
+	Progress         func(done, errors int64)
+	ProgressInterval time.Duration
+}
+
+// Report is the outcome of a load.
+type Report struct {
+	Elapsed        time.Duration    `json:"-"`
+	ElapsedSeconds float64          `json:"elapsed_seconds"`
+	Ops            int64            `json:"ops"`
+	Reads          int64            `json:"reads"`
+	Writes         int64            `json:"writes"`
+	OpsPerSec      float64          `json:"ops_per_sec"`
+	Errors         map[string]int64 `json:"errors"`
+	ErrorCount     int64            `json:"error_count"`
+	Read           LatencySummary   `json:"read_latency"`
+	Write          LatencySummary   `json:"write_latency"`
+	All            LatencySummary   `json:"latency"`
+}
+
+// ErrorKinds returns the kinds of Errors, most frequent first.
+func (r Report) ErrorKinds() []string {
+	kinds := make([]string, 0, len(r.Errors))
+	for k := range r.Errors {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if r.Errors[kinds[i]] != r.Errors[kinds[j]] {
+			return r.Errors[kinds[i]] > r.Errors[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	return kinds
+}
+
+// worker holds the results of one worker, merged once all are done.
+type worker struct {
+	reads  Histogram
+	writes Histogram
+	errors map[string]int64
+}
+
+// pacer spreads operations evenly at a target rate.
+type pacer struct {
+	mutex    sync.Mutex
+	next     time.Time
+	interval time.Duration
+}
+
+// wait blocks until the next operation is due and returns when it was.
+func (p *pacer) wait(ctx context.Context) (time.Time, error) {
+	p.mutex.Lock()
+	due := p.next
+	p.next = p.next.Add(p.interval)
+	p.mutex.Unlock()
+	if d := time.Until(due); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return due, ctx.Err()
+		}
+	}
+	return due, nil
+}
+
+// Run runs a load and reports on it.
+func Run(ctx context.Context, client Client, opts Options) (Report, error) {
+	if len(opts.Paths) == 0 {
+		return Report{}, errors.New("load: no paths")
+	}
+	if opts.Duration <= 0 && opts.Ops <= 0 {
+		return Report{}, errors.New("load: either a duration or a number of operations is required")
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	value := make([]byte, opts.ValueSize)
+	for i := range value {
+		value[i] = 'a' + byte(i%26)
+	}
+	start := time.Now()
+	var p *pacer
+	if opts.QPS > 0 {
+		p = &pacer{next: start, interval: time.Duration(float64(time.Second) / opts.QPS)}
+	}
+
+	var issued, done, failed int64
+	workers := make([]*worker, concurrency)
+	wg := new(sync.WaitGroup)
+	for i := range workers {
+		w := &worker{errors: make(map[string]int64)}
+		workers[i] = w
+		random := rand.New(rand.NewSource(opts.Seed + int64(i)))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if opts.Ops > 0 && atomic.AddInt64(&issued, 1) > opts.Ops {
+					return
+				}
+				began := time.Now()
+				if p != nil {
+					due, err := p.wait(ctx)
+					if err != nil {
+						return
+					}
+					began = due
+				}
+				path := opts.Paths[random.Intn(len(opts.Paths))]
+				var err error
+				if random.Float64() < opts.WriteRatio {
+					_, err = client.Set(path, value, -1)
+					w.writes.Record(int64(time.Since(began) / time.Microsecond))
+				} else {
+					_, _, err = client.Get(path)
+					w.reads.Record(int64(time.Since(began) / time.Microsecond))
+				}
+				if err != nil {
+					// zookeeper errors are sentinels, so their
+					// message names their kind
+					w.errors[err.Error()]++
+					atomic.AddInt64(&failed, 1)
+				}
+				atomic.AddInt64(&done, 1)
+			}
+		}()
+	}
+
+	finished, reported := make(chan struct{}), make(chan struct{})
+	if opts.Progress != nil && opts.ProgressInterval > 0 {
+		go func() {
+			defer close(reported)
+			ticker := time.NewTicker(opts.ProgressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					opts.Progress(atomic.LoadInt64(&done), atomic.LoadInt64(&failed))
+				case <-finished:
+					return
+				}
+			}
+		}()
+	} else {
+		close(reported)
+	}
+	wg.Wait()
+	close(finished)
+	<-reported
+
+	r := Report{Elapsed: time.Since(start), Errors: make(map[string]int64)}
+	var reads, writes, all Histogram
+	for _, w := range workers {
+		reads.Merge(&w.reads)
+		writes.Merge(&w.writes)
+		for k, c := range w.errors {
+			r.Errors[k] += c
+			r.ErrorCount += c
+		}
+	}
+	all.Merge(&reads)
+	all.Merge(&writes)
+	r.ElapsedSeconds = r.Elapsed.Seconds()
+	r.Reads, r.Writes, r.Ops = reads.Count(), writes.Count(), all.Count()
+	if r.ElapsedSeconds > 0 {
+		r.OpsPerSec = float64(r.Ops) / r.ElapsedSeconds
+	}
+	r.Read, r.Write, r.All = reads.Summary(), writes.Summary(), all.Summary()
+	return r, nil
+}
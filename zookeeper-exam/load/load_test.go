@@ -0,0 +1,106 @@
+package load
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+type fakeClient struct {
+	mutex  sync.Mutex
+	gets   int
+	sets   int
+	delay  time.Duration
+	failOn string
+}
+
+func (c *fakeClient) Get(path string) ([]byte, *zk.Stat, error) {
+	time.Sleep(c.delay)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gets++
+	if path == c.failOn {
+		return nil, nil, zk.ErrNoNode
+	}
+	return []byte("v"), &zk.Stat{}, nil
+}
+
+func (c *fakeClient) Set(path string, data []byte, version int32) (*zk.Stat, error) {
+	time.Sleep(c.delay)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sets++
+	if path == c.failOn {
+		return nil, zk.ErrNoNode
+	}
+	return &zk.Stat{}, nil
+}
+
+func TestRunOps(t *testing.T) {
+	client := &fakeClient{failOn: "/b"}
+	r, err := Run(context.Background(), client, Options{
+		Paths:       []string{"/a", "/b"},
+		Concurrency: 8,
+		Ops:         1000,
+		WriteRatio:  0.2,
+		ValueSize:   10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Ops != 1000 || r.Reads+r.Writes != 1000 || client.gets != int(r.Reads) || client.sets != int(r.Writes) {
+		t.Errorf("expect 1000 operations but return %+v, client %d gets and %d sets", r, client.gets, client.sets)
+	}
+	if r.Writes < 100 || r.Writes > 300 {
+		t.Errorf("expect about 200 writes but return %d", r.Writes)
+	}
+	if kinds := r.ErrorKinds(); len(kinds) != 1 || kinds[0] != zk.ErrNoNode.Error() || r.ErrorCount != r.Errors[kinds[0]] {
+		t.Errorf("expect only %v errors but return %v", zk.ErrNoNode, r.Errors)
+	}
+	if r.ErrorCount < 400 || r.ErrorCount > 600 {
+		t.Errorf("expect about 500 errors but return %d", r.ErrorCount)
+	}
+}
+
+func TestRunQPS(t *testing.T) {
+	client := &fakeClient{delay: time.Millisecond}
+	r, err := Run(context.Background(), client, Options{
+		Paths:       []string{"/a"},
+		Concurrency: 4,
+		Ops:         20,
+		QPS:         200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Ops != 20 || r.Elapsed < 95*time.Millisecond {
+		t.Errorf("expect 20 operations over at least 95ms but return %d over %v", r.Ops, r.Elapsed)
+	}
+	if r.All.Min < 1000 {
+		t.Errorf("expect latencies of at least 1ms but return %+v", r.All)
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	var progress int64
+	r, err := Run(context.Background(), &fakeClient{delay: time.Millisecond}, Options{
+		Paths:            []string{"/a"},
+		Concurrency:      2,
+		Duration:         50 * time.Millisecond,
+		Progress:         func(done, errors int64) { progress = done },
+		ProgressInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Elapsed < 50*time.Millisecond || r.Ops == 0 || progress == 0 {
+		t.Errorf("expect operations over 50ms with progress but return %d over %v, progress %d", r.Ops, r.Elapsed, progress)
+	}
+
+	if _, err := Run(context.Background(), &fakeClient{}, Options{Paths: []string{"/a"}}); err == nil {
+		t.Errorf("expect an error without a duration or number of operations")
+	}
+}
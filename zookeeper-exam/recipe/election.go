@@ -0,0 +1,126 @@
+package recipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// ErrNoLeader is returned by Leader when no candidate is elected.
+var ErrNoLeader = errors.New("recipe: no leader")
+
+// Callbacks are notified of the leadership of a candidate. They are called
+// from Run, one at a time.
+type Callbacks struct {
+	// Elected is called when the candidate becomes the leader, with a
+	// context cancelled once it is not anymore. It should return promptly,
+	// running the work of the leader in its own goroutine bound to ctx.
+	Elected func(ctx context.Context)
+	// Revoked is called when the candidate stops being the leader, after
+	// the context given to Elected is cancelled and before another
+	// candidate can be elected, so it may wait for the work of the leader
+	// to stop.
+	Revoked func()
+}
+
+// Election elects a single leader among the candidates running it on the
+// same directory, the candidate holding a Lock on it. A leader whose
+// session expires loses the leadership and runs again.
+type Election struct {
+	// RetryInterval is the wait before running again after a failure to
+	// reach the ensemble, 1 second by default.
+	RetryInterval time.Duration
+
+	conn Conn
+	dir  string
+	lock *Lock
+}
+
+// NewElection returns an election on dir for the candidate, which is stored
+// in its znode and returned by Leader.
+func NewElection(conn Conn, dir string, candidate string) *Election {
+	return &Election{
+		RetryInterval: time.Second,
+		conn:          conn,
+		dir:           dir,
+		lock:          NewLock(conn, dir, []byte(candidate)),
+	}
+}
+
+// transient tells errors after which the election is run again.
+func transient(err error) bool {
+	return errors.Is(err, zk.ErrSessionExpired) ||
+		errors.Is(err, zk.ErrConnectionClosed) ||
+		errors.Is(err, zk.ErrNoServer) ||
+		errors.Is(err, ErrLost)
+}
+
+// Run takes part in the election until ctx is done, resigning then.
+func (e *Election) Run(ctx context.Context, cb Callbacks) error {
+	for {
+		err := e.lock.Lock(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if !transient(err) {
+				return err
+			}
+			if errors.Is(err, ErrLost) {
+				e.lock.Unlock()
+			}
+			select {
+			case <-time.After(e.RetryInterval):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		leaderCtx, cancel := context.WithCancel(ctx)
+		if cb.Elected != nil {
+			cb.Elected(leaderCtx)
+		}
+		select {
+		case <-e.lock.Lost():
+		case <-ctx.Done():
+		}
+		cancel()
+		if cb.Revoked != nil {
+			cb.Revoked()
+		}
+		if err := e.lock.Unlock(); err != nil && !errors.Is(err, ErrLost) && !transient(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Leader returns the elected candidate.
+func (e *Election) Leader() (string, error) {
+	names, err := contenders(e.conn, e.dir)
+	if errors.Is(err, zk.ErrNoNode) {
+		return "", ErrNoLeader
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		data, _, err := e.conn.Get(path.Join(e.dir, name))
+		if errors.Is(err, zk.ErrNoNode) {
+			// resigned meanwhile
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get %s: %w", name, err)
+		}
+		return string(data), nil
+	}
+	return "", ErrNoLeader
+}
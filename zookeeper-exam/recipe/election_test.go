@@ -0,0 +1,131 @@
+package recipe
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"zookeeper-exam/zktest"
+)
+
+// candidate runs an election and records its leadership.
+type candidate struct {
+	name     string
+	conn     *zktest.Conn
+	election *Election
+	mutex    sync.Mutex
+	leading  bool
+	elected  int
+	revoked  int
+	done     chan error
+}
+
+func runCandidate(ctx context.Context, server *zktest.Server, name string) *candidate {
+	c := &candidate{name: name, conn: server.Connect(), done: make(chan error, 1)}
+	c.election = NewElection(c.conn, "/election", name)
+	c.election.RetryInterval = 10 * time.Millisecond
+	go func() {
+		c.done <- c.election.Run(ctx, Callbacks{
+			Elected: func(ctx context.Context) {
+				c.mutex.Lock()
+				defer c.mutex.Unlock()
+				c.leading = true
+				c.elected++
+			},
+			Revoked: func() {
+				c.mutex.Lock()
+				defer c.mutex.Unlock()
+				c.leading = false
+				c.revoked++
+			},
+		})
+	}()
+	return c
+}
+
+func (c *candidate) isLeading() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.leading
+}
+
+// leader waits for a single leader among candidates and returns it.
+func leader(t *testing.T, candidates ...*candidate) *candidate {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		leaders := make([]*candidate, 0)
+		for _, c := range candidates {
+			if c.isLeading() {
+				leaders = append(leaders, c)
+			}
+		}
+		if len(leaders) > 1 {
+			t.Fatalf("expect a single leader but return %d", len(leaders))
+		}
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("expect a leader to be elected")
+	return nil
+}
+
+func TestElection(t *testing.T) {
+	server := zktest.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observer := NewElection(server.Connect(), "/election", "")
+	if _, err := observer.Leader(); !errors.Is(err, ErrNoLeader) {
+		t.Errorf("expect %v but return %v", ErrNoLeader, err)
+	}
+
+	candidates := []*candidate{
+		runCandidate(ctx, server, "a"),
+		runCandidate(ctx, server, "b"),
+		runCandidate(ctx, server, "c"),
+	}
+	first := leader(t, candidates...)
+	if name, err := observer.Leader(); err != nil || name != first.name {
+		t.Errorf("expect %v but return %v, %v", first.name, name, err)
+	}
+
+	first.conn.Expire()
+	deadline := time.Now().Add(time.Second)
+	for first.isLeading() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	second := leader(t, candidates...)
+	if second == first {
+		t.Fatalf("expect another leader after the session of %s expired", first.name)
+	}
+	first.mutex.Lock()
+	if first.elected != 1 || first.revoked != 1 {
+		t.Errorf("expect %s elected and revoked once but return %d and %d", first.name, first.elected, first.revoked)
+	}
+	first.mutex.Unlock()
+	if name, err := observer.Leader(); err != nil || name != second.name {
+		t.Errorf("expect %v but return %v, %v", second.name, name, err)
+	}
+
+	cancel()
+	for _, c := range candidates {
+		select {
+		case err := <-c.done:
+			if err != nil {
+				t.Errorf("expect %s to resign without error but return %v", c.name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expect %s to resign", c.name)
+		}
+		if c.isLeading() {
+			t.Errorf("expect %s revoked on resigning", c.name)
+		}
+	}
+	if _, err := observer.Leader(); !errors.Is(err, ErrNoLeader) {
+		t.Errorf("expect %v but return %v", ErrNoLeader, err)
+	}
+}
@@ -0,0 +1,264 @@
+// Package recipe implements coordination recipes on zookeeper: a reentrant
+// distributed lock and a leader election built on it.
+package recipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// Conn is the part of *zk.Conn the recipes need.
+type Conn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	CreateProtectedEphemeralSequential(path string, data []byte, acl []zk.ACL) (string, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Delete(path string, version int32) error
+}
+
+var (
+	// ErrNotLocked is returned by Unlock when the lock is not held.
+	ErrNotLocked = errors.New("recipe: not locked")
+	// ErrLost is returned by Unlock when the lock was lost while held, so
+	// that another process may have held it meanwhile, and by Lock until
+	// the holds of a lost lock are released.
+	ErrLost = errors.New("recipe: lock lost while held")
+)
+
+const lockPrefix = "lock-"
+
+// ensurePath creates p and its missing ancestors as persistent znodes.
+func ensurePath(conn Conn, p string) error {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i := range parts {
+		q := "/" + strings.Join(parts[:i+1], "/")
+		if _, err := conn.Create(q, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return fmt.Errorf("failed to create %s: %w", q, err)
+		}
+	}
+	return nil
+}
+
+// sortBySequence sorts the names of sequential znodes by their sequence
+// number, the last 10 digits, ignoring any protection prefix.
+func sortBySequence(names []string) {
+	sequence := func(name string) string {
+		if len(name) < 10 {
+			return name
+		}
+		return name[len(name)-10:]
+	}
+	sort.Slice(names, func(i, j int) bool { return sequence(names[i]) < sequence(names[j]) })
+}
+
+// contenders returns the lock znodes of dir in the order they hold it.
+func contenders(conn Conn, dir string) ([]string, error) {
+	children, _, err := conn.Children(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	names := children[:0]
+	for _, c := range children {
+		if strings.Contains(c, lockPrefix) {
+			names = append(names, c)
+		}
+	}
+	sortBySequence(names)
+	return names, nil
+}
+
+// Lock is a distributed mutual exclusion lock held by the owner of the
+// oldest ephemeral sequential znode under a directory, waiters watching
+// their predecessor only.
+//
+// A Lock is reentrant: Lock may be called again while it is held, each call
+// needing a matching Unlock. Reentrancy is per Lock value, so goroutines
+// that must exclude each other should use a Lock each.
+//
+// The lock is lost when its session expires, as the ensemble then deletes
+// its znode. Holders should stop relying on the lock once Lost is closed.
+type Lock struct {
+	conn Conn
+	dir  string
+	data []byte
+
+	// sem serializes Lock and Unlock while letting waiters give up
+	sem chan struct{}
+
+	mutex    sync.Mutex
+	node     string
+	count    int
+	lost     chan struct{}
+	released chan struct{}
+}
+
+// NewLock returns a lock on dir, which is created when missing. data is
+// stored in the znode of the lock, e.g. to identify its holder.
+func NewLock(conn Conn, dir string, data []byte) *Lock {
+	return &Lock{conn: conn, dir: dir, data: data, sem: make(chan struct{}, 1)}
+}
+
+// Lock acquires the lock, waiting until ctx is done.
+func (l *Lock) Lock(ctx context.Context) error {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-l.sem }()
+
+	l.mutex.Lock()
+	held, lost := l.node != "", l.lost
+	l.mutex.Unlock()
+	if held {
+		select {
+		case <-lost:
+			// the holds must be released first
+			return ErrLost
+		default:
+		}
+		l.mutex.Lock()
+		l.count++
+		l.mutex.Unlock()
+		return nil
+	}
+
+	if err := ensurePath(l.conn, l.dir); err != nil {
+		return err
+	}
+	node, err := l.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	lost, released := make(chan struct{}), make(chan struct{})
+	l.mutex.Lock()
+	l.node, l.count, l.lost, l.released = node, 1, lost, released
+	l.mutex.Unlock()
+	go l.monitor(node, lost, released)
+	return nil
+}
+
+// acquire creates the znode of the lock and waits for it to come first.
+func (l *Lock) acquire(ctx context.Context) (string, error) {
+	node := ""
+	abort := func(err error) (string, error) {
+		if node != "" {
+			if derr := l.conn.Delete(node, -1); derr != nil && !errors.Is(derr, zk.ErrNoNode) {
+				return "", fmt.Errorf("%v, and failed to delete %s: %w", err, node, derr)
+			}
+		}
+		return "", err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return abort(err)
+		}
+		if node == "" {
+			created, err := l.conn.CreateProtectedEphemeralSequential(path.Join(l.dir, lockPrefix), l.data, zk.WorldACL(zk.PermAll))
+			if err != nil {
+				return "", fmt.Errorf("failed to create lock znode under %s: %w", l.dir, err)
+			}
+			node = created
+		}
+		names, err := contenders(l.conn, l.dir)
+		if err != nil {
+			return abort(err)
+		}
+		i := -1
+		for j, name := range names {
+			if name == path.Base(node) {
+				i = j
+				break
+			}
+		}
+		switch {
+		case i < 0:
+			// the session expired and took the znode along
+			node = ""
+			continue
+		case i == 0:
+			return node, nil
+		}
+
+		exists, _, ch, err := l.conn.ExistsW(path.Join(l.dir, names[i-1]))
+		if err != nil {
+			return abort(fmt.Errorf("failed to watch %s: %w", names[i-1], err))
+		}
+		if !exists {
+			continue
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return abort(ctx.Err())
+		}
+	}
+}
+
+// monitor closes lost when the znode of the lock disappears before the
+// lock is released.
+func (l *Lock) monitor(node string, lost, released chan struct{}) {
+	for {
+		exists, _, ch, err := l.conn.ExistsW(node)
+		if err != nil || !exists {
+			close(lost)
+			return
+		}
+		select {
+		case e := <-ch:
+			if e.Type == zk.EventNodeDeleted || e.Type == zk.EventNotWatching {
+				close(lost)
+				return
+			}
+		case <-released:
+			return
+		}
+	}
+}
+
+// Lost returns a channel closed once the held lock is lost, or nil when
+// the lock is not held.
+func (l *Lock) Lost() <-chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lost
+}
+
+// Unlock releases one hold of the lock, deleting its znode with the last
+// one.
+func (l *Lock) Unlock() error {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.count == 0 {
+		return ErrNotLocked
+	}
+	if l.count--; l.count > 0 {
+		return nil
+	}
+	node, lost := l.node, l.lost
+	close(l.released)
+	l.node, l.lost, l.released = "", nil, nil
+
+	// checked before deleting, which closes lost as well
+	select {
+	case <-lost:
+		l.conn.Delete(node, -1)
+		return ErrLost
+	default:
+	}
+	if err := l.conn.Delete(node, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return fmt.Errorf("failed to delete %s: %w", node, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package recipe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"zookeeper-exam/zktest"
+)
+
+func TestLockExclusive(t *testing.T) {
+	server := zktest.NewServer()
+	a := NewLock(server.Connect(), "/app/lock", []byte("a"))
+	b := NewLock(server.Connect(), "/app/lock", []byte("b"))
+	ctx := context.Background()
+
+	if err := a.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := b.Lock(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect %v but return %v", context.DeadlineExceeded, err)
+	}
+	if names, _ := contenders(server.Connect(), "/app/lock"); len(names) != 1 {
+		t.Errorf("expect the znode of the waiter to be deleted but return %v", names)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- b.Lock(ctx) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("expect b to wait but return %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect b to acquire the released lock")
+	}
+	if err := a.Unlock(); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("expect %v but return %v", ErrNotLocked, err)
+	}
+}
+
+func TestLockReentrant(t *testing.T) {
+	server := zktest.NewServer()
+	a := NewLock(server.Connect(), "/lock", nil)
+	b := NewLock(server.Connect(), "/lock", nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := a.Lock(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.Lock(timeout); err == nil {
+		t.Errorf("expect the lock to be held until the last unlock")
+	}
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockSessionExpiry(t *testing.T) {
+	server := zktest.NewServer()
+	conn := server.Connect()
+	a := NewLock(conn, "/lock", nil)
+	b := NewLock(server.Connect(), "/lock", nil)
+	ctx := context.Background()
+
+	if a.Lost() != nil {
+		t.Errorf("expect no lost channel before locking")
+	}
+	if err := a.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	conn.Expire()
+	select {
+	case <-a.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expect the lock to be lost with the session")
+	}
+	if err := b.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Lock(ctx); !errors.Is(err, ErrLost) {
+		t.Errorf("expect %v but return %v", ErrLost, err)
+	}
+	if err := a.Unlock(); !errors.Is(err, ErrLost) {
+		t.Errorf("expect %v but return %v", ErrLost, err)
+	}
+
+	// a waiter whose session expires queues again
+	acquired := make(chan error, 1)
+	go func() { acquired <- a.Lock(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	conn.Expire()
+	if err := b.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect a to acquire the lock on its new session")
+	}
+	if names, _ := contenders(conn, "/lock"); len(names) != 1 {
+		t.Errorf("expect a single lock znode but return %v", names)
+	}
+}
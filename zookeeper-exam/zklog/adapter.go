@@ -0,0 +1,59 @@
+package zklog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// levelRules assign levels to the messages of the zookeeper client by the
+// start of their format, the client logging everything through Printf.
+// Messages matching no rule are logged at Warn, as most of the remaining
+// ones report unexpected responses.
+var levelRules = []struct {
+	prefix string
+	level  Level
+}{
+	{"connected to ", Info},
+	{"authenticated: ", Info},
+	{"re-submitting ", Info},
+	{"recv loop terminated: ", Info},
+	{"send loop terminated: ", Info},
+	{"failed to connect to ", Warn},
+	{"recv closed, ", Debug},
+	{"should quit, ", Debug},
+	{"authentication failed: ", Error},
+	{"error in resending auth creds: ", Error},
+	{"gave up trying to send opClose", Warn},
+}
+
+// LevelOf returns the level of a message of the zookeeper client by its
+// format.
+func LevelOf(format string) Level {
+	for _, r := range levelRules {
+		if strings.HasPrefix(format, r.prefix) {
+			return r.level
+		}
+	}
+	return Warn
+}
+
+// zkLogger adapts a Logger to zk.Logger.
+type zkLogger struct {
+	logger *Logger
+}
+
+// ZK returns a zk.Logger logging the messages of the zookeeper client, at
+// the level given by LevelOf, with a component=zk field.
+func (l *Logger) ZK() zk.Logger {
+	return zkLogger{logger: l.With("component", "zk")}
+}
+
+func (z zkLogger) Printf(format string, args ...interface{}) {
+	level := LevelOf(format)
+	if !z.logger.Enabled(level) {
+		return
+	}
+	z.logger.Log(level, fmt.Sprintf(format, args...))
+}
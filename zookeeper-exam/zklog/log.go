@@ -0,0 +1,192 @@
+// Package zklog routes the messages of the zookeeper client to a leveled
+// structured logger, and reports the session state changes of connections.
+package zklog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of an entry.
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+	// Off is above every level, so that a logger at Off logs nothing.
+	Off
+)
+
+var levelNames = []string{"debug", "info", "warn", "error", "off"}
+
+func (l Level) String() string {
+	if l < Debug || l > Off {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// Levels lists the names of the levels.
+var Levels = levelNames
+
+// ParseLevel parses the name of a level.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	return Off, fmt.Errorf("unknown log level %q", name)
+}
+
+// Field is a key and value of an entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is a logged message.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+// Handler writes entries.
+type Handler func(Entry)
+
+// Logger is a leveled structured logger, dropping entries below its level.
+// It is safe for concurrent use.
+type Logger struct {
+	mutex   sync.Mutex
+	level   Level
+	handler Handler
+	fields  []Field
+}
+
+// New returns a logger passing the entries at or above level to handler.
+func New(handler Handler, level Level) *Logger {
+	return &Logger{level: level, handler: handler}
+}
+
+// SetLevel changes the level of the logger and of those derived from it
+// with With afterwards.
+func (l *Logger) SetLevel(level Level) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.level = level
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return level >= l.level
+}
+
+// With returns a logger adding the fields of keysAndValues to every entry.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	fields := append(append([]Field(nil), l.fields...), toFields(keysAndValues)...)
+	return &Logger{level: l.level, handler: l.handler, fields: fields}
+}
+
+func toFields(keysAndValues []interface{}) []Field {
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return fields
+}
+
+// Log logs msg at level, with the fields of keysAndValues, given as
+// alternating keys and values.
+func (l *Logger) Log(level Level, msg string, keysAndValues ...interface{}) {
+	l.mutex.Lock()
+	if level < l.level {
+		l.mutex.Unlock()
+		return
+	}
+	fields := append(append([]Field(nil), l.fields...), toFields(keysAndValues)...)
+	l.mutex.Unlock()
+	l.handler(Entry{Time: time.Now(), Level: level, Message: msg, Fields: fields})
+}
+
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.Log(Debug, msg, keysAndValues...)
+}
+
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.Log(Info, msg, keysAndValues...)
+}
+
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.Log(Warn, msg, keysAndValues...)
+}
+
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.Log(Error, msg, keysAndValues...)
+}
+
+// TextHandler writes entries as lines of
+//
+//	2006-01-02T15:04:05.000Z07:00 INFO message key=value ...
+func TextHandler(w io.Writer) Handler {
+	var mutex sync.Mutex
+	return func(e Entry) {
+		var b strings.Builder
+		b.WriteString(e.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+		b.WriteByte(' ')
+		b.WriteString(strings.ToUpper(e.Level.String()))
+		b.WriteByte(' ')
+		b.WriteString(e.Message)
+		for _, f := range e.Fields {
+			v := fmt.Sprint(f.Value)
+			if strings.ContainsAny(v, " \t\n\"=") {
+				v = fmt.Sprintf("%q", v)
+			}
+			fmt.Fprintf(&b, " %s=%s", f.Key, v)
+		}
+		b.WriteByte('\n')
+		mutex.Lock()
+		defer mutex.Unlock()
+		io.WriteString(w, b.String())
+	}
+}
+
+// JSONHandler writes entries as JSON lines with time, level and msg keys
+// next to the fields. Values that cannot be encoded are written as text.
+func JSONHandler(w io.Writer) Handler {
+	var mutex sync.Mutex
+	return func(e Entry) {
+		m := make(map[string]interface{}, len(e.Fields)+3)
+		for _, f := range e.Fields {
+			if err, ok := f.Value.(error); ok {
+				m[f.Key] = err.Error()
+			} else if _, err := json.Marshal(f.Value); err != nil {
+				m[f.Key] = fmt.Sprint(f.Value)
+			} else {
+				m[f.Key] = f.Value
+			}
+		}
+		m["time"] = e.Time.Format(time.RFC3339Nano)
+		m["level"] = e.Level.String()
+		m["msg"] = e.Message
+		b, _ := json.Marshal(m)
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Write(append(b, '\n'))
+	}
+}
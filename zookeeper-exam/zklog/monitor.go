@@ -0,0 +1,128 @@
+package zklog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+// StateChange is a change of the session state of a connection.
+type StateChange struct {
+	Time   time.Time
+	State  zk.State
+	Server string
+}
+
+// stateLevels are the levels session states are logged at, Info otherwise.
+var stateLevels = map[zk.State]Level{
+	zk.StateDisconnected: Warn,
+	zk.StateExpired:      Error,
+	zk.StateAuthFailed:   Error,
+	zk.StateConnecting:   Debug,
+}
+
+// Monitor follows the event channel returned by zk.Connect, logging session
+// state changes and passing them to subscribers, and debug logging the
+// other events.
+type Monitor struct {
+	logger *Logger
+
+	mutex   sync.Mutex
+	state   zk.State
+	changed chan struct{}
+	subs    map[int]chan StateChange
+	nextID  int
+}
+
+// Watch follows events until ctx is done. The zookeeper client never
+// closes its event channel, so ctx should end with the connection.
+func Watch(ctx context.Context, events <-chan zk.Event, logger *Logger) *Monitor {
+	m := &Monitor{
+		logger:  logger.With("component", "zk"),
+		state:   zk.StateDisconnected,
+		changed: make(chan struct{}),
+		subs:    make(map[int]chan StateChange),
+	}
+	go func() {
+		for {
+			select {
+			case e := <-events:
+				m.handle(e)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return m
+}
+
+func (m *Monitor) handle(e zk.Event) {
+	if e.Type != zk.EventSession {
+		m.logger.Debug("event", "type", e.Type.String(), "path", e.Path, "state", e.State.String())
+		return
+	}
+	level, ok := stateLevels[e.State]
+	if !ok {
+		level = Info
+	}
+	m.logger.Log(level, "session state changed", "state", e.State.String(), "server", e.Server)
+
+	change := StateChange{Time: time.Now(), State: e.State, Server: e.Server}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.state = e.State
+	close(m.changed)
+	m.changed = make(chan struct{})
+	for _, ch := range m.subs {
+		select {
+		case ch <- change:
+		default:
+			// subscribers falling behind miss changes rather than
+			// stalling the monitor
+		}
+	}
+}
+
+// State returns the last session state.
+func (m *Monitor) State() zk.State {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.state
+}
+
+// Subscribe returns a channel of the state changes, buffering up to buffer
+// of them for a slow receiver, and a function ending the subscription.
+func (m *Monitor) Subscribe(buffer int) (<-chan StateChange, func()) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	id := m.nextID
+	m.nextID++
+	ch := make(chan StateChange, buffer)
+	m.subs[id] = ch
+	return ch, func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		delete(m.subs, id)
+	}
+}
+
+// Wait waits until the session reaches one of states and returns it.
+func (m *Monitor) Wait(ctx context.Context, states ...zk.State) (zk.State, error) {
+	for {
+		m.mutex.Lock()
+		state, changed := m.state, m.changed
+		m.mutex.Unlock()
+		for _, s := range states {
+			if state == s {
+				return state, nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return state, ctx.Err()
+		}
+	}
+}
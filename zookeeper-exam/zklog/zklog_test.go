@@ -0,0 +1,163 @@
+package zklog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+type entries struct {
+	mutex sync.Mutex
+	list  []Entry
+}
+
+func (e *entries) handle(entry Entry) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.list = append(e.list, entry)
+}
+
+func (e *entries) messages() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	messages := make([]string, 0, len(e.list))
+	for _, entry := range e.list {
+		messages = append(messages, entry.Level.String()+" "+entry.Message)
+	}
+	return messages
+}
+
+func TestLevels(t *testing.T) {
+	got := &entries{}
+	logger := New(got.handle, Info)
+	logger.Debug("hidden")
+	logger.Info("shown", "k", 1)
+	logger.With("component", "x").Error("failed", "err", errors.New("boom"), "odd")
+	logger.SetLevel(Off)
+	logger.Error("hidden")
+
+	if messages := got.messages(); strings.Join(messages, ",") != "info shown,error failed" {
+		t.Errorf("expect info shown and error failed but return %v", messages)
+	}
+	fields := got.list[1].Fields
+	if len(fields) != 3 || fields[0].Key != "component" || fields[2].Key != "odd" || fields[2].Value != nil {
+		t.Errorf("expect component, err and odd fields but return %v", fields)
+	}
+
+	for _, name := range Levels {
+		level, err := ParseLevel(strings.ToUpper(name))
+		if err != nil || level.String() != name {
+			t.Errorf("expect %v but return %v, %v", name, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("expect an error for an unknown level")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	var text, js bytes.Buffer
+	entry := Entry{
+		Time:    time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC),
+		Level:   Warn,
+		Message: "failed to connect",
+		Fields:  []Field{{"server", "zk-1:2181"}, {"err", errors.New("connection refused")}},
+	}
+	TextHandler(&text)(entry)
+	if expect := "2021-03-01T09:00:00.000Z WARN failed to connect server=zk-1:2181 err=\"connection refused\"\n"; text.String() != expect {
+		t.Errorf("expect %q but return %q", expect, text.String())
+	}
+	JSONHandler(&js)(entry)
+	var m map[string]interface{}
+	if err := json.Unmarshal(js.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["level"] != "warn" || m["msg"] != "failed to connect" || m["err"] != "connection refused" || m["server"] != "zk-1:2181" {
+		t.Errorf("expect the entry as JSON but return %v", m)
+	}
+}
+
+func TestZKAdapter(t *testing.T) {
+	got := &entries{}
+	z := New(got.handle, Info).ZK()
+	z.Printf("connected to %s", "127.0.0.1:2181")
+	z.Printf("recv loop terminated: %v", "EOF")
+	z.Printf("failed to connect to %s: %v", "127.0.0.1:2181", "refused")
+	z.Printf("authentication failed: %s", "bad")
+	z.Printf("Response for unknown request with xid %d", 7)
+
+	expect := []string{
+		"info connected to 127.0.0.1:2181",
+		"info recv loop terminated: EOF",
+		"warn failed to connect to 127.0.0.1:2181: refused",
+		"error authentication failed: bad",
+		"warn Response for unknown request with xid 7",
+	}
+	if messages := got.messages(); strings.Join(messages, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expect %v but return %v", expect, messages)
+	}
+	if f := got.list[0].Fields; len(f) != 1 || f[0].Value != "zk" {
+		t.Errorf("expect a component=zk field but return %v", f)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := &entries{}
+	events := make(chan zk.Event)
+	m := Watch(ctx, events, New(got.handle, Debug))
+	changes, unsubscribe := m.Subscribe(10)
+
+	waited := make(chan zk.State, 1)
+	go func() {
+		state, _ := m.Wait(ctx, zk.StateHasSession, zk.StateAuthFailed)
+		waited <- state
+	}()
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateConnecting, Server: "zk-1:2181"}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateConnected, Server: "zk-1:2181"}
+	events <- zk.Event{Type: zk.EventNodeCreated, Path: "/a"}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession, Server: "zk-1:2181"}
+
+	select {
+	case state := <-waited:
+		if state != zk.StateHasSession {
+			t.Errorf("expect %v but return %v", zk.StateHasSession, state)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect the session to be reached")
+	}
+	for _, expect := range []zk.State{zk.StateConnecting, zk.StateConnected, zk.StateHasSession} {
+		if c := <-changes; c.State != expect || c.Server != "zk-1:2181" {
+			t.Errorf("expect %v but return %+v", expect, c)
+		}
+	}
+
+	unsubscribe()
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateExpired}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateDisconnected}
+	if state, _ := m.Wait(ctx, zk.StateDisconnected); state != zk.StateDisconnected || m.State() != zk.StateDisconnected {
+		t.Errorf("expect %v but return %v", zk.StateDisconnected, state)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expect no change after unsubscribing but return %d", len(changes))
+	}
+
+	messages := got.messages()
+	if len(messages) != 6 || messages[2] != "debug event" || messages[4] != "error session state changed" {
+		t.Errorf("expect 5 state changes and an event but return %v", messages)
+	}
+
+	timeout, cancelWait := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelWait()
+	if _, err := m.Wait(timeout, zk.StateHasSession); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect %v but return %v", context.DeadlineExceeded, err)
+	}
+}
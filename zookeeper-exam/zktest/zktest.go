@@ -0,0 +1,490 @@
+// Package zktest is an in-process stand-in for a zookeeper ensemble, for
+// tests. Its Conn has the methods of *zk.Conn used by this module and keeps
+// their semantics: ephemeral and sequential znodes, versions, one shot
+// watches, ACLs of the world and digest schemes, and sessions that can be
+// expired to test recovery.
+package zktest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+)
+
+type node struct {
+	data     []byte
+	acl      []zk.ACL
+	stat     zk.Stat
+	children map[string]bool
+}
+
+type watcher struct {
+	conn *Conn
+	ch   chan zk.Event
+}
+
+// Server holds the znodes shared by its connections.
+type Server struct {
+	mutex        sync.Mutex
+	nodes        map[string]*node
+	zxid         int64
+	sessions     int64
+	dataWatches  map[string][]watcher
+	childWatches map[string][]watcher
+}
+
+func NewServer() *Server {
+	return &Server{
+		nodes:        map[string]*node{"/": {acl: zk.WorldACL(zk.PermAll), children: make(map[string]bool)}},
+		dataWatches:  make(map[string][]watcher),
+		childWatches: make(map[string][]watcher),
+	}
+}
+
+// Conn is a session of a Server.
+type Conn struct {
+	server    *Server
+	sessionID int64
+	closed    bool
+	// ids are the identities added by AddAuth, as ACLs without perms
+	ids []zk.ACL
+}
+
+// Connect opens a new session.
+func (s *Server) Connect() *Conn {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions++
+	return &Conn{server: s, sessionID: s.sessions}
+}
+
+func (c *Conn) SessionID() int64 {
+	c.server.mutex.Lock()
+	defer c.server.mutex.Unlock()
+	return c.sessionID
+}
+
+// Expire expires the session as the ensemble would after losing contact
+// with the client: its ephemeral znodes are deleted, its watches fire
+// EventNotWatching with zk.ErrSessionExpired, and it continues with a new
+// session as *zk.Conn does when it reconnects.
+func (c *Conn) Expire() {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.endSession(c, zk.ErrSessionExpired)
+	s.sessions++
+	c.sessionID = s.sessions
+}
+
+// Close ends the session, deleting its ephemeral znodes.
+func (c *Conn) Close() {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if c.closed {
+		return
+	}
+	s.endSession(c, zk.ErrClosing)
+	c.closed = true
+}
+
+func (s *Server) endSession(c *Conn, err error) {
+	owned := make([]string, 0)
+	for p, n := range s.nodes {
+		if n.stat.EphemeralOwner == c.sessionID {
+			owned = append(owned, p)
+		}
+	}
+	sort.Strings(owned)
+	for _, p := range owned {
+		s.remove(p)
+	}
+	for _, watches := range []map[string][]watcher{s.dataWatches, s.childWatches} {
+		for p, ws := range watches {
+			kept := ws[:0]
+			for _, w := range ws {
+				if w.conn != c {
+					kept = append(kept, w)
+					continue
+				}
+				w.ch <- zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: p, Err: err}
+			}
+			watches[p] = kept
+		}
+	}
+}
+
+// fire triggers the watches of path, which are one shot.
+func (s *Server) fire(watches map[string][]watcher, p string, t zk.EventType) {
+	for _, w := range watches[p] {
+		w.ch <- zk.Event{Type: t, State: zk.StateHasSession, Path: p}
+	}
+	delete(watches, p)
+}
+
+func (s *Server) watch(c *Conn, watches map[string][]watcher, p string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	watches[p] = append(watches[p], watcher{conn: c, ch: ch})
+	return ch
+}
+
+// remove deletes a znode without children and fires its watches.
+func (s *Server) remove(p string) {
+	delete(s.nodes, p)
+	s.zxid++
+	parent := s.nodes[path.Dir(p)]
+	delete(parent.children, path.Base(p))
+	parent.stat.Cversion++
+	parent.stat.NumChildren--
+	parent.stat.Pzxid = s.zxid
+	s.fire(s.dataWatches, p, zk.EventNodeDeleted)
+	s.fire(s.childWatches, p, zk.EventNodeDeleted)
+	s.fire(s.childWatches, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+// AddAuth adds an identity to the session. Only the digest scheme is
+// supported, auth being user:password. Identities are kept when the session
+// expires, as *zk.Conn submits them again.
+func (c *Conn) AddAuth(scheme string, auth []byte) error {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if c.closed {
+		return zk.ErrClosing
+	}
+	i := strings.IndexByte(string(auth), ':')
+	if scheme != "digest" || i < 0 {
+		return zk.ErrAuthFailed
+	}
+	c.ids = append(c.ids, zk.DigestACL(0, string(auth[:i]), string(auth[i+1:]))...)
+	return nil
+}
+
+// allowed tells whether the ACL of n grants perm to the session.
+func (c *Conn) allowed(n *node, perm int32) bool {
+	for _, a := range n.acl {
+		if a.Perms&perm == 0 {
+			continue
+		}
+		if a.Scheme == "world" && a.ID == "anyone" {
+			return true
+		}
+		for _, id := range c.ids {
+			if a.Scheme == id.Scheme && a.ID == id.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandACL replaces the entries of the auth scheme of acl by the
+// identities of the session, as the ensemble does on create and set.
+func (c *Conn) expandACL(acl []zk.ACL) ([]zk.ACL, error) {
+	if len(acl) == 0 {
+		return nil, zk.ErrInvalidACL
+	}
+	expanded := make([]zk.ACL, 0, len(acl))
+	for _, a := range acl {
+		if a.Scheme != "auth" {
+			expanded = append(expanded, a)
+			continue
+		}
+		if len(c.ids) == 0 {
+			return nil, zk.ErrInvalidACL
+		}
+		for _, id := range c.ids {
+			expanded = append(expanded, zk.ACL{Perms: a.Perms, Scheme: id.Scheme, ID: id.ID})
+		}
+	}
+	return expanded, nil
+}
+
+func (c *Conn) check(p string) error {
+	if c.closed {
+		return zk.ErrClosing
+	}
+	if !strings.HasPrefix(p, "/") || len(p) > 1 && strings.HasSuffix(p, "/") || path.Clean(p) != p {
+		return zk.ErrInvalidPath
+	}
+	return nil
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (c *Conn) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return "", err
+	}
+	if p == "/" {
+		return "", zk.ErrNodeExists
+	}
+	if flags&^(zk.FlagEphemeral|zk.FlagSequence) != 0 {
+		return "", zk.ErrInvalidFlags
+	}
+	parent, ok := s.nodes[path.Dir(p)]
+	if !ok {
+		return "", zk.ErrNoNode
+	}
+	if !c.allowed(parent, zk.PermCreate) {
+		return "", zk.ErrNoAuth
+	}
+	if parent.stat.EphemeralOwner != 0 {
+		return "", zk.ErrNoChildrenForEphemerals
+	}
+	acl, err := c.expandACL(acl)
+	if err != nil {
+		return "", err
+	}
+	if flags&zk.FlagSequence != 0 {
+		p += fmt.Sprintf("%010d", parent.stat.Cversion)
+	}
+	if _, ok := s.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+
+	s.zxid++
+	now := millis(time.Now())
+	n := &node{
+		data:     append([]byte(nil), data...),
+		acl:      acl,
+		children: make(map[string]bool),
+		stat: zk.Stat{
+			Czxid:      s.zxid,
+			Mzxid:      s.zxid,
+			Pzxid:      s.zxid,
+			Ctime:      now,
+			Mtime:      now,
+			DataLength: int32(len(data)),
+		},
+	}
+	if flags&zk.FlagEphemeral != 0 {
+		n.stat.EphemeralOwner = c.sessionID
+	}
+	s.nodes[p] = n
+	parent.children[path.Base(p)] = true
+	parent.stat.Cversion++
+	parent.stat.NumChildren++
+	parent.stat.Pzxid = s.zxid
+	s.fire(s.dataWatches, p, zk.EventNodeCreated)
+	s.fire(s.childWatches, path.Dir(p), zk.EventNodeChildrenChanged)
+	return p, nil
+}
+
+// CreateProtectedEphemeralSequential creates an ephemeral sequential znode
+// whose name is prefixed by a unique id, as *zk.Conn does to find the znode
+// again after a connection loss.
+func (c *Conn) CreateProtectedEphemeralSequential(p string, data []byte, acl []zk.ACL) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	dir, name := path.Split(p)
+	return c.Create(dir+"_c_"+hex.EncodeToString(b)+"-"+name, data, zk.FlagEphemeral|zk.FlagSequence, acl)
+}
+
+func (c *Conn) Get(p string) ([]byte, *zk.Stat, error) {
+	data, stat, _, err := c.get(p, false)
+	return data, stat, err
+}
+
+func (c *Conn) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	return c.get(p, true)
+}
+
+func (c *Conn) get(p string, watch bool) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return nil, nil, nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	if !c.allowed(n, zk.PermRead) {
+		return nil, nil, nil, zk.ErrNoAuth
+	}
+	var ch <-chan zk.Event
+	if watch {
+		ch = s.watch(c, s.dataWatches, p)
+	}
+	stat := n.stat
+	return append([]byte(nil), n.data...), &stat, ch, nil
+}
+
+func (c *Conn) Exists(p string) (bool, *zk.Stat, error) {
+	ok, stat, _, err := c.exists(p, false)
+	return ok, stat, err
+}
+
+// ExistsW sets a watch whether the znode exists or not, firing on its
+// creation in the latter case.
+func (c *Conn) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	return c.exists(p, true)
+}
+
+func (c *Conn) exists(p string, watch bool) (bool, *zk.Stat, <-chan zk.Event, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return false, nil, nil, err
+	}
+	var ch <-chan zk.Event
+	if watch {
+		ch = s.watch(c, s.dataWatches, p)
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return false, nil, ch, nil
+	}
+	stat := n.stat
+	return true, &stat, ch, nil
+}
+
+func (c *Conn) Children(p string) ([]string, *zk.Stat, error) {
+	children, stat, _, err := c.children(p, false)
+	return children, stat, err
+}
+
+func (c *Conn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	return c.children(p, true)
+}
+
+func (c *Conn) children(p string, watch bool) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return nil, nil, nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	if !c.allowed(n, zk.PermRead) {
+		return nil, nil, nil, zk.ErrNoAuth
+	}
+	children := make([]string, 0, len(n.children))
+	for name := range n.children {
+		children = append(children, name)
+	}
+	var ch <-chan zk.Event
+	if watch {
+		ch = s.watch(c, s.childWatches, p)
+	}
+	stat := n.stat
+	return children, &stat, ch, nil
+}
+
+func (c *Conn) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if !c.allowed(n, zk.PermWrite) {
+		return nil, zk.ErrNoAuth
+	}
+	if version >= 0 && version != n.stat.Version {
+		return nil, zk.ErrBadVersion
+	}
+	s.zxid++
+	n.data = append([]byte(nil), data...)
+	n.stat.Version++
+	n.stat.Mzxid = s.zxid
+	n.stat.Mtime = millis(time.Now())
+	n.stat.DataLength = int32(len(data))
+	s.fire(s.dataWatches, p, zk.EventNodeDataChanged)
+	stat := n.stat
+	return &stat, nil
+}
+
+func (c *Conn) Delete(p string, version int32) error {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return err
+	}
+	if p == "/" {
+		return zk.ErrBadArguments
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return zk.ErrNoNode
+	}
+	if !c.allowed(s.nodes[path.Dir(p)], zk.PermDelete) {
+		return zk.ErrNoAuth
+	}
+	if version >= 0 && version != n.stat.Version {
+		return zk.ErrBadVersion
+	}
+	if len(n.children) > 0 {
+		return zk.ErrNotEmpty
+	}
+	s.remove(p)
+	return nil
+}
+
+func (c *Conn) GetACL(p string) ([]zk.ACL, *zk.Stat, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return nil, nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	stat := n.stat
+	return append([]zk.ACL(nil), n.acl...), &stat, nil
+}
+
+func (c *Conn) SetACL(p string, acl []zk.ACL, version int32) (*zk.Stat, error) {
+	s := c.server
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := c.check(p); err != nil {
+		return nil, err
+	}
+	n, ok := s.nodes[p]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if !c.allowed(n, zk.PermAdmin) {
+		return nil, zk.ErrNoAuth
+	}
+	if version >= 0 && version != n.stat.Aversion {
+		return nil, zk.ErrBadVersion
+	}
+	acl, err := c.expandACL(acl)
+	if err != nil {
+		return nil, err
+	}
+	n.acl = acl
+	n.stat.Aversion++
+	stat := n.stat
+	return &stat, nil
+}